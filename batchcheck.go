@@ -0,0 +1,142 @@
+package kzgceremony
+
+import (
+	"fmt"
+)
+
+// CeremonySize contains the number of powers of a sub-ceremony
+type CeremonySize struct {
+	NumG1Powers uint64
+	NumG2Powers uint64
+}
+
+// EthereumCeremonySizes are the sizes of the sub-ceremonies of the Ethereum
+// KZG Ceremony
+var EthereumCeremonySizes = []CeremonySize{
+	{4096, 65},
+	{8192, 65},
+	{16384, 65},
+	{32768, 65},
+}
+
+// BatchReport contains the result of the checks done by CheckReceivedBatch,
+// where each error is nil when the check passed or was skipped
+type BatchReport struct {
+	// SizesErr is the error of comparing the number of Contributions and
+	// powers with the expected CeremonySizes
+	SizesErr error
+	// StructureErr is the error of checking that the PowersOfTau of each
+	// Contribution are valid points following the powers of tau structure
+	StructureErr error
+	// StateErr is the error of comparing the BatchContribution with the
+	// public State, where G1Powers[1] of each Contribution must be the last
+	// RunningProducts of the matching Transcript
+	StateErr error
+}
+
+// Valid returns true when all the checks passed
+func (r *BatchReport) Valid() bool {
+	return r.SizesErr == nil && r.StructureErr == nil && r.StateErr == nil
+}
+
+// CheckReceivedBatch checks the BatchContribution received from the
+// Sequencer before contributing on top of it: that it has the expected sizes,
+// that the PowersOfTau of each Contribution follow the powers of tau
+// structure (see CheckSRS), and that it continues the given public State
+// (obtained from /info/current_state). The sizes check is skipped when
+// expected is nil, and the State check when s is nil.
+func CheckReceivedBatch(bc *BatchContribution, expected []CeremonySize, s *State,
+	mode VerificationMode) *BatchReport {
+	r := &BatchReport{}
+	if expected != nil {
+		r.SizesErr = checkBatchSizes(bc, expected)
+	}
+	r.StructureErr = checkBatchStructure(bc, mode)
+	if s != nil {
+		r.StateErr = checkBatchState(bc, s)
+	}
+	return r
+}
+
+// checkBatchSizes checks that the BatchContribution has one Contribution for
+// each expected CeremonySize, with the same number of powers
+func checkBatchSizes(bc *BatchContribution, expected []CeremonySize) error {
+	if len(bc.Contributions) != len(expected) {
+		return &VerificationError{Check: SizeCheck, Transcript: -1,
+			Element: "Contributions", Index: -1,
+			Err: fmt.Errorf("expected %d contributions, got %d",
+				len(expected), len(bc.Contributions))}
+	}
+	for i, c := range bc.Contributions {
+		if c.NumG1Powers != expected[i].NumG1Powers ||
+			c.NumG2Powers != expected[i].NumG2Powers {
+			return &VerificationError{Check: SizeCheck, Transcript: i,
+				Element: "PowersOfTau", Index: -1,
+				Err: fmt.Errorf("expected %d G1 and %d G2 powers, got %d and %d",
+					expected[i].NumG1Powers, expected[i].NumG2Powers,
+					c.NumG1Powers, c.NumG2Powers)}
+		}
+	}
+	return nil
+}
+
+// checkBatchStructure checks that the PowersOfTau of each Contribution match
+// its sizes and pass CheckSRS
+func checkBatchStructure(bc *BatchContribution, mode VerificationMode) error {
+	for i, c := range bc.Contributions {
+		if c.PowersOfTau == nil ||
+			uint64(len(c.PowersOfTau.G1Powers)) != c.NumG1Powers ||
+			uint64(len(c.PowersOfTau.G2Powers)) != c.NumG2Powers {
+			return &VerificationError{Check: SizeCheck, Transcript: i,
+				Element: "PowersOfTau", Index: -1,
+				Err: fmt.Errorf("powers of tau do not match %d G1 and %d G2 powers",
+					c.NumG1Powers, c.NumG2Powers)}
+		}
+		if err := CheckSRS(c.PowersOfTau, mode); err != nil {
+			return withTranscript(err, i)
+		}
+	}
+	return nil
+}
+
+// checkBatchState checks that the BatchContribution has the sizes of the
+// Transcripts of the State, and that G1Powers[1] of each Contribution is the
+// last RunningProducts of the Transcript
+func checkBatchState(bc *BatchContribution, s *State) error {
+	if len(bc.Contributions) != len(s.Transcripts) {
+		return &VerificationError{Check: SizeCheck, Transcript: -1,
+			Element: "Contributions", Index: -1,
+			Err: fmt.Errorf("the state has %d transcripts, got %d contributions",
+				len(s.Transcripts), len(bc.Contributions))}
+	}
+	for i, c := range bc.Contributions {
+		t := s.Transcripts[i]
+		if c.NumG1Powers != t.NumG1Powers || c.NumG2Powers != t.NumG2Powers {
+			return &VerificationError{Check: SizeCheck, Transcript: i,
+				Element: "PowersOfTau", Index: -1,
+				Err: fmt.Errorf("the state has %d G1 and %d G2 powers, got %d and %d",
+					t.NumG1Powers, t.NumG2Powers, c.NumG1Powers, c.NumG2Powers)}
+		}
+		if t.Witness == nil || len(t.Witness.RunningProducts) == 0 {
+			return &VerificationError{Check: WitnessLengthCheck, Transcript: i,
+				Element: "RunningProducts", Index: -1,
+				Err: fmt.Errorf("the state does not contain running products")}
+		}
+		if c.PowersOfTau == nil || len(c.PowersOfTau.G1Powers) < 2 {
+			return &VerificationError{Check: SizeCheck, Transcript: i,
+				Element: "PowersOfTau", Index: -1,
+				Err: fmt.Errorf("missing G1Powers[1]")}
+		}
+		n := len(t.Witness.RunningProducts)
+		last := t.Witness.RunningProducts[n-1]
+		if c.PowersOfTau.G1Powers[1] == nil || last == nil ||
+			!g1.Equal(c.PowersOfTau.G1Powers[1], last) {
+			return &VerificationError{Check: RunningProductCheck, Transcript: i,
+				Element: "G1Powers", Index: 1,
+				Point: g1PointToString(c.PowersOfTau.G1Powers[1]),
+				Err: fmt.Errorf("does not match the last running product %s"+
+					" of the state", g1PointToString(last))}
+		}
+	}
+	return nil
+}
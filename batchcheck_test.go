@@ -0,0 +1,82 @@
+package kzgceremony
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+func TestCheckReceivedBatch(t *testing.T) {
+	c := qt.New(t)
+
+	s := NewEmptyState([]int{8, 4}, []int{4, 4})
+	sizes := []CeremonySize{{8, 4}, {4, 4}}
+
+	// genesis batch, where G1Powers[1] is the generator
+	r := CheckReceivedBatch(s.NextBatch(), sizes, s, VerifyStrict)
+	c.Assert(r.Valid(), qt.IsTrue)
+
+	s, err := s.Contribute(
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+	r = CheckReceivedBatch(s.NextBatch(), sizes, s, VerifyBatch)
+	c.Assert(r.Valid(), qt.IsTrue)
+
+	// the checks are skipped when no sizes or State are given
+	r = CheckReceivedBatch(s.NextBatch(), nil, nil, VerifyBatch)
+	c.Assert(r.Valid(), qt.IsTrue)
+
+	r = CheckReceivedBatch(s.NextBatch(), EthereumCeremonySizes, s, VerifyBatch)
+	c.Assert(r.Valid(), qt.IsFalse)
+	c.Assert(r.SizesErr, qt.ErrorMatches,
+		"verification failed: size check, Contributions: expected 4 contributions, got 2")
+	c.Assert(r.StructureErr, qt.IsNil)
+	c.Assert(r.StateErr, qt.IsNil)
+
+	copyBatch := func() *BatchContribution {
+		bc := s.NextBatch()
+		for i := range bc.Contributions {
+			srs := *bc.Contributions[i].PowersOfTau
+			srs.G1Powers = append([]*bls12381.PointG1{}, srs.G1Powers...)
+			srs.G2Powers = append([]*bls12381.PointG2{}, srs.G2Powers...)
+			bc.Contributions[i].PowersOfTau = &srs
+		}
+		return bc
+	}
+	// a batch that is not a powers of tau sequence
+	bc := copyBatch()
+	g1s := bc.Contributions[1].PowersOfTau.G1Powers
+	g1s[2], g1s[3] = g1s[3], g1s[2]
+	r = CheckReceivedBatch(bc, sizes, s, VerifyBatch)
+	c.Assert(r.SizesErr, qt.IsNil)
+	c.Assert(r.StateErr, qt.IsNil)
+	var vErr *VerificationError
+	c.Assert(errors.As(r.StructureErr, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, G1StructureCheck)
+	c.Assert(vErr.Transcript, qt.Equals, 1)
+
+	// a batch with wrong sizes
+	bc = copyBatch()
+	bc.Contributions[0].NumG1Powers = 4
+	r = CheckReceivedBatch(bc, sizes, s, VerifyBatch)
+	for _, err := range []error{r.SizesErr, r.StructureErr, r.StateErr} {
+		c.Assert(errors.As(err, &vErr), qt.IsTrue)
+		c.Assert(vErr.Check, qt.Equals, SizeCheck)
+		c.Assert(vErr.Transcript, qt.Equals, 0)
+	}
+
+	// a valid batch that does not continue the public State
+	other, err := s.Contribute(
+		[]byte("2222222222222222222222222222222222222222222222222222222222222222"))
+	c.Assert(err, qt.IsNil)
+	r = CheckReceivedBatch(other.NextBatch(), sizes, s, VerifyBatch)
+	c.Assert(r.SizesErr, qt.IsNil)
+	c.Assert(r.StructureErr, qt.IsNil)
+	c.Assert(errors.As(r.StateErr, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, RunningProductCheck)
+	c.Assert(vErr.Transcript, qt.Equals, 0)
+	c.Assert(vErr.Element, qt.Equals, "G1Powers")
+	c.Assert(vErr.Index, qt.Equals, 1)
+}
@@ -0,0 +1,792 @@
+package kzgceremony
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	bls12381 "github.com/kilic/bls12-381"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Binary format
+//
+// The binary format is a compact alternative to the official JSON format,
+// where the points are stored as raw bytes in the ZCash compressed (or
+// uncompressed) format instead of hex strings. A binary document is:
+//
+//	magic "KZGC" | version (1 byte) | kind (1 byte) | flags (1 byte) |
+//	body | checksum
+//
+// where checksum is the blake2b-256 hash of all the previous bytes. All the
+// integers in the body are uint64 big-endian, and:
+//
+//	SRS:               numG1 | G1 points | numG2 | G2 points
+//	Transcript:        SRS | runningProducts | potPubkeys | blsSignatures
+//	State:             numTranscripts | Transcripts | participantIds |
+//	                   participantEcdsaSignatures
+//	BatchContribution: numContributions | (SRS | potPubkey |
+//	                   blsSignature)... | ecdsaSignature
+//
+// The point arrays are length-prefixed. The optional points (the BLS
+// signatures) are prefixed by a byte, 0 for a nil point and 1 otherwise. The
+// strings are length-prefixed UTF-8 bytes.
+
+// BinaryVersion is the version of the binary format written by the
+// BinaryEncoder
+const BinaryVersion = 1
+
+var binaryMagic = [4]byte{'K', 'Z', 'G', 'C'}
+
+// BinaryKind is the type of the value stored in a binary document
+type BinaryKind uint8
+
+const (
+	// BinarySRS is the kind of a binary document containing an SRS
+	BinarySRS BinaryKind = iota + 1
+	// BinaryTranscript is the kind of a binary document containing a
+	// Transcript
+	BinaryTranscript
+	// BinaryState is the kind of a binary document containing a State
+	BinaryState
+	// BinaryBatchContribution is the kind of a binary document containing
+	// a BatchContribution
+	BinaryBatchContribution
+)
+
+// String implements the fmt.Stringer interface
+func (k BinaryKind) String() string {
+	switch k {
+	case BinarySRS:
+		return "SRS"
+	case BinaryTranscript:
+		return "Transcript"
+	case BinaryState:
+		return "State"
+	case BinaryBatchContribution:
+		return "BatchContribution"
+	default:
+		return fmt.Sprintf("BinaryKind(%d)", uint8(k))
+	}
+}
+
+// binaryFlagUncompressed marks that the points are in the uncompressed
+// format
+const binaryFlagUncompressed = 1
+
+// maxBinaryLen bounds the lengths read from a binary document, so that a
+// malformed one can not make the decoder allocate arbitrary amounts of
+// memory
+const maxBinaryLen = 1 << 24
+
+// maxBinaryCap bounds the initial capacity of the slices read from a binary
+// document
+const maxBinaryCap = 1 << 12
+
+// binaryCap returns the initial capacity of a slice of n elements read from
+// a binary document. It is bounded, and the slice grows as the elements are
+// read, so that a wrong length does not allocate more memory than the
+// available data.
+func binaryCap(n int) int {
+	if n > maxBinaryCap {
+		return maxBinaryCap
+	}
+	return n
+}
+
+const (
+	g1CompressedSize = 48
+	g2CompressedSize = 96
+)
+
+// BinaryEncoder writes values in the binary format to an io.Writer
+type BinaryEncoder struct {
+	w io.Writer
+	// Uncompressed makes the encoder store the points in the uncompressed
+	// format, which is twice as large but faster to decode
+	Uncompressed bool
+}
+
+// NewBinaryEncoder returns a BinaryEncoder that writes compressed points to w
+func NewBinaryEncoder(w io.Writer) *BinaryEncoder {
+	return &BinaryEncoder{w: w}
+}
+
+// EncodeSRS writes the SRS as a binary document
+func (e *BinaryEncoder) EncodeSRS(srs *SRS) error {
+	return e.encode(BinarySRS, func(w *binaryWriter) {
+		w.srs(srs)
+	})
+}
+
+// EncodeTranscript writes the Transcript as a binary document
+func (e *BinaryEncoder) EncodeTranscript(t *Transcript) error {
+	return e.encode(BinaryTranscript, func(w *binaryWriter) {
+		w.transcript(t)
+	})
+}
+
+// EncodeState writes the State as a binary document
+func (e *BinaryEncoder) EncodeState(s *State) error {
+	return e.encode(BinaryState, func(w *binaryWriter) {
+		w.uint64(uint64(len(s.Transcripts)))
+		for i := range s.Transcripts {
+			w.transcript(&s.Transcripts[i])
+		}
+		w.strings(s.ParticipantIDs)
+		w.strings(s.ParticipantECDSASignatures)
+	})
+}
+
+// EncodeBatchContribution writes the BatchContribution as a binary document
+func (e *BinaryEncoder) EncodeBatchContribution(bc *BatchContribution) error {
+	return e.encode(BinaryBatchContribution, func(w *binaryWriter) {
+		w.uint64(uint64(len(bc.Contributions)))
+		for _, c := range bc.Contributions {
+			if c.PowersOfTau == nil {
+				w.fail(fmt.Errorf("contribution without PowersOfTau"))
+				return
+			}
+			if c.NumG1Powers != uint64(len(c.PowersOfTau.G1Powers)) {
+				w.fail(fmt.Errorf("wrong NumG1Powers"))
+			}
+			if c.NumG2Powers != uint64(len(c.PowersOfTau.G2Powers)) {
+				w.fail(fmt.Errorf("wrong NumG2Powers"))
+			}
+			w.srs(c.PowersOfTau)
+			w.optionalPointsG2([]*bls12381.PointG2{c.PotPubKey})
+			w.optionalPointsG1([]*bls12381.PointG1{c.BLSSignature})
+		}
+		w.string(bc.ECDSASignature)
+	})
+}
+
+func (e *BinaryEncoder) encode(kind BinaryKind, body func(w *binaryWriter)) error {
+	h, _ := blake2b.New256(nil)
+	bw := bufio.NewWriter(e.w)
+	w := &binaryWriter{
+		w:            io.MultiWriter(bw, h),
+		g1:           bls12381.NewG1(),
+		g2:           bls12381.NewG2(),
+		uncompressed: e.Uncompressed,
+	}
+	var flags byte
+	if e.Uncompressed {
+		flags |= binaryFlagUncompressed
+	}
+	w.write(binaryMagic[:])
+	w.write([]byte{BinaryVersion, byte(kind), flags})
+	body(w)
+	if w.err != nil {
+		return w.err
+	}
+	if _, err := bw.Write(h.Sum(nil)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// binaryWriter writes the binary format elements, keeping the first error so
+// that the callers check it once at the end
+type binaryWriter struct {
+	w            io.Writer
+	g1           *bls12381.G1
+	g2           *bls12381.G2
+	uncompressed bool
+	err          error
+}
+
+func (w *binaryWriter) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *binaryWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.Write(b)
+}
+
+func (w *binaryWriter) uint64(n uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], n)
+	w.write(b[:])
+}
+
+func (w *binaryWriter) string(s string) {
+	w.uint64(uint64(len(s)))
+	w.write([]byte(s))
+}
+
+func (w *binaryWriter) strings(s []string) {
+	w.uint64(uint64(len(s)))
+	for _, e := range s {
+		w.string(e)
+	}
+}
+
+func (w *binaryWriter) pointG1(p *bls12381.PointG1) {
+	if p == nil {
+		w.fail(fmt.Errorf("empty point value"))
+		return
+	}
+	if w.uncompressed {
+		w.write(w.g1.ToUncompressed(p))
+		return
+	}
+	w.write(w.g1.ToCompressed(p))
+}
+
+func (w *binaryWriter) pointG2(p *bls12381.PointG2) {
+	if p == nil {
+		w.fail(fmt.Errorf("empty point value"))
+		return
+	}
+	if w.uncompressed {
+		w.write(w.g2.ToUncompressed(p))
+		return
+	}
+	w.write(w.g2.ToCompressed(p))
+}
+
+func (w *binaryWriter) pointsG1(points []*bls12381.PointG1) {
+	w.uint64(uint64(len(points)))
+	for _, p := range points {
+		w.pointG1(p)
+	}
+}
+
+func (w *binaryWriter) pointsG2(points []*bls12381.PointG2) {
+	w.uint64(uint64(len(points)))
+	for _, p := range points {
+		w.pointG2(p)
+	}
+}
+
+// optionalPointsG1 writes the points prefixing each one with its presence
+// byte. Unlike pointsG1, the length is not written.
+func (w *binaryWriter) optionalPointsG1(points []*bls12381.PointG1) {
+	for _, p := range points {
+		if p == nil {
+			w.write([]byte{0})
+			continue
+		}
+		w.write([]byte{1})
+		w.pointG1(p)
+	}
+}
+
+// optionalPointsG2 acts as optionalPointsG1 for G2 points
+func (w *binaryWriter) optionalPointsG2(points []*bls12381.PointG2) {
+	for _, p := range points {
+		if p == nil {
+			w.write([]byte{0})
+			continue
+		}
+		w.write([]byte{1})
+		w.pointG2(p)
+	}
+}
+
+func (w *binaryWriter) srs(srs *SRS) {
+	w.pointsG1(srs.G1Powers)
+	w.pointsG2(srs.G2Powers)
+}
+
+func (w *binaryWriter) transcript(t *Transcript) {
+	if t.PowersOfTau == nil || t.Witness == nil {
+		w.fail(fmt.Errorf("transcript without PowersOfTau or Witness"))
+		return
+	}
+	if t.NumG1Powers != uint64(len(t.PowersOfTau.G1Powers)) {
+		w.fail(fmt.Errorf("wrong NumG1Powers"))
+	}
+	if t.NumG2Powers != uint64(len(t.PowersOfTau.G2Powers)) {
+		w.fail(fmt.Errorf("wrong NumG2Powers"))
+	}
+	w.srs(t.PowersOfTau)
+	w.pointsG1(t.Witness.RunningProducts)
+	w.pointsG2(t.Witness.PotPubKeys)
+	w.uint64(uint64(len(t.Witness.BLSSignatures)))
+	w.optionalPointsG1(t.Witness.BLSSignatures)
+}
+
+// BinaryDecoder reads values in the binary format from an io.Reader,
+// checking the correctness of all the points as the JSON parsers do
+type BinaryDecoder struct {
+	r *bufio.Reader
+}
+
+// NewBinaryDecoder returns a BinaryDecoder that reads from r
+func NewBinaryDecoder(r io.Reader) *BinaryDecoder {
+	return &BinaryDecoder{r: bufio.NewReader(r)}
+}
+
+// DecodeSRS reads a binary document containing an SRS
+func (d *BinaryDecoder) DecodeSRS() (*SRS, error) {
+	var srs *SRS
+	err := d.decode(BinarySRS, func(r *binaryReader) {
+		srs = r.srs()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return srs, nil
+}
+
+// DecodeTranscript reads a binary document containing a Transcript
+func (d *BinaryDecoder) DecodeTranscript() (*Transcript, error) {
+	var t *Transcript
+	err := d.decode(BinaryTranscript, func(r *binaryReader) {
+		t = r.transcript()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// DecodeState reads a binary document containing a State
+func (d *BinaryDecoder) DecodeState() (*State, error) {
+	s := &State{}
+	err := d.decode(BinaryState, func(r *binaryReader) {
+		n := r.length()
+		s.Transcripts = make([]Transcript, 0, binaryCap(n))
+		for i := 0; i < n && r.err == nil; i++ {
+			t := r.transcript()
+			if r.err != nil {
+				r.err = fmt.Errorf("transcript %d: %w", i, r.err)
+				return
+			}
+			s.Transcripts = append(s.Transcripts, *t)
+		}
+		s.ParticipantIDs = r.strings()
+		s.ParticipantECDSASignatures = r.strings()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// DecodeBatchContribution reads a binary document containing a
+// BatchContribution
+func (d *BinaryDecoder) DecodeBatchContribution() (*BatchContribution, error) {
+	bc := &BatchContribution{}
+	err := d.decode(BinaryBatchContribution, func(r *binaryReader) {
+		n := r.length()
+		bc.Contributions = make([]Contribution, 0, binaryCap(n))
+		for i := 0; i < n && r.err == nil; i++ {
+			c := Contribution{}
+			c.PowersOfTau = r.srs()
+			c.NumG1Powers = uint64(len(c.PowersOfTau.G1Powers))
+			c.NumG2Powers = uint64(len(c.PowersOfTau.G2Powers))
+			c.PotPubKey = r.optionalPointG2()
+			c.BLSSignature = r.optionalPointG1()
+			if r.err != nil {
+				r.err = fmt.Errorf("contribution %d: %w", i, r.err)
+				return
+			}
+			bc.Contributions = append(bc.Contributions, c)
+		}
+		bc.ECDSASignature = r.string()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bc, nil
+}
+
+func (d *BinaryDecoder) decode(kind BinaryKind, body func(r *binaryReader)) error {
+	h, _ := blake2b.New256(nil)
+	r := &binaryReader{r: io.TeeReader(d.r, h)}
+
+	var header [7]byte
+	r.read(header[:])
+	if r.err != nil {
+		return r.err
+	}
+	if !bytes.Equal(header[:4], binaryMagic[:]) {
+		return fmt.Errorf("not a binary document, wrong magic %x", header[:4])
+	}
+	if header[4] != BinaryVersion {
+		return fmt.Errorf("unsupported binary version %d", header[4])
+	}
+	if BinaryKind(header[5]) != kind {
+		return fmt.Errorf("binary document contains a %s, expected a %s",
+			BinaryKind(header[5]), kind)
+	}
+	if header[6]&^binaryFlagUncompressed != 0 {
+		return fmt.Errorf("unknown binary flags %x", header[6])
+	}
+	r.uncompressed = header[6]&binaryFlagUncompressed != 0
+
+	body(r)
+	if r.err != nil {
+		return r.err
+	}
+	sum := h.Sum(nil)
+	checksum := make([]byte, len(sum))
+	if _, err := io.ReadFull(d.r, checksum); err != nil {
+		return fmt.Errorf("reading checksum: %w", err)
+	}
+	if subtle.ConstantTimeCompare(sum, checksum) != 1 {
+		return fmt.Errorf("wrong binary checksum")
+	}
+	return nil
+}
+
+// binaryReader reads the binary format elements, keeping the first error so
+// that the callers check it once at the end
+type binaryReader struct {
+	r            io.Reader
+	uncompressed bool
+	err          error
+}
+
+func (r *binaryReader) read(b []byte) {
+	if r.err != nil {
+		return
+	}
+	_, r.err = io.ReadFull(r.r, b)
+}
+
+// bytes reads n bytes, growing the buffer as the data is read, so that a
+// wrong length does not allocate more memory than the available data
+func (r *binaryReader) bytes(n int) []byte {
+	const chunk = 1 << 20
+	var b []byte
+	for len(b) < n && r.err == nil {
+		m := n - len(b)
+		if m > chunk {
+			m = chunk
+		}
+		b = append(b, make([]byte, m)...)
+		r.read(b[len(b)-m:])
+	}
+	return b
+}
+
+func (r *binaryReader) uint64() uint64 {
+	var b [8]byte
+	r.read(b[:])
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// length reads a length, checking that it is in the allowed bounds
+func (r *binaryReader) length() int {
+	n := r.uint64()
+	if r.err == nil && n > maxBinaryLen {
+		r.err = fmt.Errorf("length %d exceeds the maximum %d", n, maxBinaryLen)
+	}
+	if r.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binaryReader) string() string {
+	return string(r.bytes(r.length()))
+}
+
+func (r *binaryReader) strings() []string {
+	n := r.length()
+	s := make([]string, 0, binaryCap(n))
+	for i := 0; i < n && r.err == nil; i++ {
+		s = append(s, r.string())
+	}
+	return s
+}
+
+func (r *binaryReader) pointSizes() (int, int) {
+	if r.uncompressed {
+		return 2 * g1CompressedSize, 2 * g2CompressedSize
+	}
+	return g1CompressedSize, g2CompressedSize
+}
+
+// pointsG1 reads a length-prefixed array of G1 points, which are decoded
+// and checked concurrently
+func (r *binaryReader) pointsG1() []*bls12381.PointG1 {
+	n := r.length()
+	size, _ := r.pointSizes()
+	b := r.bytes(n * size)
+	if r.err != nil {
+		return nil
+	}
+	points := make([]*bls12381.PointG1, n)
+	errs := make([]error, n)
+	parallelize(n, func(start, end int) {
+		g1 := bls12381.NewG1()
+		for i := start; i < end; i++ {
+			points[i], errs[i] = r.decodePointG1(g1, b[i*size:(i+1)*size])
+			if errs[i] != nil {
+				return
+			}
+		}
+	})
+	for i, err := range errs {
+		if err != nil {
+			r.err = fmt.Errorf("G1 point %d: %w", i, err)
+			return nil
+		}
+	}
+	return points
+}
+
+// pointsG2 acts as pointsG1 for G2 points
+func (r *binaryReader) pointsG2() []*bls12381.PointG2 {
+	n := r.length()
+	_, size := r.pointSizes()
+	b := r.bytes(n * size)
+	if r.err != nil {
+		return nil
+	}
+	points := make([]*bls12381.PointG2, n)
+	errs := make([]error, n)
+	parallelize(n, func(start, end int) {
+		g2 := bls12381.NewG2()
+		for i := start; i < end; i++ {
+			points[i], errs[i] = r.decodePointG2(g2, b[i*size:(i+1)*size])
+			if errs[i] != nil {
+				return
+			}
+		}
+	})
+	for i, err := range errs {
+		if err != nil {
+			r.err = fmt.Errorf("G2 point %d: %w", i, err)
+			return nil
+		}
+	}
+	return points
+}
+
+// optionalPointsG1 reads n G1 points prefixed by their presence byte
+func (r *binaryReader) optionalPointsG1(n int) []*bls12381.PointG1 {
+	size, _ := r.pointSizes()
+	points := make([]*bls12381.PointG1, 0, binaryCap(n))
+	g1 := bls12381.NewG1()
+	b := make([]byte, size)
+	for i := 0; i < n && r.err == nil; i++ {
+		if !r.present() {
+			points = append(points, nil)
+			continue
+		}
+		r.read(b)
+		if r.err != nil {
+			break
+		}
+		p, err := r.decodePointG1(g1, b)
+		if err != nil {
+			r.err = fmt.Errorf("G1 point %d: %w", i, err)
+			break
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+// optionalPointsG2 acts as optionalPointsG1 for G2 points
+func (r *binaryReader) optionalPointsG2(n int) []*bls12381.PointG2 {
+	_, size := r.pointSizes()
+	points := make([]*bls12381.PointG2, 0, binaryCap(n))
+	g2 := bls12381.NewG2()
+	b := make([]byte, size)
+	for i := 0; i < n && r.err == nil; i++ {
+		if !r.present() {
+			points = append(points, nil)
+			continue
+		}
+		r.read(b)
+		if r.err != nil {
+			break
+		}
+		p, err := r.decodePointG2(g2, b)
+		if err != nil {
+			r.err = fmt.Errorf("G2 point %d: %w", i, err)
+			break
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+// optionalPointG1 reads a G1 point prefixed by its presence byte
+func (r *binaryReader) optionalPointG1() *bls12381.PointG1 {
+	if points := r.optionalPointsG1(1); len(points) == 1 {
+		return points[0]
+	}
+	return nil
+}
+
+// optionalPointG2 acts as optionalPointG1 for a G2 point
+func (r *binaryReader) optionalPointG2() *bls12381.PointG2 {
+	if points := r.optionalPointsG2(1); len(points) == 1 {
+		return points[0]
+	}
+	return nil
+}
+
+// present reads the presence byte of an optional point
+func (r *binaryReader) present() bool {
+	var b [1]byte
+	r.read(b[:])
+	if r.err == nil && b[0] > 1 {
+		r.err = fmt.Errorf("invalid optional point tag %d", b[0])
+	}
+	return r.err == nil && b[0] == 1
+}
+
+func (r *binaryReader) decodePointG1(g1 *bls12381.G1, b []byte) (*bls12381.PointG1, error) {
+	var p *bls12381.PointG1
+	var err error
+	if r.uncompressed {
+		p, err = g1.FromUncompressed(b)
+	} else {
+		p, err = g1.FromCompressed(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkG1Point(g1, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *binaryReader) decodePointG2(g2 *bls12381.G2, b []byte) (*bls12381.PointG2, error) {
+	var p *bls12381.PointG2
+	var err error
+	if r.uncompressed {
+		p, err = g2.FromUncompressed(b)
+	} else {
+		p, err = g2.FromCompressed(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkG2Point(g2, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *binaryReader) srs() *SRS {
+	srs := &SRS{}
+	srs.G1Powers = r.pointsG1()
+	srs.G2Powers = r.pointsG2()
+	return srs
+}
+
+func (r *binaryReader) transcript() *Transcript {
+	t := &Transcript{Witness: &Witness{}}
+	t.PowersOfTau = r.srs()
+	t.NumG1Powers = uint64(len(t.PowersOfTau.G1Powers))
+	t.NumG2Powers = uint64(len(t.PowersOfTau.G2Powers))
+	t.Witness.RunningProducts = r.pointsG1()
+	t.Witness.PotPubKeys = r.pointsG2()
+	t.Witness.BLSSignatures = r.optionalPointsG1(r.length())
+	return t
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, using the
+// binary format with compressed points
+func (srs *SRS) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	err := NewBinaryEncoder(&b).EncodeSRS(srs)
+	return b.Bytes(), err
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+func (srs *SRS) UnmarshalBinary(b []byte) error {
+	d := NewBinaryDecoder(bytes.NewReader(b))
+	s, err := d.DecodeSRS()
+	if err != nil {
+		return err
+	}
+	if err := d.expectEOF(); err != nil {
+		return err
+	}
+	*srs = *s
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, using the
+// binary format with compressed points
+func (t *Transcript) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	err := NewBinaryEncoder(&b).EncodeTranscript(t)
+	return b.Bytes(), err
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+func (t *Transcript) UnmarshalBinary(b []byte) error {
+	d := NewBinaryDecoder(bytes.NewReader(b))
+	tr, err := d.DecodeTranscript()
+	if err != nil {
+		return err
+	}
+	if err := d.expectEOF(); err != nil {
+		return err
+	}
+	*t = *tr
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, using the
+// binary format with compressed points
+func (s *State) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	err := NewBinaryEncoder(&b).EncodeState(s)
+	return b.Bytes(), err
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+func (s *State) UnmarshalBinary(b []byte) error {
+	d := NewBinaryDecoder(bytes.NewReader(b))
+	st, err := d.DecodeState()
+	if err != nil {
+		return err
+	}
+	if err := d.expectEOF(); err != nil {
+		return err
+	}
+	*s = *st
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, using the
+// binary format with compressed points
+func (c *BatchContribution) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	err := NewBinaryEncoder(&b).EncodeBatchContribution(c)
+	return b.Bytes(), err
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+func (c *BatchContribution) UnmarshalBinary(b []byte) error {
+	d := NewBinaryDecoder(bytes.NewReader(b))
+	bc, err := d.DecodeBatchContribution()
+	if err != nil {
+		return err
+	}
+	if err := d.expectEOF(); err != nil {
+		return err
+	}
+	*c = *bc
+	return nil
+}
+
+// expectEOF checks that there is no data after the decoded document
+func (d *BinaryDecoder) expectEOF() error {
+	if _, err := d.r.ReadByte(); err != io.EOF {
+		return fmt.Errorf("unexpected data after the binary document")
+	}
+	return nil
+}
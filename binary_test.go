@@ -0,0 +1,177 @@
+package kzgceremony
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"runtime"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestBinaryStateRoundTrip(t *testing.T) {
+	c := qt.New(t)
+	j, err := ioutil.ReadFile("current_state_10.json")
+	c.Assert(err, qt.IsNil)
+	state := &State{}
+	err = json.Unmarshal(j, state)
+	c.Assert(err, qt.IsNil)
+	expected, err := json.Marshal(state)
+	c.Assert(err, qt.IsNil)
+
+	for _, uncompressed := range []bool{false, true} {
+		var b bytes.Buffer
+		e := NewBinaryEncoder(&b)
+		e.Uncompressed = uncompressed
+		err = e.EncodeState(state)
+		c.Assert(err, qt.IsNil)
+		if !uncompressed {
+			// about half the size of the JSON
+			c.Assert(b.Len() < len(expected)*55/100, qt.IsTrue)
+		}
+
+		decoded, err := NewBinaryDecoder(&b).DecodeState()
+		c.Assert(err, qt.IsNil)
+		got, err := json.Marshal(decoded)
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(got), qt.Equals, string(expected))
+	}
+
+	b, err := state.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	state2 := &State{}
+	err = state2.UnmarshalBinary(b)
+	c.Assert(err, qt.IsNil)
+	got, err := json.Marshal(state2)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(got), qt.Equals, string(expected))
+
+	// SRS & Transcript
+	tb, err := state.Transcripts[1].MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	tr := &Transcript{}
+	err = tr.UnmarshalBinary(tb)
+	c.Assert(err, qt.IsNil)
+	c.Assert(g1PointsToStrings(tr.Witness.BLSSignatures), qt.DeepEquals,
+		g1PointsToStrings(state.Transcripts[1].Witness.BLSSignatures))
+
+	sb, err := state.Transcripts[2].PowersOfTau.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	srs := &SRS{}
+	err = srs.UnmarshalBinary(sb)
+	c.Assert(err, qt.IsNil)
+	c.Assert(g2PointsToStrings(srs.G2Powers), qt.DeepEquals,
+		g2PointsToStrings(state.Transcripts[2].PowersOfTau.G2Powers))
+	// a document of another kind is rejected
+	err = srs.UnmarshalBinary(tb)
+	c.Assert(err, qt.ErrorMatches,
+		"binary document contains a Transcript, expected a SRS")
+}
+
+func TestBinaryBatchContributionRoundTrip(t *testing.T) {
+	c := qt.New(t)
+	j, err := ioutil.ReadFile("batch_contribution_10.json")
+	c.Assert(err, qt.IsNil)
+	bc := &BatchContribution{}
+	err = json.Unmarshal(j, bc)
+	c.Assert(err, qt.IsNil)
+	bc.ECDSASignature = "0x1234"
+	bc.Contributions[2].BLSSignature = nil
+	expected, err := json.Marshal(bc)
+	c.Assert(err, qt.IsNil)
+
+	b, err := bc.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	bc2 := &BatchContribution{}
+	err = bc2.UnmarshalBinary(b)
+	c.Assert(err, qt.IsNil)
+	got, err := json.Marshal(bc2)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(got), qt.Equals, string(expected))
+}
+
+func TestBinaryErrors(t *testing.T) {
+	c := qt.New(t)
+	srs := newEmptySRS(8, 4)
+	b, err := srs.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+
+	err = (&SRS{}).UnmarshalBinary(append(append([]byte{}, b...), 0))
+	c.Assert(err, qt.ErrorMatches, "unexpected data after the binary document")
+
+	err = (&SRS{}).UnmarshalBinary(b[:len(b)-1])
+	c.Assert(err, qt.ErrorMatches, "reading checksum: unexpected EOF")
+
+	corrupted := append([]byte{}, b...)
+	corrupted[len(corrupted)-1] ^= 1
+	err = (&SRS{}).UnmarshalBinary(corrupted)
+	c.Assert(err, qt.ErrorMatches, "wrong binary checksum")
+
+	corrupted = append([]byte{}, b...)
+	corrupted[0] = 'X'
+	err = (&SRS{}).UnmarshalBinary(corrupted)
+	c.Assert(err, qt.ErrorMatches, "not a binary document, wrong magic .*")
+
+	corrupted = append([]byte{}, b...)
+	corrupted[4] = 2
+	err = (&SRS{}).UnmarshalBinary(corrupted)
+	c.Assert(err, qt.ErrorMatches, "unsupported binary version 2")
+
+	// a point set to the infinity, 7 bytes of header and 8 of length
+	corrupted = append([]byte{}, b...)
+	p := corrupted[7+8+3*g1CompressedSize:]
+	p[0] = 0xc0
+	for i := 1; i < g1CompressedSize; i++ {
+		p[i] = 0
+	}
+	err = (&SRS{}).UnmarshalBinary(corrupted)
+	c.Assert(err, qt.ErrorMatches, "G1 point 3: point can not be zero")
+
+	// huge lengths are rejected before allocating
+	corrupted = append([]byte{}, b...)
+	corrupted[7] = 0xff
+	err = (&SRS{}).UnmarshalBinary(corrupted)
+	c.Assert(err, qt.ErrorMatches, "length .* exceeds the maximum .*")
+}
+
+func TestBinaryHugeCounts(t *testing.T) {
+	c := qt.New(t)
+
+	// short documents claiming the maximum number of elements, which must
+	// fail without allocating memory for all of them
+	huge := make([]byte, 8)
+	binary.BigEndian.PutUint64(huge, maxBinaryLen)
+	zero := make([]byte, 8)
+	document := func(kind BinaryKind, body ...[]byte) []byte {
+		b := append(append([]byte{}, binaryMagic[:]...), BinaryVersion, byte(kind), 0)
+		for _, p := range body {
+			b = append(b, p...)
+		}
+		return b
+	}
+	testCases := []struct {
+		name  string
+		doc   []byte
+		value interface{ UnmarshalBinary([]byte) error }
+	}{
+		{"transcripts", document(BinaryState, huge), &State{}},
+		{"participant ids", document(BinaryState, zero, huge), &State{}},
+		{"contributions", document(BinaryBatchContribution, huge), &BatchContribution{}},
+		{"bls signatures", document(BinaryTranscript, zero, zero, zero, zero, huge),
+			&Transcript{}},
+	}
+	for _, tc := range testCases {
+		c.Run(tc.name, func(c *qt.C) {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			err := tc.value.UnmarshalBinary(tc.doc)
+			runtime.ReadMemStats(&after)
+			c.Assert(err, qt.ErrorMatches, ".*EOF")
+			c.Assert(after.TotalAlloc-before.TotalAlloc < 16<<20, qt.IsTrue,
+				qt.Commentf("allocated %d bytes", after.TotalAlloc-before.TotalAlloc))
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package kzgceremony
+
+import (
+	"fmt"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// BLSSignatureDST is the domain separation tag used to hash the participant
+// identity into G1, following the BLS signatures proof of possession
+// ciphersuite (BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_) used by the
+// official Ethereum KZG Ceremony
+const BLSSignatureDST = "BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_"
+
+// hashIdentity hashes the given participant identity into a G1 point, using
+// the hash to curve suite BLS12381G1_XMD:SHA-256_SSWU_RO_
+func hashIdentity(identity string) (*bls12381.PointG1, error) {
+	return bls12381.NewG1().HashToCurve([]byte(identity), []byte(BLSSignatureDST))
+}
+
+// signIdentity computes the BLS signature of the participant identity, using
+// the toxic waste τ as secret key: [τ]H(identity)
+func signIdentity(t *toxicWaste, identity string) (*bls12381.PointG1, error) {
+	h, err := hashIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+	g1 := bls12381.NewG1()
+	sig := g1.New()
+	g1.MulScalar(sig, h, t.tau)
+	return sig, nil
+}
+
+// SignatureStatus is the result of verifying the BLS signatures of a
+// participant
+type SignatureStatus int
+
+const (
+	// SignatureValid means that the participant signed its identity in
+	// all the Transcripts
+	SignatureValid SignatureStatus = iota
+	// SignatureMissing means that the participant did not sign its
+	// identity, and the empty signature placeholder is used in all the
+	// Transcripts
+	SignatureMissing
+	// SignatureInvalid means that at least one of the signatures of the
+	// participant is not valid or is missing
+	SignatureInvalid
+)
+
+func (s SignatureStatus) String() string {
+	switch s {
+	case SignatureValid:
+		return "valid"
+	case SignatureMissing:
+		return "missing"
+	case SignatureInvalid:
+		return "invalid"
+	default:
+		return "unknown"
+	}
+}
+
+// SignatureReport contains the result of verifying the BLS signatures of a
+// single participant across all the Transcripts
+type SignatureReport struct {
+	// Index is the position of the participant in the witness arrays
+	Index int
+	// ParticipantID is the identity of the participant
+	ParticipantID string
+	// Status is the result of the verification
+	Status SignatureStatus
+	// Err is the *VerificationError of the first invalid signature when
+	// Status is SignatureInvalid
+	Err error
+}
+
+// VerifyBLSSignatures checks, for each participant of the State (excluding
+// the genesis entry), the BLS signatures of the Witness of each Transcript
+// against the matching PotPubKeys entry and ParticipantIDs string:
+//
+//	e(BLSSignatures[k], [1]₂) == e(H(ParticipantIDs[k]), PotPubKeys[k])
+//
+// Empty signatures are accepted as the placeholder of participants that did
+// not sign their identity, and reported as SignatureMissing. It returns an
+// error if the lengths of the witness arrays do not match.
+func VerifyBLSSignatures(s *State) ([]SignatureReport, error) {
+	n := len(s.ParticipantIDs)
+	for ti, t := range s.Transcripts {
+		if t.Witness == nil || len(t.Witness.BLSSignatures) != n ||
+			len(t.Witness.PotPubKeys) != n {
+			return nil, &VerificationError{Check: WitnessLengthCheck,
+				Transcript: ti, Element: "BLSSignatures", Index: -1,
+				Err: fmt.Errorf("BLSSignatures and PotPubKeys must have the"+
+					" same length than the %d ParticipantIDs", n)}
+		}
+	}
+
+	var reports []SignatureReport
+	pairing := bls12381.NewEngine()
+	for k := 1; k < n; k++ {
+		report := SignatureReport{
+			Index:         k,
+			ParticipantID: s.ParticipantIDs[k],
+			Status:        SignatureMissing,
+		}
+		var h *bls12381.PointG1
+		missing := 0
+		for ti, t := range s.Transcripts {
+			sig := t.Witness.BLSSignatures[k]
+			if sig == nil {
+				missing++
+				continue
+			}
+			if h == nil {
+				var err error
+				h, err = hashIdentity(s.ParticipantIDs[k])
+				if err != nil {
+					return nil, err
+				}
+			}
+			err := checkBLSSignature(pairing, h, sig, t.Witness.PotPubKeys[k])
+			if err != nil && report.Err == nil {
+				vErr := err.(*VerificationError)
+				vErr.Transcript = ti
+				vErr.Index = k
+				report.Err = vErr
+			}
+		}
+		switch {
+		case report.Err != nil:
+			report.Status = SignatureInvalid
+		case missing == len(s.Transcripts):
+			report.Status = SignatureMissing
+		case missing > 0:
+			report.Status = SignatureInvalid
+			report.Err = &VerificationError{Check: BLSSignatureCheck,
+				Transcript: -1, Element: "BLSSignatures", Index: k,
+				Err: fmt.Errorf("signature missing in %d of %d transcripts",
+					missing, len(s.Transcripts))}
+		default:
+			report.Status = SignatureValid
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// checkBLSSignature checks that sig is a valid BLS signature of the hashed
+// identity h for the given PotPubKey: e(sig, [1]₂) == e(h, [p]₂)
+func checkBLSSignature(pairing *bls12381.Engine, h, sig *bls12381.PointG1,
+	potPubKey *bls12381.PointG2) error {
+	if err := checkG1PointCorrectness(sig); err != nil {
+		return &VerificationError{Check: PointValidityCheck, Transcript: -1,
+			Element: "BLSSignatures", Index: -1,
+			Point: g1PointToString(sig), Err: err}
+	}
+	if err := checkG2PointCorrectness(potPubKey); err != nil {
+		return &VerificationError{Check: PointValidityCheck, Transcript: -1,
+			Element: "PotPubKeys", Index: -1,
+			Point: g2PointToString(potPubKey), Err: err}
+	}
+	ok := pairing.AddPair(sig, g2.One()).AddPairInv(h, potPubKey).Check()
+	pairing.Reset()
+	if !ok {
+		return &VerificationError{Check: BLSSignatureCheck, Transcript: -1,
+			Element: "BLSSignatures", Index: -1, Point: g1PointToString(sig)}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package kzgceremony
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+func TestContributeWithIdentity(t *testing.T) {
+	c := qt.New(t)
+	j, err := ioutil.ReadFile("batch_contribution_10.json")
+	c.Assert(err, qt.IsNil)
+
+	bc := &BatchContribution{}
+	err = json.Unmarshal(j, bc)
+	c.Assert(err, qt.IsNil)
+	for i := 0; i < len(bc.Contributions); i++ {
+		c.Assert(bc.Contributions[i].BLSSignature, qt.IsNil)
+	}
+
+	identity := "git|6507765|arnaucube"
+	nb, err := bc.ContributeWithIdentity(
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"),
+		identity)
+	c.Assert(err, qt.IsNil)
+
+	// e([τ]H(identity), [1]₂) == e(H(identity), [τ]₂)
+	h, err := hashIdentity(identity)
+	c.Assert(err, qt.IsNil)
+	pairing := bls12381.NewEngine()
+	for i := 0; i < len(nb.Contributions); i++ {
+		sig := nb.Contributions[i].BLSSignature
+		c.Assert(sig, qt.IsNotNil)
+		c.Assert(pairing.AddPair(sig, g2.One()).
+			AddPairInv(h, nb.Contributions[i].PotPubKey).Check(), qt.IsTrue)
+		pairing.Reset()
+	}
+
+	// the signature is kept through the json marshalers
+	b, err := json.Marshal(nb)
+	c.Assert(err, qt.IsNil)
+	parsed := &BatchContribution{}
+	err = json.Unmarshal(b, parsed)
+	c.Assert(err, qt.IsNil)
+	for i := 0; i < len(nb.Contributions); i++ {
+		c.Assert(g1.Equal(parsed.Contributions[i].BLSSignature,
+			nb.Contributions[i].BLSSignature), qt.IsTrue)
+	}
+
+	// without identity, the signatures are left empty
+	nb, err = bc.Contribute(
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(nb.Contributions[0].BLSSignature, qt.IsNil)
+	b, err = json.Marshal(nb)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Contains, `"blsSignature":""`)
+}
+
+func TestStateContributeWithIdentity(t *testing.T) {
+	c := qt.New(t)
+
+	s := NewEmptyState([]int{8, 4}, []int{4, 4})
+	ns, err := s.ContributeWithIdentity(
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"),
+		"eth|0x33b187514f5ea150a007651bebc82eaaa5b1a6c6")
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(ns.ParticipantIDs, qt.DeepEquals,
+		[]string{"", "eth|0x33b187514f5ea150a007651bebc82eaaa5b1a6c6"})
+	c.Assert(ns.ParticipantECDSASignatures, qt.HasLen, 2)
+	for i := 0; i < len(ns.Transcripts); i++ {
+		w := ns.Transcripts[i].Witness
+		c.Assert(w.BLSSignatures, qt.HasLen, 2)
+		c.Assert(w.BLSSignatures[0], qt.IsNil)
+		c.Assert(w.BLSSignatures[1], qt.IsNotNil)
+	}
+	c.Assert(g1.Equal(ns.Transcripts[0].Witness.BLSSignatures[1],
+		ns.Transcripts[1].Witness.BLSSignatures[1]), qt.IsFalse)
+}
+
+func TestVerifyBLSSignaturesFromFile(t *testing.T) {
+	c := qt.New(t)
+	j, err := ioutil.ReadFile("current_state_10.json")
+	c.Assert(err, qt.IsNil)
+
+	s := &State{}
+	err = json.Unmarshal(j, s)
+	c.Assert(err, qt.IsNil)
+
+	reports, err := VerifyBLSSignatures(s)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reports, qt.HasLen, 9)
+	expected := map[int]SignatureStatus{
+		1: SignatureMissing, 2: SignatureValid, 3: SignatureMissing,
+		4: SignatureMissing, 5: SignatureValid, 6: SignatureValid,
+		7: SignatureMissing, 8: SignatureValid, 9: SignatureMissing,
+	}
+	for _, r := range reports {
+		c.Assert(r.Status, qt.Equals, expected[r.Index], qt.Commentf("%s", r.ParticipantID))
+		c.Assert(r.ParticipantID, qt.Equals, s.ParticipantIDs[r.Index])
+		c.Assert(r.Err, qt.IsNil)
+	}
+
+	// swap the identities of two participants that signed
+	s.ParticipantIDs[2], s.ParticipantIDs[5] = s.ParticipantIDs[5], s.ParticipantIDs[2]
+	reports, err = VerifyBLSSignatures(s)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reports[1].Status, qt.Equals, SignatureInvalid)
+	c.Assert(reports[4].Status, qt.Equals, SignatureInvalid)
+	var vErr *VerificationError
+	c.Assert(errors.As(reports[1].Err, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, BLSSignatureCheck)
+	c.Assert(vErr.Transcript, qt.Equals, 0)
+	c.Assert(vErr.Index, qt.Equals, 2)
+}
+
+func TestVerifyBLSSignatures(t *testing.T) {
+	c := qt.New(t)
+
+	s := NewEmptyState([]int{8, 4}, []int{4, 4})
+	s, err := s.ContributeWithIdentity(
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"),
+		"git|6507765|arnaucube")
+	c.Assert(err, qt.IsNil)
+	s, err = s.Contribute(
+		[]byte("2222222222222222222222222222222222222222222222222222222222222222"))
+	c.Assert(err, qt.IsNil)
+	s, err = s.ContributeWithIdentity(
+		[]byte("3333333333333333333333333333333333333333333333333333333333333333"),
+		"eth|0x33b187514f5ea150a007651bebc82eaaa5b1a6c6")
+	c.Assert(err, qt.IsNil)
+
+	reports, err := VerifyBLSSignatures(s)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reports, qt.HasLen, 3)
+	c.Assert(reports[0].Status, qt.Equals, SignatureValid)
+	c.Assert(reports[1].Status, qt.Equals, SignatureMissing)
+	c.Assert(reports[2].Status, qt.Equals, SignatureValid)
+
+	// remove the signature of one of the Transcripts
+	s.Transcripts[1].Witness.BLSSignatures[3] = nil
+	reports, err = VerifyBLSSignatures(s)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reports[2].Status, qt.Equals, SignatureInvalid)
+	c.Assert(reports[2].Err, qt.IsNotNil)
+
+	// lengths
+	s.Transcripts[0].Witness.BLSSignatures = s.Transcripts[0].Witness.BLSSignatures[:2]
+	_, err = VerifyBLSSignatures(s)
+	var vErr *VerificationError
+	c.Assert(errors.As(err, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, WitnessLengthCheck)
+}
@@ -1,6 +1,9 @@
 package client
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type errorMsg struct {
 	Message string `json:"message"`
@@ -29,6 +32,20 @@ type IDToken struct {
 	Sub      string `json:"sub"`
 }
 
+// Identity returns the participant identity as it appears in the
+// participantIds of the State, eg. "git|1234|username" or "eth|0x...", which
+// is signed in the contribution
+func (t IDToken) Identity() string {
+	switch strings.ToLower(t.Provider) {
+	case "github", "git":
+		return "git|" + t.Sub + "|" + t.Nickname
+	case "ethereum", "eth":
+		return "eth|" + strings.ToLower(t.Sub)
+	default:
+		return t.Sub
+	}
+}
+
 type MsgAuthCallback struct {
 	IDToken   IDToken `json:"id_token"`
 	SessionID string  `json:"session_id"`
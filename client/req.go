@@ -1,8 +1,8 @@
 package client
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -65,14 +65,14 @@ func (c *Client) GetCurrentState() (*kzgceremony.State, error) {
 		return nil, fmt.Errorf("unexpected http code: %d", resp.StatusCode)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
+	defer resp.Body.Close()
+
+	state := &kzgceremony.State{}
+	err = kzgceremony.NewStateDecoder(resp.Body).Decode(state)
 	if err != nil {
 		return nil, err
 	}
-
-	var state *kzgceremony.State
-	err = json.Unmarshal(body, &state)
-	return state, err
+	return state, nil
 }
 
 func (c *Client) GetRequestLink() (*MsgRequestLink, error) {
@@ -215,34 +215,83 @@ func (c *Client) PostAbortContribution(sessionID string) ([]byte, error) {
 	return body, nil
 }
 
+// PostContribute uploads the BatchContribution, streaming its JSON encoding
+// as the request body
 func (c *Client) PostContribute(sessionID string, bc *kzgceremony.BatchContribution) (*MsgContributeReceipt, error) {
+	return c.PostContributeTee(sessionID, bc, nil)
+}
+
+// PostContributeTee acts as PostContribute, additionally writing the same
+// JSON encoding to w (eg. the local contribution.json file) while it is
+// uploaded. w receives the whole encoding even when the request fails.
+func (c *Client) PostContributeTee(sessionID string, bc *kzgceremony.BatchContribution,
+	w io.Writer) (*MsgContributeReceipt, error) {
 	bearer := "Bearer " + sessionID
 
-	jsonBC, err := json.Marshal(bc)
+	pr, pw := io.Pipe()
+	encoded := make(chan error, 1)
+	go func() {
+		var out io.Writer = pw
+		if w != nil {
+			out = &teeWriter{w: w, pipe: pw}
+		}
+		err := kzgceremony.NewBatchContributionEncoder(out).Encode(bc)
+		_ = pw.CloseWithError(err)
+		encoded <- err
+	}()
+
+	resp, err := c.postWithAuth(
+		c.url+"/contribute", "application/json", pr, bearer)
+	// unblock the encoder in case that the request did not consume the
+	// whole body
+	_ = pr.Close()
+	encErr := <-encoded
 	if err != nil {
+		if encErr != nil {
+			return nil, encErr
+		}
 		return nil, err
 	}
+	defer resp.Body.Close()
+	// the sequencer can answer before reading the whole body (eg. when it
+	// is not the turn of the participant), closing the pipe of the encoder
+	if encErr != nil && !errors.Is(encErr, io.ErrClosedPipe) {
+		return nil, encErr
+	}
 
-	resp, err := c.postWithAuth(
-		c.url+"/contribute", "application/json", bytes.NewBuffer(jsonBC), bearer)
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
 		switch resp.StatusCode {
 		case http.StatusBadRequest:
-			return nil, fmt.Errorf("invalid request")
+			return nil, fmt.Errorf("invalid request: %s", strings.TrimSpace(string(body)))
 		default:
 			return nil, fmt.Errorf("unexpected http code: %d", resp.StatusCode)
 		}
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
 	var msg MsgContributeReceipt
 	err = json.Unmarshal(body, &msg)
 	return &msg, err
 }
+
+// teeWriter writes to w and to the pipe. Once the pipe fails (eg. the
+// request was aborted) it keeps writing to w only, so that w always gets the
+// complete output.
+type teeWriter struct {
+	w       io.Writer
+	pipe    io.Writer
+	pipeErr error
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	if n, err := t.w.Write(p); err != nil {
+		return n, err
+	}
+	if t.pipeErr == nil {
+		_, t.pipeErr = t.pipe.Write(p)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// newTestBatch returns a BatchContribution whose JSON encoding does not fit
+// in the buffers of the connection
+func newTestBatch(c *qt.C) (*kzgceremony.BatchContribution, []byte) {
+	g1 := bls12381.NewG1()
+	g2 := bls12381.NewG2()
+	bc := &kzgceremony.BatchContribution{}
+	for _, n := range []int{4096, 4096} {
+		srs := &kzgceremony.SRS{
+			G1Powers: make([]*bls12381.PointG1, n),
+			G2Powers: make([]*bls12381.PointG2, 65),
+		}
+		for i := range srs.G1Powers {
+			srs.G1Powers[i] = g1.One()
+		}
+		for i := range srs.G2Powers {
+			srs.G2Powers[i] = g2.One()
+		}
+		bc.Contributions = append(bc.Contributions, kzgceremony.Contribution{
+			NumG1Powers: uint64(n),
+			NumG2Powers: 65,
+			PowersOfTau: srs,
+			PotPubKey:   g2.One(),
+		})
+	}
+	b, err := json.Marshal(bc)
+	c.Assert(err, qt.IsNil)
+	return bc, b
+}
+
+func TestPostContributeTee(t *testing.T) {
+	c := qt.New(t)
+	bc, expected := newTestBatch(c)
+
+	var received []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Header.Get("Authorization"), qt.Equals, "Bearer session")
+		received, _ = ioutil.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"receipt":"r","signature":"s"}`))
+	}))
+	defer ts.Close()
+	cl := NewClient(ts.URL)
+
+	var tee bytes.Buffer
+	receipt, err := cl.PostContributeTee("session", bc, &tee)
+	c.Assert(err, qt.IsNil)
+	c.Assert(*receipt, qt.Equals, MsgContributeReceipt{Receipt: "r", Signature: "s"})
+	c.Assert(string(received), qt.Equals, string(expected))
+	c.Assert(tee.String(), qt.Equals, string(expected))
+}
+
+func TestPostContributeEarlyAnswer(t *testing.T) {
+	c := qt.New(t)
+	bc, expected := newTestBatch(c)
+
+	// the server answers without reading the body, as the sequencer does
+	// when it is not the turn of the participant
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":"ContributeError::NotUsersTurn",` +
+			`"error":"not your turn to participate"}`))
+	}))
+	defer ts.Close()
+	cl := NewClient(ts.URL)
+
+	_, err := cl.PostContribute("session", bc)
+	c.Assert(err, qt.ErrorMatches, "invalid request: .*NotUsersTurn.*")
+
+	var tee bytes.Buffer
+	_, err = cl.PostContributeTee("session", bc, &tee)
+	c.Assert(err, qt.ErrorMatches, "invalid request: .*NotUsersTurn.*")
+	c.Assert(tee.String(), qt.Equals, string(expected))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+	"github.com/arnaucube/eth-kzg-ceremony-alt/client"
+)
+
+// defaultSizes returns the EthereumCeremonySizes in the g1:g2 format of the
+// --expected-sizes flag
+func defaultSizes() []string {
+	sizes := make([]string, len(kzgceremony.EthereumCeremonySizes))
+	for i, s := range kzgceremony.EthereumCeremonySizes {
+		sizes[i] = fmt.Sprintf("%d:%d", s.NumG1Powers, s.NumG2Powers)
+	}
+	return sizes
+}
+
+// parseSizes parses the g1:g2 values of the --expected-sizes flag. Returns
+// nil when there are no values, which skips the sizes check.
+func parseSizes(sizes []string) ([]kzgceremony.CeremonySize, error) {
+	if len(sizes) == 0 {
+		return nil, nil
+	}
+	r := make([]kzgceremony.CeremonySize, len(sizes))
+	for i, s := range sizes {
+		parts := strings.Split(s, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid expected size %q, must be g1:g2", s)
+		}
+		g1, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expected size %q: %w", s, err)
+		}
+		g2, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expected size %q: %w", s, err)
+		}
+		r[i] = kzgceremony.CeremonySize{NumG1Powers: g1, NumG2Powers: g2}
+	}
+	return r, nil
+}
+
+// checkReceivedBatch checks the batch received from the sequencer against
+// the expected sizes and the current state of the sequencer, and prints the
+// report. When a check fails, it asks whether to contribute anyway. Returns
+// false when the contribution has to be aborted.
+func checkReceivedBatch(c *client.Client, bc *kzgceremony.BatchContribution,
+	expected []kzgceremony.CeremonySize) bool {
+	fmt.Println("checking the batch received from the sequencer")
+	t0 := time.Now()
+	state, stateErr := c.GetCurrentState()
+	if stateErr != nil {
+		stateErr = fmt.Errorf("could not get the current state: %w", stateErr)
+	}
+	report := kzgceremony.CheckReceivedBatch(bc, expected, state,
+		kzgceremony.VerifyBatch)
+	if stateErr == nil {
+		stateErr = report.StateErr
+	}
+
+	fmt.Println("Batch report:")
+	for i, contribution := range bc.Contributions {
+		fmt.Printf("  sub-ceremony %d: %d G1 powers, %d G2 powers\n", i,
+			contribution.NumG1Powers, contribution.NumG2Powers)
+	}
+	printCheck("sizes", expected == nil, report.SizesErr)
+	printCheck("powers of tau structure", false, report.StructureErr)
+	printCheck("running product of the current state", false, stateErr)
+	fmt.Println("Batch checked in", time.Since(t0))
+
+	if report.SizesErr == nil && report.StructureErr == nil && stateErr == nil {
+		return true
+	}
+	_, _ = redB.Println("The batch received from the sequencer did not pass all the checks.")
+	_, _ = greenB.Printf("Contribute on top of it anyway? (y/N):\n")
+	answer, err := readInput()
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func printCheck(name string, skipped bool, err error) {
+	switch {
+	case skipped:
+		fmt.Printf("  %s: skipped\n", name)
+	case err != nil:
+		_, _ = red.Printf("  %s: FAILED, %v\n", name, err)
+	default:
+		_, _ = green.Printf("  %s: ok\n", name)
+	}
+}
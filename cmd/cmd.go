@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
 	"github.com/arnaucube/eth-kzg-ceremony-alt/client"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/fatih/color"
 	flag "github.com/spf13/pflag"
 )
@@ -32,16 +34,45 @@ func main() {
 	var sequencerURL string
 	var randomness string
 	var sleepTime uint64
+	var workers int
+	var ethKeyHex string
+	var ethKeystore string
+	var entropyFile string
+	var entropyCmd string
+	var sizes []string
 	flag.StringVarP(&sequencerURL, "url", "u",
 		"https://seq.ceremony.ethereum.org", "sequencer url")
 	flag.StringVarP(&randomness, "rand", "r",
 		"", fmt.Sprintf("randomness, needs to be bigger than %d bytes", kzgceremony.MinRandomnessLen))
 	flag.Uint64VarP(&sleepTime, "sleeptime", "s",
 		30, "time (seconds) sleeping before trying again to be the next contributor")
+	flag.IntVarP(&workers, "workers", "w",
+		0, "number of goroutines used to compute the contribution (default number of CPUs)")
+	flag.StringVar(&ethKeyHex, "eth-key",
+		"", "hex secp256k1 private key, to participate with an Ethereum identity")
+	flag.StringVar(&ethKeystore, "eth-keystore",
+		"", "path to an Ethereum keystore file, to participate with an Ethereum identity")
+	flag.StringVar(&entropyFile, "entropy-file",
+		"", "optional file used as additional source of entropy")
+	flag.StringVar(&entropyCmd, "entropy-cmd",
+		"", "optional command whose output is used as additional source of entropy")
+	flag.StringSliceVar(&sizes, "expected-sizes",
+		defaultSizes(), "expected number of G1 and G2 powers of each sub-ceremony,"+
+			" as g1:g2 (empty to skip the check)")
 
 	flag.CommandLine.SortFlags = false
 	flag.Parse()
 
+	kzgceremony.NumWorkers = workers
+	expectedSizes, err := parseSizes(sizes)
+	if err != nil {
+		printErrAndExit(err)
+	}
+	entropyCmdFields := strings.Fields(entropyCmd)
+	if entropyCmd != "" && len(entropyCmdFields) == 0 {
+		printErrAndExit(fmt.Errorf("--entropy-cmd is set but does not contain a command"))
+	}
+
 	c := client.NewClient(sequencerURL)
 
 	// get status
@@ -63,9 +94,27 @@ func main() {
 		os.Exit(1)
 	}
 
+	ethKey, err := loadEthKey(ethKeyHex, ethKeystore)
+	if err != nil {
+		printErrAndExit(err)
+	}
+
 	// Auth
-	fmt.Println("Github Authorization:")
-	authMsg := authGH(c)
+	var authMsg client.MsgAuthCallback
+	if ethKey != nil {
+		fmt.Println("Ethereum Authorization, with address",
+			kzgceremony.EthAddress(ethKey.PubKey()))
+		authMsg = authEth(c)
+		address := kzgceremony.EthAddress(ethKey.PubKey())
+		if authMsg.IDToken.Identity() != "eth|"+address {
+			printErrAndExit(fmt.Errorf("authenticated identity %s does not match"+
+				" the address of the Ethereum key %s",
+				authMsg.IDToken.Identity(), address))
+		}
+	} else {
+		fmt.Println("Github Authorization:")
+		authMsg = authGH(c)
+	}
 
 	// TODO this will be only triggered by a flag
 	// msg, err := c.PostAbortContribution(authMsg.SessionID)
@@ -89,8 +138,13 @@ func main() {
 			break
 		}
 		if status == client.StatusReauth {
-			fmt.Println("SessionID has expired, authenticate again with Github:")
-			authMsg = authGH(c)
+			if ethKey != nil {
+				fmt.Println("SessionID has expired, authenticate again with Ethereum:")
+				authMsg = authEth(c)
+			} else {
+				fmt.Println("SessionID has expired, authenticate again with Github:")
+				authMsg = authGH(c)
+			}
 		}
 		msgStatus, err := c.GetCurrentStatus()
 		if err != nil {
@@ -104,38 +158,100 @@ func main() {
 		time.Sleep(time.Duration(sleepTime) * time.Second)
 	}
 
-	// get latest state
-	// currentState, err := c.GetCurrentState()
-	// if err != nil {
-	//         red.Println(err)
-	//	   os.Exit(1)
-	// }
+	// check the batch received from the sequencer before contributing on
+	// top of it
+	if !checkReceivedBatch(c, prevBatchContribution, expectedSizes) {
+		_, _ = redB.Println("Aborting the contribution")
+		// release the slot, so that the next participant can contribute
+		if _, err := c.PostAbortContribution(authMsg.SessionID); err != nil {
+			_, _ = red.Println(err)
+		}
+		os.Exit(1)
+	}
+
+	// mix the user randomness with the other sources of entropy
+	pool := kzgceremony.NewEntropyPool(
+		kzgceremony.UserEntropy([]byte(randomness)),
+		kzgceremony.CryptoRandEntropy(64),
+		kzgceremony.SystemEntropy(),
+	)
+	if entropyFile != "" {
+		pool.Add(kzgceremony.FileEntropy(entropyFile))
+	}
+	if len(entropyCmdFields) > 0 {
+		pool.Add(kzgceremony.CommandEntropy(entropyCmdFields[0], entropyCmdFields[1:]...))
+	}
+	pool.Logf = func(format string, a ...interface{}) {
+		fmt.Printf("  "+format, a...)
+	}
+	fmt.Println("mixing entropy sources:")
+	mixedRandomness, err := pool.Randomness()
+	if err != nil {
+		printErrAndExit(err)
+	}
 
 	fmt.Println("starting to compute new contribution")
 	t0 := time.Now()
-	newBatchContribution, err := prevBatchContribution.Contribute([]byte(randomness))
+	newBatchContribution, err := prevBatchContribution.ContributeWithIdentity(
+		mixedRandomness, authMsg.IDToken.Identity())
 	if err != nil {
-		fmt.Println("error on prevBatchContribution.Contribute")
+		fmt.Println("error on prevBatchContribution.ContributeWithIdentity")
 		printErrAndExit(err)
 	}
 	fmt.Println("Contribution computed in", time.Since(t0))
 
-	// store contribution
-	fmt.Println("storing contribution.json")
-	b, err := json.Marshal(newBatchContribution)
+	if ethKey != nil {
+		fmt.Println("signing the contribution pot pubkeys with the Ethereum key")
+		if err = newBatchContribution.SignECDSA(ethKey); err != nil {
+			printErrAndExit(err)
+		}
+	}
+
+	// verify the computed contribution and its signatures before sending
+	// it, as the sequencer does, so that an invalid contribution is never
+	// uploaded
+	fmt.Println("verifying the computed contribution")
+	t0 = time.Now()
+	err = kzgceremony.CheckBatchContributionWithIdentity(prevBatchContribution,
+		newBatchContribution, authMsg.IDToken.Identity(), kzgceremony.VerifyBatch)
 	if err != nil {
-		// print error but do not exit
+		_, _ = redB.Println("The computed contribution is not valid, it will not be uploaded:")
 		_, _ = red.Println(err)
+		dir, derr := writeDiagnosticBundle(prevBatchContribution,
+			newBatchContribution, authMsg.IDToken.Identity(), err)
+		if derr != nil {
+			_, _ = red.Println("error storing the diagnostic bundle:", derr)
+		} else {
+			fmt.Println("diagnostic bundle stored at", dir)
+		}
+		// release the slot, so that the next participant can contribute
+		if _, aerr := c.PostAbortContribution(authMsg.SessionID); aerr != nil {
+			_, _ = red.Println(aerr)
+		}
+		os.Exit(1)
 	}
-	err = ioutil.WriteFile("contribution.json", b, 0600)
+	fmt.Println("Contribution verified in", time.Since(t0))
+
+	// send contribution, storing it at contribution.json while it is
+	// uploaded
+	fmt.Println("sending contribution & storing contribution.json")
+	var contributionFile io.Writer
+	f, err := os.OpenFile("contribution.json",
+		os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		// print error but do not exit
 		_, _ = red.Println(err)
+	} else {
+		contributionFile = f
+	}
+	receipt, err := c.PostContributeTee(authMsg.SessionID,
+		newBatchContribution, contributionFile)
+	if f != nil {
+		if cerr := f.Close(); cerr != nil {
+			// print error but do not exit
+			_, _ = red.Println(cerr)
+		}
 	}
-
-	// send contribution
-	fmt.Println("sending contribution")
-	receipt, err := c.PostContribute(authMsg.SessionID, newBatchContribution)
 	if err != nil {
 		printErrAndExit(err)
 	}
@@ -144,7 +260,7 @@ func main() {
 
 	// store receipt
 	fmt.Println("storing contribution_receipt.json")
-	b, err = json.Marshal(receipt)
+	b, err := json.Marshal(receipt)
 	if err != nil {
 		printErrAndExit(err)
 	}
@@ -161,8 +277,23 @@ func authGH(c *client.Client) client.MsgAuthCallback {
 	}
 
 	_, _ = green.Printf("Please go to\n%s\n and authenticate with Github.\n", msgReqLink.GithubAuthURL)
-	fmt.Println("(currently only Github auth is supported)")
 
+	return readAuthMsg()
+}
+
+func authEth(c *client.Client) client.MsgAuthCallback {
+	msgReqLink, err := c.GetRequestLink()
+	if err != nil {
+		printErrAndExit(err)
+	}
+
+	_, _ = green.Printf("Please go to\n%s\n and authenticate with the Ethereum account"+
+		" of the given key.\n", msgReqLink.EthAuthURL)
+
+	return readAuthMsg()
+}
+
+func readAuthMsg() client.MsgAuthCallback {
 	_, _ = greenB.Printf("Paste here the RawData from the auth answer:\n")
 	s, err := readInput()
 	if err != nil {
@@ -177,6 +308,30 @@ func authGH(c *client.Client) client.MsgAuthCallback {
 	return authMsg
 }
 
+// loadEthKey loads the Ethereum key either from the hex string or from the
+// keystore file, asking for its password. Returns nil when none is set.
+func loadEthKey(keyHex, keystorePath string) (*secp256k1.PrivateKey, error) {
+	if keyHex != "" && keystorePath != "" {
+		return nil, fmt.Errorf("only one of --eth-key and --eth-keystore can be set")
+	}
+	if keyHex != "" {
+		return kzgceremony.EthKeyFromHex(keyHex)
+	}
+	if keystorePath == "" {
+		return nil, nil
+	}
+	keystore, err := ioutil.ReadFile(keystorePath)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = greenB.Printf("Keystore password:\n")
+	password, err := readInput()
+	if err != nil {
+		return nil, err
+	}
+	return kzgceremony.EthKeyFromKeystore(keystore, password)
+}
+
 func printErrAndExit(err error) {
 	_, _ = red.Println(err)
 	os.Exit(1)
@@ -0,0 +1,180 @@
+// Command coordinator runs a private KZG ceremony, serving the sequencer API
+// (see the sequencer package) that the contributors, such as the kzgceremony
+// command, use to participate.
+//
+// The State is stored after every contribution, so that the coordinator can
+// be restarted at any moment continuing from the last accepted contribution.
+// The participants are authenticated with the fake auth provider of the
+// sequencer package, which trusts the identity claimed by each participant,
+// so it is only meant for ceremonies between trusted parties.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+	"github.com/arnaucube/eth-kzg-ceremony-alt/sequencer"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/fatih/color"
+	flag "github.com/spf13/pflag"
+)
+
+var (
+	red    = color.New(color.FgRed)
+	cyan   = color.New(color.FgCyan)
+	greenB = color.New(color.FgHiGreen, color.Bold)
+)
+
+func main() {
+	fmt.Println("eth-kzg-ceremony-alt coordinator")
+	fmt.Printf("================================\n\n")
+
+	var addr string
+	var statePath string
+	var nG1s []int
+	var nG2s []int
+	var keyHex string
+	var keystorePath string
+	var computeDeadline time.Duration
+	var lobbyCheckin time.Duration
+	var rateLimit time.Duration
+	var batchVerification bool
+	var workers int
+	flag.StringVarP(&addr, "addr", "a",
+		":8080", "address where the sequencer API is served")
+	flag.StringVar(&statePath, "state",
+		"state.json", "file where the ceremony state is stored, created if it does not exist")
+	flag.IntSliceVar(&nG1s, "g1-powers",
+		[]int{4096, 8192, 16384, 32768}, "number of G1 powers of each sub-ceremony of a new ceremony")
+	flag.IntSliceVar(&nG2s, "g2-powers",
+		[]int{65, 65, 65, 65}, "number of G2 powers of each sub-ceremony of a new ceremony")
+	flag.StringVar(&keyHex, "key",
+		"", "hex secp256k1 private key used to sign the receipts")
+	flag.StringVar(&keystorePath, "keystore",
+		"", "path to an Ethereum keystore file with the key used to sign the receipts")
+	flag.DurationVar(&computeDeadline, "compute-deadline",
+		180*time.Second, "time a participant has to upload its contribution once selected")
+	flag.DurationVar(&lobbyCheckin, "lobby-checkin",
+		90*time.Second, "time after which the participants that stop calling try_contribute leave the lobby")
+	flag.DurationVar(&rateLimit, "rate-limit",
+		0, "minimum time between two try_contribute calls of a participant (0 disables it)")
+	flag.BoolVar(&batchVerification, "batch-verification",
+		true, "check the powers of tau structure with a random linear combination instead of pairing by pairing")
+	flag.IntVarP(&workers, "workers", "w",
+		0, "number of goroutines used to verify the contributions (default number of CPUs)")
+
+	flag.CommandLine.SortFlags = false
+	flag.Parse()
+
+	kzgceremony.NumWorkers = workers
+	mode := kzgceremony.VerifyStrict
+	if batchVerification {
+		mode = kzgceremony.VerifyBatch
+	}
+
+	state, err := loadState(statePath, nG1s, nG2s, mode)
+	if err != nil {
+		printErrAndExit(err)
+	}
+
+	key, err := loadKey(keyHex, keystorePath)
+	if err != nil {
+		printErrAndExit(err)
+	}
+	if key == nil {
+		_, _ = red.Println("no --key nor --keystore set, the receipts are signed with a" +
+			" new random key, which will change after a restart")
+	}
+
+	srv, err := sequencer.New(state, sequencer.Config{
+		Key:                   key,
+		Mode:                  mode,
+		TryContributeInterval: rateLimit,
+		ComputeDeadline:       computeDeadline,
+		LobbyCheckinTimeout:   lobbyCheckin,
+		StatePath:             statePath,
+	})
+	if err != nil {
+		printErrAndExit(err)
+	}
+
+	fmt.Println("sequencer address:", srv.Address())
+	_, _ = greenB.Printf("serving the sequencer API at %s\n", addr)
+	if err := http.ListenAndServe(addr, srv); err != nil {
+		printErrAndExit(err)
+	}
+}
+
+// loadState reads the State stored at path, checking it, or creates and
+// stores the State of a new ceremony with the given sizes when path does not
+// exist
+func loadState(path string, nG1s, nG2s []int,
+	mode kzgceremony.VerificationMode) (*kzgceremony.State, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if len(nG1s) == 0 || len(nG1s) != len(nG2s) {
+			return nil, fmt.Errorf("--g1-powers and --g2-powers must have the same" +
+				" number of sub-ceremonies")
+		}
+		for i := range nG1s {
+			if nG1s[i] < 2 || nG2s[i] < 2 {
+				return nil, fmt.Errorf("each sub-ceremony needs at least 2 G1" +
+					" and 2 G2 powers")
+			}
+		}
+		fmt.Printf("creating a new ceremony at %s, G1 powers %v, G2 powers %v\n",
+			path, nG1s, nG2s)
+		state := kzgceremony.NewEmptyState(nG1s, nG2s)
+		return state, sequencer.WriteStateFile(path, state)
+	}
+
+	fmt.Printf("loading the ceremony state from %s\n", path)
+	state, err := sequencer.ReadStateFile(path)
+	if err != nil {
+		return nil, err
+	}
+	n := len(state.ParticipantIDs) - 1
+	if n > 0 {
+		// the genesis State does not contain any contribution to check
+		if err := kzgceremony.CheckState(state, mode); err != nil {
+			return nil, err
+		}
+	}
+	_, _ = cyan.Printf("state with %d contributions\n", n)
+	return state, nil
+}
+
+// loadKey loads the key either from the hex string or from the keystore
+// file, asking for its password. Returns nil when none is set.
+func loadKey(keyHex, keystorePath string) (*secp256k1.PrivateKey, error) {
+	if keyHex != "" && keystorePath != "" {
+		return nil, fmt.Errorf("only one of --key and --keystore can be set")
+	}
+	if keyHex != "" {
+		return kzgceremony.EthKeyFromHex(keyHex)
+	}
+	if keystorePath == "" {
+		return nil, nil
+	}
+	keystore, err := ioutil.ReadFile(keystorePath)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Keystore password:")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	password = strings.TrimSuffix(password, "\n")
+	return kzgceremony.EthKeyFromKeystore(keystore, password)
+}
+
+func printErrAndExit(err error) {
+	_, _ = red.Println(err)
+	os.Exit(1)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+)
+
+// diagnosticReport describes the check that failed when verifying the
+// computed contribution, stored as report.json in the diagnostic bundle
+type diagnosticReport struct {
+	Time       string `json:"time"`
+	Identity   string `json:"identity"`
+	Workers    int    `json:"workers"`
+	Error      string `json:"error"`
+	Check      string `json:"check,omitempty"`
+	Transcript *int   `json:"transcript,omitempty"`
+	Element    string `json:"element,omitempty"`
+	Index      *int   `json:"index,omitempty"`
+	Point      string `json:"point,omitempty"`
+}
+
+// writeDiagnosticBundle stores in a new directory the batch received from
+// the sequencer (the input), the computed contribution (the output) and the
+// report of the failed verification, so that the failure can be
+// investigated. It returns the path of the directory.
+func writeDiagnosticBundle(prev, next *kzgceremony.BatchContribution,
+	identity string, verifErr error) (string, error) {
+	now := time.Now()
+	dir := "diagnostic_" + now.Format("20060102_150405")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return "", err
+	}
+
+	report := diagnosticReport{
+		Time:     now.Format(time.RFC3339),
+		Identity: identity,
+		Workers:  kzgceremony.NumWorkers,
+		Error:    verifErr.Error(),
+	}
+	var vErr *kzgceremony.VerificationError
+	if errors.As(verifErr, &vErr) {
+		report.Check = vErr.Check.String()
+		if vErr.Transcript >= 0 {
+			report.Transcript = &vErr.Transcript
+		}
+		report.Element = vErr.Element
+		if vErr.Index >= 0 {
+			report.Index = &vErr.Index
+		}
+		report.Point = vErr.Point
+	}
+
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{"prevBatchContribution.json", prev},
+		{"newBatchContribution.json", next},
+		{"report.json", report},
+	}
+	for _, f := range files {
+		b, err := json.MarshalIndent(f.v, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", f.name, err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, f.name), b, 0600); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
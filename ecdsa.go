@@ -0,0 +1,229 @@
+package kzgceremony
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	bls12381 "github.com/kilic/bls12-381"
+	"golang.org/x/crypto/sha3"
+)
+
+// EIP-712 domain used by the official Ethereum KZG Ceremony to sign the
+// PotPubKeys of a contribution
+const (
+	eip712DomainName    = "Ethereum KZG Ceremony"
+	eip712DomainVersion = "1.0"
+	eip712ChainID       = 1
+)
+
+var (
+	eip712DomainTypeHash = keccak256([]byte(
+		"EIP712Domain(string name,string version,uint256 chainId)"))
+	contributionPubkeyType     = "contributionPubkey(uint256 numG1Powers,uint256 numG2Powers,bytes potPubkey)"
+	contributionPubkeyTypeHash = keccak256([]byte(contributionPubkeyType))
+	potPubkeysTypeHash         = keccak256([]byte(
+		"PoTPubkeys(contributionPubkey[] potPubkeys)" + contributionPubkeyType))
+)
+
+// PotPubKeyEntry contains the values of a Transcript (or Contribution) that
+// are signed with ECDSA by the Ethereum participants
+type PotPubKeyEntry struct {
+	NumG1Powers uint64
+	NumG2Powers uint64
+	PotPubKey   *bls12381.PointG2
+}
+
+// PotPubKeysTypedDataHash returns the EIP-712 hash of the typed data
+//
+//	PoTPubkeys(contributionPubkey[] potPubkeys)
+//	contributionPubkey(uint256 numG1Powers,uint256 numG2Powers,bytes potPubkey)
+//
+// in the domain {name: "Ethereum KZG Ceremony", version: "1.0", chainId: 1},
+// which is the message signed by the Ethereum participants
+func PotPubKeysTypedDataHash(entries []PotPubKeyEntry) []byte {
+	var hashes []byte
+	for _, e := range entries {
+		h := keccak256(
+			contributionPubkeyTypeHash,
+			uint256Bytes(e.NumG1Powers),
+			uint256Bytes(e.NumG2Powers),
+			keccak256(g2.ToCompressed(e.PotPubKey)),
+		)
+		hashes = append(hashes, h...)
+	}
+	message := keccak256(potPubkeysTypeHash, keccak256(hashes))
+	domain := keccak256(
+		eip712DomainTypeHash,
+		keccak256([]byte(eip712DomainName)),
+		keccak256([]byte(eip712DomainVersion)),
+		uint256Bytes(eip712ChainID),
+	)
+	return keccak256([]byte{0x19, 0x01}, domain, message)
+}
+
+// potPubKeyEntries returns the PotPubKeyEntry of each Contribution
+func (bc *BatchContribution) potPubKeyEntries() []PotPubKeyEntry {
+	entries := make([]PotPubKeyEntry, len(bc.Contributions))
+	for i, c := range bc.Contributions {
+		entries[i] = PotPubKeyEntry{c.NumG1Powers, c.NumG2Powers, c.PotPubKey}
+	}
+	return entries
+}
+
+// SignECDSA signs the PotPubKeys of the BatchContribution following EIP-712
+// with the given secp256k1 key, and stores the signature in ECDSASignature
+func (bc *BatchContribution) SignECDSA(sk *secp256k1.PrivateKey) error {
+	sig, err := SignPotPubKeys(sk, bc.potPubKeyEntries())
+	if err != nil {
+		return err
+	}
+	bc.ECDSASignature = sig
+	return nil
+}
+
+// SignPotPubKeys signs the EIP-712 hash of the given entries with the
+// secp256k1 key, returning the signature as 0x prefixed hex of r || s || v,
+// with v ∈ {27, 28}
+func SignPotPubKeys(sk *secp256k1.PrivateKey, entries []PotPubKeyEntry) (string, error) {
+	for _, e := range entries {
+		if e.PotPubKey == nil {
+			return "", fmt.Errorf("empty PotPubKey")
+		}
+	}
+	compact := ecdsa.SignCompact(sk, PotPubKeysTypedDataHash(entries), false)
+	// compact signature is v || r || s, while Ethereum uses r || s || v
+	sig := append(compact[1:], compact[0])
+	return "0x" + hex.EncodeToString(sig), nil
+}
+
+// RecoverPotPubKeysSigner returns the Ethereum address (0x prefixed lower
+// case hex) of the key that produced the given signature over the EIP-712
+// hash of the entries
+func RecoverPotPubKeysSigner(sigHex string, entries []PotPubKeyEntry) (string, error) {
+	sig, err := hex.DecodeString(strings.TrimPrefix(sigHex, "0x"))
+	if err != nil {
+		return "", err
+	}
+	if len(sig) != 65 {
+		return "", fmt.Errorf("invalid ECDSA signature length: %d", len(sig))
+	}
+	v := sig[64]
+	if v < 27 {
+		v += 27
+	}
+	compact := append([]byte{v}, sig[:64]...)
+	pk, _, err := ecdsa.RecoverCompact(compact, PotPubKeysTypedDataHash(entries))
+	if err != nil {
+		return "", err
+	}
+	return EthAddress(pk), nil
+}
+
+// EthAddress returns the Ethereum address (0x prefixed lower case hex) of
+// the given secp256k1 public key
+func EthAddress(pk *secp256k1.PublicKey) string {
+	h := keccak256(pk.SerializeUncompressed()[1:])
+	return "0x" + hex.EncodeToString(h[12:])
+}
+
+// VerifyECDSASignatures checks, for each Ethereum participant of the State
+// (participant id "eth|0x..."), that the ParticipantECDSASignatures entry is
+// a valid EIP-712 signature of the PotPubKeys of the participant, done by the
+// address of its participant id. Empty signatures are reported as
+// SignatureMissing. Participants that are not Ethereum addresses are not
+// reported, unless they have a non-empty ECDSA signature, which is reported
+// as SignatureInvalid.
+func VerifyECDSASignatures(s *State) ([]SignatureReport, error) {
+	n := len(s.ParticipantIDs)
+	if len(s.ParticipantECDSASignatures) != n {
+		return nil, &VerificationError{Check: WitnessLengthCheck, Transcript: -1,
+			Element: "ParticipantECDSASignatures", Index: -1,
+			Err: fmt.Errorf("length %d, while there are %d ParticipantIDs",
+				len(s.ParticipantECDSASignatures), n)}
+	}
+	for ti, t := range s.Transcripts {
+		if t.Witness == nil || len(t.Witness.PotPubKeys) != n {
+			return nil, &VerificationError{Check: WitnessLengthCheck,
+				Transcript: ti, Element: "PotPubKeys", Index: -1,
+				Err: fmt.Errorf("PotPubKeys must have the same length than"+
+					" the %d ParticipantIDs", n)}
+		}
+	}
+
+	var reports []SignatureReport
+	for k := 1; k < n; k++ {
+		id := s.ParticipantIDs[k]
+		sig := s.ParticipantECDSASignatures[k]
+		isEth := strings.HasPrefix(id, "eth|")
+		if !isEth && sig == "" {
+			continue
+		}
+		report := SignatureReport{Index: k, ParticipantID: id}
+		switch {
+		case !isEth:
+			report.Status = SignatureInvalid
+			report.Err = &VerificationError{Check: ECDSASignatureCheck,
+				Transcript: -1, Element: "ParticipantECDSASignatures", Index: k,
+				Err: fmt.Errorf("ECDSA signature of a non Ethereum participant")}
+		case sig == "":
+			report.Status = SignatureMissing
+		default:
+			entries := make([]PotPubKeyEntry, len(s.Transcripts))
+			for ti, t := range s.Transcripts {
+				entries[ti] = PotPubKeyEntry{t.NumG1Powers, t.NumG2Powers,
+					t.Witness.PotPubKeys[k]}
+			}
+			if err := checkECDSASignature(sig, strings.TrimPrefix(id, "eth|"),
+				entries); err != nil {
+				report.Status = SignatureInvalid
+				err.Index = k
+				report.Err = err
+			} else {
+				report.Status = SignatureValid
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// checkECDSASignature checks that the given signature of the entries was
+// done by the given Ethereum address
+func checkECDSASignature(sig, address string, entries []PotPubKeyEntry) *VerificationError {
+	for _, e := range entries {
+		if e.PotPubKey == nil {
+			return &VerificationError{Check: ECDSASignatureCheck,
+				Transcript: -1, Element: "PotPubKeys", Index: -1,
+				Err: fmt.Errorf("empty PotPubKey")}
+		}
+	}
+	signer, err := RecoverPotPubKeysSigner(sig, entries)
+	if err != nil {
+		return &VerificationError{Check: ECDSASignatureCheck, Transcript: -1,
+			Element: "ParticipantECDSASignatures", Index: -1, Err: err}
+	}
+	if signer != strings.ToLower(address) {
+		return &VerificationError{Check: ECDSASignatureCheck, Transcript: -1,
+			Element: "ParticipantECDSASignatures", Index: -1,
+			Err: fmt.Errorf("signer %s does not match participant address %s",
+				signer, address)}
+	}
+	return nil
+}
+
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		_, _ = h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// uint256Bytes returns the 32 bytes big-endian encoding of n
+func uint256Bytes(n uint64) []byte {
+	return new(big.Int).SetUint64(n).FillBytes(make([]byte, 32))
+}
@@ -0,0 +1,160 @@
+package kzgceremony
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestVerifyECDSASignaturesFromFile(t *testing.T) {
+	c := qt.New(t)
+	j, err := ioutil.ReadFile("current_state_10.json")
+	c.Assert(err, qt.IsNil)
+
+	s := &State{}
+	err = json.Unmarshal(j, s)
+	c.Assert(err, qt.IsNil)
+
+	// the signatures of the test file were done over the official ceremony
+	// sizes, while the test file only contains 10 powers
+	officialSizes := []uint64{4096, 8192, 16384, 32768}
+	for i := 0; i < len(s.Transcripts); i++ {
+		s.Transcripts[i].NumG1Powers = officialSizes[i]
+		s.Transcripts[i].NumG2Powers = 65
+	}
+
+	reports, err := VerifyECDSASignatures(s)
+	c.Assert(err, qt.IsNil)
+	// only the Ethereum participants are reported
+	c.Assert(reports, qt.HasLen, 4)
+	for _, r := range reports {
+		c.Assert(r.Status, qt.Equals, SignatureValid, qt.Commentf("%s", r.ParticipantID))
+		c.Assert(r.Err, qt.IsNil)
+	}
+
+	// the signature does not match the address of another participant
+	s.ParticipantIDs[2], s.ParticipantIDs[5] = s.ParticipantIDs[5], s.ParticipantIDs[2]
+	reports, err = VerifyECDSASignatures(s)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reports[0].Index, qt.Equals, 2)
+	c.Assert(reports[0].Status, qt.Equals, SignatureInvalid)
+	var vErr *VerificationError
+	c.Assert(errors.As(reports[0].Err, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, ECDSASignatureCheck)
+	c.Assert(vErr.Index, qt.Equals, 2)
+}
+
+func TestSignECDSA(t *testing.T) {
+	c := qt.New(t)
+
+	sk, err := EthKeyFromHex("0x7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d")
+	c.Assert(err, qt.IsNil)
+	address := EthAddress(sk.PubKey())
+	c.Assert(address, qt.Equals, "0x008aeeda4d805471df9b2a5b0f38a0c3bcba786b")
+
+	j, err := ioutil.ReadFile("batch_contribution_10.json")
+	c.Assert(err, qt.IsNil)
+	bc := &BatchContribution{}
+	err = json.Unmarshal(j, bc)
+	c.Assert(err, qt.IsNil)
+	c.Assert(bc.ECDSASignature, qt.Equals, "")
+
+	nb, err := bc.ContributeWithIdentity(
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"),
+		"eth|"+address)
+	c.Assert(err, qt.IsNil)
+	err = nb.SignECDSA(sk)
+	c.Assert(err, qt.IsNil)
+	c.Assert(nb.ECDSASignature, qt.HasLen, 2+65*2)
+
+	signer, err := RecoverPotPubKeysSigner(nb.ECDSASignature, nb.potPubKeyEntries())
+	c.Assert(err, qt.IsNil)
+	c.Assert(signer, qt.Equals, address)
+
+	// the signature is kept through the json marshalers
+	b, err := json.Marshal(nb)
+	c.Assert(err, qt.IsNil)
+	parsed := &BatchContribution{}
+	err = json.Unmarshal(b, parsed)
+	c.Assert(err, qt.IsNil)
+	c.Assert(parsed.ECDSASignature, qt.Equals, nb.ECDSASignature)
+
+	// a State containing the contribution verifies the signature
+	s := NewEmptyState([]int{10, 10, 10, 10}, []int{10, 10, 10, 10})
+	ns, err := s.ContributeWithIdentity(
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"),
+		"eth|"+address)
+	c.Assert(err, qt.IsNil)
+	ns.ParticipantECDSASignatures[1] = nb.ECDSASignature
+	reports, err := VerifyECDSASignatures(ns)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reports, qt.HasLen, 1)
+	c.Assert(reports[0].Status, qt.Equals, SignatureValid)
+
+	ns.ParticipantECDSASignatures[1] = ""
+	reports, err = VerifyECDSASignatures(ns)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reports[0].Status, qt.Equals, SignatureMissing)
+}
+
+func TestEthKeyFromKeystore(t *testing.T) {
+	c := qt.New(t)
+
+	// test vectors from the Web3 Secret Storage Definition
+	pbkdf2Keystore := `{
+		"crypto" : {
+			"cipher" : "aes-128-ctr",
+			"cipherparams" : {"iv" : "6087dab2f9fdbbfaddc31a909735c1e6"},
+			"ciphertext" : "5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46",
+			"kdf" : "pbkdf2",
+			"kdfparams" : {
+				"c" : 262144,
+				"dklen" : 32,
+				"prf" : "hmac-sha256",
+				"salt" : "ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"
+			},
+			"mac" : "517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"
+		},
+		"id" : "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+		"version" : 3
+	}`
+	scryptKeystore := `{
+		"crypto" : {
+			"cipher" : "aes-128-ctr",
+			"cipherparams" : {"iv" : "83dbcc02d8ccb40e466191a123791e0e"},
+			"ciphertext" : "d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c",
+			"kdf" : "scrypt",
+			"kdfparams" : {
+				"dklen" : 32,
+				"n" : 262144,
+				"p" : 8,
+				"r" : 1,
+				"salt" : "ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"
+			},
+			"mac" : "2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"
+		},
+		"id" : "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+		"version" : 3
+	}`
+
+	for _, keystore := range []string{pbkdf2Keystore, scryptKeystore} {
+		sk, err := EthKeyFromKeystore([]byte(keystore), "testpassword")
+		c.Assert(err, qt.IsNil)
+		expected, err := EthKeyFromHex("7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sk.Serialize(), qt.DeepEquals, expected.Serialize())
+
+		_, err = EthKeyFromKeystore([]byte(keystore), "wrongpassword")
+		c.Assert(err, qt.ErrorMatches, "could not decrypt keystore: invalid password")
+	}
+
+	// the iv is not covered by the mac
+	badIV := strings.Replace(pbkdf2Keystore, "6087dab2f9fdbbfaddc31a909735c1e6",
+		"6087dab2f9fdbbfaddc31a909735c1", 1)
+	_, err := EthKeyFromKeystore([]byte(badIV), "testpassword")
+	c.Assert(err, qt.ErrorMatches, "invalid keystore iv length: 15")
+}
@@ -0,0 +1,226 @@
+package kzgceremony
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/hkdf"
+)
+
+// EntropyPoolSalt is the salt used to extract the randomness from the
+// entropy sources mixed in the EntropyPool
+const EntropyPoolSalt = "eth-kzg-ceremony-alt entropy pool v1"
+
+// tauInfo is the prefix of the info used to derive τ for each sub-ceremony
+// from the randomness
+const tauInfo = "eth-kzg-ceremony-alt tau v1"
+
+// EntropySource is a source of entropy that can be mixed in an EntropyPool
+type EntropySource interface {
+	// Name returns the description of the source, which can be logged (it
+	// must not contain any of the entropy)
+	Name() string
+	// Entropy returns the bytes obtained from the source
+	Entropy() ([]byte, error)
+}
+
+// EntropyPool mixes several EntropySources into the randomness used to
+// derive τ
+type EntropyPool struct {
+	sources []EntropySource
+	// Logf, when set, is used to log which sources are mixed (never their
+	// contents)
+	Logf func(format string, a ...interface{})
+}
+
+// NewEntropyPool returns a new EntropyPool with the given sources
+func NewEntropyPool(sources ...EntropySource) *EntropyPool {
+	return &EntropyPool{sources: sources}
+}
+
+// Add adds the given source to the EntropyPool
+func (p *EntropyPool) Add(s EntropySource) {
+	p.sources = append(p.sources, s)
+}
+
+// Randomness reads all the sources of the EntropyPool, and mixes them with
+// HKDF-Extract (using blake2b-512) into 64 bytes of randomness, which can be
+// used in the Contribute methods. The entropy of each source is length
+// prefixed together with the source name, so different splits of the same
+// bytes among the sources give different randomness. It fails if any of the
+// sources fails or returns no entropy.
+func (p *EntropyPool) Randomness() ([]byte, error) {
+	if len(p.sources) == 0 {
+		return nil, fmt.Errorf("entropy pool without sources")
+	}
+	var ikm bytes.Buffer
+	for _, s := range p.sources {
+		e, err := s.Entropy()
+		if err != nil {
+			return nil, fmt.Errorf("entropy source %s: %s", s.Name(), err)
+		}
+		if len(e) == 0 {
+			return nil, fmt.Errorf("entropy source %s: no entropy", s.Name())
+		}
+		writeLengthPrefixed(&ikm, []byte(s.Name()))
+		writeLengthPrefixed(&ikm, e)
+		p.logf("mixing entropy source: %s (%d bytes)\n", s.Name(), len(e))
+		zero(e)
+	}
+	prk := hkdf.Extract(newBlake2b512, ikm.Bytes(), []byte(EntropyPoolSalt))
+	zero(ikm.Bytes())
+	return prk, nil
+}
+
+func (p *EntropyPool) logf(format string, a ...interface{}) {
+	if p.Logf != nil {
+		p.Logf(format, a...)
+	}
+}
+
+// deriveTau derives the 64 bytes used as τ of the sub-ceremony with the
+// given index and sizes from the randomness, using HKDF (with blake2b-512)
+// with the sub-ceremony parameters as info, so each sub-ceremony gets an
+// independent τ
+func deriveTau(randomness []byte, round, nG1, nG2 int) ([]byte, error) {
+	var info bytes.Buffer
+	info.WriteString(tauInfo)
+	_ = binary.Write(&info, binary.BigEndian, uint64(round))
+	_ = binary.Write(&info, binary.BigEndian, uint64(nG1))
+	_ = binary.Write(&info, binary.BigEndian, uint64(nG2))
+
+	out := make([]byte, 64)
+	r := hkdf.New(newBlake2b512, randomness, nil, info.Bytes())
+	if _, err := io.ReadFull(r, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func newBlake2b512() hash.Hash {
+	h, _ := blake2b.New512(nil)
+	return h
+}
+
+func writeLengthPrefixed(w *bytes.Buffer, b []byte) {
+	_ = binary.Write(w, binary.BigEndian, uint64(len(b)))
+	_, _ = w.Write(b)
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+type userEntropy []byte
+
+// UserEntropy returns an EntropySource with the randomness given by the user
+func UserEntropy(randomness []byte) EntropySource {
+	return userEntropy(randomness)
+}
+
+func (u userEntropy) Name() string { return "user randomness" }
+
+func (u userEntropy) Entropy() ([]byte, error) {
+	return append([]byte{}, u...), nil
+}
+
+type cryptoRandEntropy int
+
+// CryptoRandEntropy returns an EntropySource that reads n bytes from
+// crypto/rand
+func CryptoRandEntropy(n int) EntropySource {
+	return cryptoRandEntropy(n)
+}
+
+func (n cryptoRandEntropy) Name() string { return "crypto/rand" }
+
+func (n cryptoRandEntropy) Entropy() ([]byte, error) {
+	b := make([]byte, int(n))
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type systemEntropy struct{}
+
+// SystemEntropy returns an EntropySource with volatile state of the operating
+// system and the process: high resolution timestamps and timing jitter,
+// process and host identifiers and memory statistics
+func SystemEntropy() EntropySource {
+	return systemEntropy{}
+}
+
+func (systemEntropy) Name() string { return "system state" }
+
+func (systemEntropy) Entropy() ([]byte, error) {
+	var b bytes.Buffer
+	_ = binary.Write(&b, binary.BigEndian, time.Now().UnixNano())
+	_ = binary.Write(&b, binary.BigEndian, int64(os.Getpid()))
+	_ = binary.Write(&b, binary.BigEndian, int64(os.Getppid()))
+	hostname, _ := os.Hostname()
+	b.WriteString(hostname)
+	if wd, err := os.Getwd(); err == nil {
+		b.WriteString(wd)
+	}
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	_ = binary.Write(&b, binary.BigEndian, m.Alloc)
+	_ = binary.Write(&b, binary.BigEndian, m.TotalAlloc)
+	_ = binary.Write(&b, binary.BigEndian, m.Mallocs)
+	_ = binary.Write(&b, binary.BigEndian, m.PauseTotalNs)
+
+	// timing jitter of hashing operations
+	h := newBlake2b512()
+	for i := 0; i < 64; i++ {
+		t0 := time.Now()
+		_, _ = h.Write(b.Bytes())
+		_ = binary.Write(&b, binary.BigEndian, time.Since(t0).Nanoseconds())
+	}
+	_ = binary.Write(&b, binary.BigEndian, time.Now().UnixNano())
+	return b.Bytes(), nil
+}
+
+type fileEntropy string
+
+// FileEntropy returns an EntropySource that reads the content of the given
+// file
+func FileEntropy(path string) EntropySource {
+	return fileEntropy(path)
+}
+
+func (f fileEntropy) Name() string { return "file " + string(f) }
+
+func (f fileEntropy) Entropy() ([]byte, error) {
+	return ioutil.ReadFile(string(f))
+}
+
+type commandEntropy struct {
+	name string
+	args []string
+}
+
+// CommandEntropy returns an EntropySource that runs the given command and
+// takes its standard output
+func CommandEntropy(name string, args ...string) EntropySource {
+	return commandEntropy{name, args}
+}
+
+func (c commandEntropy) Name() string { return "command " + c.name }
+
+func (c commandEntropy) Entropy() ([]byte, error) {
+	return exec.Command(c.name, c.args...).Output()
+}
@@ -0,0 +1,93 @@
+package kzgceremony
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestEntropyPool(t *testing.T) {
+	c := qt.New(t)
+
+	userRandomness := []byte("1111111111111111111111111111111111111111111111111111111111111111")
+	path := filepath.Join(c.TempDir(), "entropy")
+	err := ioutil.WriteFile(path, []byte("file entropy"), 0600)
+	c.Assert(err, qt.IsNil)
+
+	var log bytes.Buffer
+	pool := NewEntropyPool(UserEntropy(userRandomness), FileEntropy(path))
+	pool.Add(CommandEntropy("echo", "command entropy"))
+	pool.Logf = func(format string, a ...interface{}) {
+		fmt.Fprintf(&log, format, a...)
+	}
+	r0, err := pool.Randomness()
+	c.Assert(err, qt.IsNil)
+	c.Assert(r0, qt.HasLen, 64)
+	c.Assert(len(r0) >= MinRandomnessLen, qt.IsTrue)
+
+	// the used sources are logged, but not their contents
+	c.Assert(log.String(), qt.Contains, "user randomness")
+	c.Assert(log.String(), qt.Contains, "file "+path)
+	c.Assert(log.String(), qt.Contains, "command echo")
+	c.Assert(log.String(), qt.Not(qt.Contains), "1111")
+	c.Assert(log.String(), qt.Not(qt.Contains), "file entropy")
+	c.Assert(log.String(), qt.Not(qt.Contains), "command entropy")
+	// the user randomness is not modified
+	c.Assert(string(userRandomness), qt.Equals,
+		"1111111111111111111111111111111111111111111111111111111111111111")
+
+	// deterministic sources give the same randomness
+	r1, err := pool.Randomness()
+	c.Assert(err, qt.IsNil)
+	c.Assert(r1, qt.DeepEquals, r0)
+
+	// while the same bytes split differently among the sources do not
+	pool = NewEntropyPool(UserEntropy([]byte("11111111111111111111111111111111")),
+		UserEntropy([]byte("11111111111111111111111111111111")))
+	r2, err := pool.Randomness()
+	c.Assert(err, qt.IsNil)
+	pool = NewEntropyPool(UserEntropy(userRandomness))
+	r3, err := pool.Randomness()
+	c.Assert(err, qt.IsNil)
+	c.Assert(r2, qt.Not(qt.DeepEquals), r3)
+
+	// non-deterministic sources
+	pool = NewEntropyPool(UserEntropy(userRandomness), CryptoRandEntropy(64),
+		SystemEntropy())
+	r4, err := pool.Randomness()
+	c.Assert(err, qt.IsNil)
+	r5, err := pool.Randomness()
+	c.Assert(err, qt.IsNil)
+	c.Assert(r4, qt.Not(qt.DeepEquals), r5)
+
+	// failing sources
+	_, err = NewEntropyPool().Randomness()
+	c.Assert(err, qt.ErrorMatches, "entropy pool without sources")
+	_, err = NewEntropyPool(FileEntropy(filepath.Join(c.TempDir(), "nonexistent"))).Randomness()
+	c.Assert(err, qt.ErrorMatches, "entropy source file .*: .*no such file or directory")
+	_, err = NewEntropyPool(UserEntropy(nil)).Randomness()
+	c.Assert(err, qt.ErrorMatches, "entropy source user randomness: no entropy")
+}
+
+func TestDeriveTau(t *testing.T) {
+	c := qt.New(t)
+
+	randomness := []byte("1111111111111111111111111111111111111111111111111111111111111111")
+	t0, err := deriveTau(randomness, 0, 4096, 65)
+	c.Assert(err, qt.IsNil)
+	c.Assert(t0, qt.HasLen, 64)
+	t0b, err := deriveTau(randomness, 0, 4096, 65)
+	c.Assert(err, qt.IsNil)
+	c.Assert(t0b, qt.DeepEquals, t0)
+
+	// domain separation by index and sizes
+	for _, params := range [][3]int{{1, 4096, 65}, {0, 8192, 65}, {0, 4096, 64}} {
+		ti, err := deriveTau(randomness, params[0], params[1], params[2])
+		c.Assert(err, qt.IsNil)
+		c.Assert(ti, qt.Not(qt.DeepEquals), t0)
+	}
+}
@@ -0,0 +1,127 @@
+package kzgceremony
+
+import (
+	"fmt"
+)
+
+// VerificationCheck identifies each one of the checks done by the verifiers
+type VerificationCheck int
+
+const (
+	// PointValidityCheck checks that a point is non-empty, non-zero, on the
+	// curve, and in the correct prime order subgroup
+	PointValidityCheck VerificationCheck = iota
+	// RunningProductCheck checks that the last running product of the
+	// Witness (or the Proof.G1PTau) matches [τ']₁ of the SRS
+	RunningProductCheck
+	// PubKeyCheck checks the pairing between the previous running product,
+	// the PotPubKey and the new running product:
+	// e([τ]₁, [p]₂) == e([τ']₁, [1]₂)
+	PubKeyCheck
+	// G1StructureCheck checks that the G1Powers follow the powers of tau
+	// structure: e([τ'ⁱ]₁, [τ']₂) == e([τ'ⁱ⁺¹]₁, [1]₂)
+	G1StructureCheck
+	// G2StructureCheck checks that the G2Powers follow the powers of tau
+	// structure: e([τ']₁, [τ'ʲ]₂) == e([1]₁, [τ'ʲ⁺¹]₂)
+	G2StructureCheck
+	// WitnessLengthCheck checks that the lengths of the RunningProducts,
+	// PotPubKeys, BLSSignatures and ParticipantIDs agree
+	WitnessLengthCheck
+	// GenesisCheck checks that the witness chain starts from the generator
+	GenesisCheck
+	// BLSSignatureCheck checks the BLS signature of the participant identity
+	// against the PotPubKey: e(sig, [1]₂) == e(H(identity), [p]₂)
+	BLSSignatureCheck
+	// ECDSASignatureCheck checks the EIP-712 ECDSA signature of the
+	// PotPubKeys against the Ethereum address of the participant
+	ECDSASignatureCheck
+	// HashChainCheck checks that a file of a Zcash powersoftau ceremony
+	// contains the hash of the file it was computed from
+	HashChainCheck
+	// SizeCheck checks that a BatchContribution has one Contribution for
+	// each Transcript, with the same number of powers
+	SizeCheck
+	// ParticipantCheck checks that the identity of a new participant is not
+	// empty, and that it did not contribute before
+	ParticipantCheck
+)
+
+func (c VerificationCheck) String() string {
+	switch c {
+	case PointValidityCheck:
+		return "point validity"
+	case RunningProductCheck:
+		return "running product"
+	case PubKeyCheck:
+		return "pot pubkey pairing"
+	case G1StructureCheck:
+		return "G1 powers structure"
+	case G2StructureCheck:
+		return "G2 powers structure"
+	case WitnessLengthCheck:
+		return "witness length"
+	case GenesisCheck:
+		return "genesis"
+	case BLSSignatureCheck:
+		return "bls signature"
+	case ECDSASignatureCheck:
+		return "ecdsa signature"
+	case HashChainCheck:
+		return "hash chain"
+	case SizeCheck:
+		return "size"
+	case ParticipantCheck:
+		return "participant"
+	default:
+		return fmt.Sprintf("unknown check (%d)", int(c))
+	}
+}
+
+// VerificationError is returned by the verifiers when one of the checks
+// fails, carrying the information needed to locate the failure
+type VerificationError struct {
+	// Check is the kind of check that failed
+	Check VerificationCheck
+	// Transcript is the index of the Transcript (or Contribution) where the
+	// check failed, -1 when the verified data is a single SRS
+	Transcript int
+	// Element is the name of the array containing the failing element, eg.
+	// "G1Powers", "G2Powers", "RunningProducts" or "PotPubKeys"
+	Element string
+	// Index is the position of the failing element inside the array, -1
+	// when it could not be determined. For the structure checks, Index i
+	// means that the power i does not follow from the power i-1
+	Index int
+	// Point is the compressed encoding (0x prefixed hex) of the failing
+	// point, empty when the point is missing or not applicable
+	Point string
+	// Err is the underlying error, if any
+	Err error
+}
+
+// Error implements the error interface
+func (e *VerificationError) Error() string {
+	msg := fmt.Sprintf("verification failed: %s check", e.Check)
+	if e.Transcript >= 0 {
+		msg += fmt.Sprintf(", transcript %d", e.Transcript)
+	}
+	if e.Element != "" {
+		if e.Index >= 0 {
+			msg += fmt.Sprintf(", %s[%d]", e.Element, e.Index)
+		} else {
+			msg += fmt.Sprintf(", %s", e.Element)
+		}
+	}
+	if e.Point != "" {
+		msg += fmt.Sprintf(" (%s)", e.Point)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,244 @@
+package kzgceremony
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+func TestCheckNewSRSFromPrevSRSErrors(t *testing.T) {
+	c := qt.New(t)
+
+	srs_0 := newEmptySRS(10, 10)
+	srs_1, proof_1, err := Contribute(srs_0, 0,
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+	srs_2, proof_2, err := Contribute(srs_1, 0,
+		[]byte("2222222222222222222222222222222222222222222222222222222222222222"))
+	c.Assert(err, qt.IsNil)
+
+	copySRS := func(srs *SRS) *SRS {
+		return &SRS{
+			G1Powers: append([]*bls12381.PointG1{}, srs.G1Powers...),
+			G2Powers: append([]*bls12381.PointG2{}, srs.G2Powers...),
+		}
+	}
+
+	for _, mode := range []VerificationMode{VerifyStrict, VerifyBatch} {
+		c.Assert(CheckNewSRSFromPrevSRS(srs_1, srs_2, proof_2, mode), qt.IsNil)
+
+		// point validity
+		srs := copySRS(srs_2)
+		srs.G2Powers[4] = g2.Zero()
+		var vErr *VerificationError
+		err = CheckNewSRSFromPrevSRS(srs_1, srs, proof_2, mode)
+		c.Assert(errors.As(err, &vErr), qt.IsTrue)
+		c.Assert(vErr.Check, qt.Equals, PointValidityCheck)
+		c.Assert(vErr.Transcript, qt.Equals, -1)
+		c.Assert(vErr.Element, qt.Equals, "G2Powers")
+		c.Assert(vErr.Index, qt.Equals, 4)
+		c.Assert(vErr.Point, qt.Equals, g2PointToString(g2.Zero()))
+		c.Assert(vErr.Err, qt.IsNotNil)
+
+		// running product
+		err = CheckNewSRSFromPrevSRS(srs_1, srs_2, proof_1, mode)
+		c.Assert(errors.As(err, &vErr), qt.IsTrue)
+		c.Assert(vErr.Check, qt.Equals, RunningProductCheck)
+
+		// pot pubkey pairing
+		err = CheckNewSRSFromPrevSRS(srs_0, srs_2, proof_2, mode)
+		c.Assert(errors.As(err, &vErr), qt.IsTrue)
+		c.Assert(vErr.Check, qt.Equals, PubKeyCheck)
+
+		// G1 structure
+		srs = copySRS(srs_2)
+		srs.G1Powers[7] = srs_1.G1Powers[7]
+		err = CheckNewSRSFromPrevSRS(srs_1, srs, proof_2, mode)
+		c.Assert(errors.As(err, &vErr), qt.IsTrue)
+		c.Assert(vErr.Check, qt.Equals, G1StructureCheck)
+		c.Assert(vErr.Element, qt.Equals, "G1Powers")
+		c.Assert(vErr.Index, qt.Equals, 7)
+		c.Assert(vErr.Point, qt.Equals, g1PointToString(srs_1.G1Powers[7]))
+
+		// G2 structure
+		srs = copySRS(srs_2)
+		srs.G2Powers[3] = srs_1.G2Powers[3]
+		err = CheckNewSRSFromPrevSRS(srs_1, srs, proof_2, mode)
+		c.Assert(errors.As(err, &vErr), qt.IsTrue)
+		c.Assert(vErr.Check, qt.Equals, G2StructureCheck)
+		c.Assert(vErr.Element, qt.Equals, "G2Powers")
+		c.Assert(vErr.Index, qt.Equals, 3)
+	}
+}
+
+func TestCheckStateErrors(t *testing.T) {
+	c := qt.New(t)
+
+	s := NewEmptyState([]int{8, 8}, []int{4, 4})
+	s, err := s.Contribute(
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(CheckState(s, VerifyStrict), qt.IsNil)
+
+	// use the PotPubKey of the first Transcript in the second one
+	w := s.Transcripts[1].Witness
+	w.PotPubKeys[1] = s.Transcripts[0].Witness.PotPubKeys[1]
+	err = CheckState(s, VerifyStrict)
+	var vErr *VerificationError
+	c.Assert(errors.As(err, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, PubKeyCheck)
+	c.Assert(vErr.Transcript, qt.Equals, 1)
+	c.Assert(vErr.Element, qt.Equals, "PotPubKeys")
+	c.Assert(vErr.Index, qt.Equals, 1)
+	c.Assert(err.Error(), qt.Matches,
+		`verification failed: pot pubkey pairing check, transcript 1, PotPubKeys\[1\] \(0x[0-9a-f]+\)`)
+}
+
+func TestCheckBatchContributionErrors(t *testing.T) {
+	c := qt.New(t)
+
+	s := NewEmptyState([]int{8, 4}, []int{4, 4})
+	prev := s.NextBatch()
+	next, err := prev.Contribute(
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+	other, err := prev.Contribute(
+		[]byte("2222222222222222222222222222222222222222222222222222222222222222"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(CheckBatchContribution(prev, next, VerifyStrict), qt.IsNil)
+
+	copyBatch := func(bc *BatchContribution) *BatchContribution {
+		nb := &BatchContribution{}
+		for _, contribution := range bc.Contributions {
+			contribution.PowersOfTau = &SRS{
+				G1Powers: append([]*bls12381.PointG1{}, contribution.PowersOfTau.G1Powers...),
+				G2Powers: append([]*bls12381.PointG2{}, contribution.PowersOfTau.G2Powers...),
+			}
+			nb.Contributions = append(nb.Contributions, contribution)
+		}
+		return nb
+	}
+
+	testCases := []struct {
+		name       string
+		prev, next func() *BatchContribution
+		check      VerificationCheck
+		transcript int
+		element    string
+		index      int
+	}{
+		{"number of contributions", func() *BatchContribution { return prev },
+			func() *BatchContribution {
+				nb := copyBatch(next)
+				nb.Contributions = nb.Contributions[1:]
+				return nb
+			}, SizeCheck, -1, "Contributions", -1},
+		{"number of powers", func() *BatchContribution { return prev },
+			func() *BatchContribution {
+				nb := copyBatch(next)
+				nb.Contributions[1].NumG2Powers = 3
+				return nb
+			}, SizeCheck, 1, "PowersOfTau", -1},
+		{"previous powers", func() *BatchContribution {
+			pb := copyBatch(prev)
+			pb.Contributions[0].PowersOfTau.G1Powers =
+				pb.Contributions[0].PowersOfTau.G1Powers[:7]
+			return pb
+		}, func() *BatchContribution { return next }, SizeCheck, 0, "PowersOfTau", -1},
+		{"invalid point", func() *BatchContribution { return prev },
+			func() *BatchContribution {
+				nb := copyBatch(next)
+				nb.Contributions[1].PowersOfTau.G1Powers[2] = g1.Zero()
+				return nb
+			}, PointValidityCheck, 1, "G1Powers", 2},
+		{"invalid pot pubkey", func() *BatchContribution { return prev },
+			func() *BatchContribution {
+				nb := copyBatch(next)
+				nb.Contributions[0].PotPubKey = g2.Zero()
+				return nb
+			}, PointValidityCheck, 0, "PotPubKey", -1},
+		{"pot pubkey pairing", func() *BatchContribution { return prev },
+			func() *BatchContribution {
+				nb := copyBatch(next)
+				nb.Contributions[1].PotPubKey = other.Contributions[1].PotPubKey
+				return nb
+			}, PubKeyCheck, 1, "PotPubKey", -1},
+		{"powers structure", func() *BatchContribution { return prev },
+			func() *BatchContribution {
+				nb := copyBatch(next)
+				nb.Contributions[0].PowersOfTau.G2Powers[3] =
+					other.Contributions[0].PowersOfTau.G2Powers[3]
+				return nb
+			}, G2StructureCheck, 0, "G2Powers", 3},
+	}
+	for _, tc := range testCases {
+		c.Run(tc.name, func(c *qt.C) {
+			for _, mode := range []VerificationMode{VerifyStrict, VerifyBatch} {
+				err := CheckBatchContribution(tc.prev(), tc.next(), mode)
+				var vErr *VerificationError
+				c.Assert(errors.As(err, &vErr), qt.IsTrue, qt.Commentf("%v", err))
+				c.Assert(vErr.Check, qt.Equals, tc.check)
+				c.Assert(vErr.Transcript, qt.Equals, tc.transcript)
+				c.Assert(vErr.Element, qt.Equals, tc.element)
+				c.Assert(vErr.Index, qt.Equals, tc.index)
+			}
+		})
+	}
+}
+
+func TestCheckBatchContributionWithIdentity(t *testing.T) {
+	c := qt.New(t)
+
+	id := "eth|0x008aeeda4d805471df9b2a5b0f38a0c3bcba786b"
+	sk, err := EthKeyFromHex("0x7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d")
+	c.Assert(err, qt.IsNil)
+	otherSK, err := EthKeyFromHex("0x1111111111111111111111111111111111111111111111111111111111111111")
+	c.Assert(err, qt.IsNil)
+
+	prev := NewEmptyState([]int{8, 4}, []int{4, 4}).NextBatch()
+	contribute := func(identity string, key *secp256k1.PrivateKey) *BatchContribution {
+		next, err := prev.ContributeWithIdentity(
+			[]byte("1111111111111111111111111111111111111111111111111111111111111111"),
+			identity)
+		c.Assert(err, qt.IsNil)
+		if key != nil {
+			c.Assert(next.SignECDSA(key), qt.IsNil)
+		}
+		return next
+	}
+	next := contribute(id, sk)
+	c.Assert(CheckBatchContributionWithIdentity(prev, next, id, VerifyBatch), qt.IsNil)
+
+	testCases := []struct {
+		name     string
+		next     *BatchContribution
+		identity string
+		check    VerificationCheck
+	}{
+		{"identity of another participant", contribute(id, sk), "git|1234|username", BLSSignatureCheck},
+		{"bls signature of another identity", contribute("git|1234|username", nil), id,
+			BLSSignatureCheck},
+		{"ecdsa signature of another key", contribute(id, otherSK), id, ECDSASignatureCheck},
+		{"ecdsa signature of a non Ethereum participant",
+			contribute("git|1234|username", sk), "git|1234|username", ECDSASignatureCheck},
+	}
+	for _, tc := range testCases {
+		c.Run(tc.name, func(c *qt.C) {
+			err := CheckBatchContributionWithIdentity(prev, tc.next, tc.identity, VerifyBatch)
+			var vErr *VerificationError
+			c.Assert(errors.As(err, &vErr), qt.IsTrue, qt.Commentf("%v", err))
+			c.Assert(vErr.Check, qt.Equals, tc.check)
+		})
+	}
+
+	// the checks of CheckBatchContribution are done first
+	broken := contribute(id, sk)
+	broken.Contributions = broken.Contributions[1:]
+	err = CheckBatchContributionWithIdentity(prev, broken, id, VerifyBatch)
+	var vErr *VerificationError
+	c.Assert(errors.As(err, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, SizeCheck)
+}
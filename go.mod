@@ -3,6 +3,7 @@ module github.com/arnaucube/eth-kzg-ceremony-alt
 go 1.19
 
 require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.1.0
 	github.com/fatih/color v1.13.0
 	github.com/frankban/quicktest v1.14.4
 	github.com/kilic/bls12-381 v0.1.0
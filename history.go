@@ -0,0 +1,151 @@
+package kzgceremony
+
+import (
+	"fmt"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// HistoryReport contains the result of verifying the full history of a
+// State, from the genesis to the last contribution
+type HistoryReport struct {
+	// Contributions contains the report of each contribution of the
+	// witness, starting from the first one after the genesis
+	Contributions []ContributionReport
+	// SRSErr is the result of checking the current PowersOfTau of the
+	// State against the last contribution (see CheckState)
+	SRSErr error
+}
+
+// ContributionReport contains the result of verifying a single contribution
+// of the State history
+type ContributionReport struct {
+	// Index is the position of the contribution in the witness arrays
+	Index int
+	// ParticipantID is the identity of the contributor
+	ParticipantID string
+	// Err is the *VerificationError of the first Transcript for which the
+	// contribution is not valid, nil when valid in all the Transcripts
+	Err error
+}
+
+// Valid returns true if all the contributions and the current SRS are valid
+func (r *HistoryReport) Valid() bool {
+	if r.SRSErr != nil {
+		return false
+	}
+	for _, c := range r.Contributions {
+		if c.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Invalid returns the reports of the contributions that are not valid
+func (r *HistoryReport) Invalid() []ContributionReport {
+	var invalid []ContributionReport
+	for _, c := range r.Contributions {
+		if c.Err != nil {
+			invalid = append(invalid, c)
+		}
+	}
+	return invalid
+}
+
+// VerifyStateHistory verifies every contribution of the given State, walking
+// all the witness entries of each Transcript. For each Transcript it checks
+// that:
+//   - RunningProducts, PotPubKeys, BLSSignatures and ParticipantIDs have
+//     the same length
+//   - RunningProducts[0] is the G1 generator
+//   - e(RunningProducts[k-1], PotPubKeys[k]) == e(RunningProducts[k], [1]₂),
+//     for k ∈ [1, n-1]
+//
+// and then checks the current PowersOfTau with CheckState using the given
+// VerificationMode. It returns an error if the witness can not be walked
+// (lengths or genesis are not correct), otherwise it returns the
+// HistoryReport with the result of each contribution.
+func VerifyStateHistory(s *State, mode VerificationMode) (*HistoryReport, error) {
+	n := len(s.ParticipantIDs)
+	for ti, t := range s.Transcripts {
+		if t.Witness == nil {
+			return nil, &VerificationError{Check: WitnessLengthCheck,
+				Transcript: ti, Index: -1, Err: fmt.Errorf("missing witness")}
+		}
+		lengths := []struct {
+			element string
+			n       int
+		}{
+			{"RunningProducts", len(t.Witness.RunningProducts)},
+			{"PotPubKeys", len(t.Witness.PotPubKeys)},
+			{"BLSSignatures", len(t.Witness.BLSSignatures)},
+		}
+		for _, l := range lengths {
+			if l.n != n {
+				return nil, &VerificationError{Check: WitnessLengthCheck,
+					Transcript: ti, Element: l.element, Index: -1,
+					Err: fmt.Errorf("length %d, while there are %d ParticipantIDs",
+						l.n, n)}
+			}
+		}
+		if n == 0 {
+			return nil, &VerificationError{Check: GenesisCheck,
+				Transcript: ti, Element: "RunningProducts", Index: 0,
+				Err: fmt.Errorf("empty witness")}
+		}
+		if t.Witness.RunningProducts[0] == nil ||
+			!g1.Equal(t.Witness.RunningProducts[0], g1.One()) {
+			return nil, &VerificationError{Check: GenesisCheck,
+				Transcript: ti, Element: "RunningProducts", Index: 0,
+				Point: g1PointToString(t.Witness.RunningProducts[0]),
+				Err:   fmt.Errorf("first running product is not the generator")}
+		}
+	}
+
+	report := &HistoryReport{}
+	if n > 1 {
+		report.Contributions = make([]ContributionReport, n-1)
+	}
+	pairing := bls12381.NewEngine()
+	for k := 1; k < n; k++ {
+		report.Contributions[k-1] = ContributionReport{
+			Index:         k,
+			ParticipantID: s.ParticipantIDs[k],
+		}
+		for ti, t := range s.Transcripts {
+			if err := checkWitnessEntry(pairing, t.Witness, k); err != nil {
+				report.Contributions[k-1].Err = withTranscript(err, ti)
+				break
+			}
+		}
+	}
+
+	report.SRSErr = CheckState(s, mode)
+	return report, nil
+}
+
+// checkWitnessEntry checks that the k-th entry of the witness is correctly
+// related to the previous one:
+// e(RunningProducts[k-1], PotPubKeys[k]) == e(RunningProducts[k], [1]₂)
+func checkWitnessEntry(pairing *bls12381.Engine, w *Witness, k int) error {
+	if err := checkG1PointCorrectness(w.RunningProducts[k]); err != nil {
+		return &VerificationError{Check: PointValidityCheck, Transcript: -1,
+			Element: "RunningProducts", Index: k,
+			Point: g1PointToString(w.RunningProducts[k]), Err: err}
+	}
+	if err := checkG2PointCorrectness(w.PotPubKeys[k]); err != nil {
+		return &VerificationError{Check: PointValidityCheck, Transcript: -1,
+			Element: "PotPubKeys", Index: k,
+			Point: g2PointToString(w.PotPubKeys[k]), Err: err}
+	}
+
+	eL := pairing.AddPair(w.RunningProducts[k-1], w.PotPubKeys[k]).Result()
+	eR := pairing.AddPair(w.RunningProducts[k], g2.One()).Result()
+	if !eL.Equal(eR) {
+		return &VerificationError{Check: PubKeyCheck, Transcript: -1,
+			Element: "PotPubKeys", Index: k,
+			Point: g2PointToString(w.PotPubKeys[k])}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package kzgceremony
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestVerifyStateHistoryFromFile(t *testing.T) {
+	c := qt.New(t)
+	j, err := ioutil.ReadFile("current_state_10.json")
+	c.Assert(err, qt.IsNil)
+
+	s := &State{}
+	err = json.Unmarshal(j, s)
+	c.Assert(err, qt.IsNil)
+
+	report, err := VerifyStateHistory(s, VerifyBatch)
+	c.Assert(err, qt.IsNil)
+	c.Assert(len(report.Contributions), qt.Equals, 9)
+	c.Assert(report.Invalid(), qt.HasLen, 0)
+	for i, contribution := range report.Contributions {
+		c.Assert(contribution.Index, qt.Equals, i+1)
+		c.Assert(contribution.ParticipantID, qt.Equals, s.ParticipantIDs[i+1])
+	}
+	// the PowersOfTau of the test file do not correspond to the last
+	// running product
+	var vErr *VerificationError
+	c.Assert(errors.As(report.SRSErr, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, RunningProductCheck)
+	c.Assert(report.Valid(), qt.IsFalse)
+}
+
+func TestVerifyStateHistory(t *testing.T) {
+	c := qt.New(t)
+
+	s := NewEmptyState([]int{8, 8, 4}, []int{4, 4, 4})
+	for i, r := range []string{
+		"1111111111111111111111111111111111111111111111111111111111111111",
+		"2222222222222222222222222222222222222222222222222222222222222222",
+		"3333333333333333333333333333333333333333333333333333333333333333",
+	} {
+		var err error
+		s, err = s.ContributeWithIdentity([]byte(r), "git|"+r[:i+1])
+		c.Assert(err, qt.IsNil)
+	}
+
+	report, err := VerifyStateHistory(s, VerifyStrict)
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Valid(), qt.IsTrue)
+	c.Assert(report.Contributions, qt.HasLen, 3)
+
+	// replace the PotPubKey of the second contribution of the last
+	// Transcript
+	w := s.Transcripts[2].Witness
+	pk := w.PotPubKeys[2]
+	w.PotPubKeys[2] = w.PotPubKeys[1]
+	report, err = VerifyStateHistory(s, VerifyStrict)
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Valid(), qt.IsFalse)
+	c.Assert(report.SRSErr, qt.IsNil)
+	invalid := report.Invalid()
+	c.Assert(invalid, qt.HasLen, 1)
+	c.Assert(invalid[0].Index, qt.Equals, 2)
+	c.Assert(invalid[0].ParticipantID, qt.Equals, "git|22")
+	var vErr *VerificationError
+	c.Assert(errors.As(invalid[0].Err, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, PubKeyCheck)
+	c.Assert(vErr.Transcript, qt.Equals, 2)
+	c.Assert(vErr.Index, qt.Equals, 2)
+	w.PotPubKeys[2] = pk
+
+	// lengths
+	s.ParticipantIDs = s.ParticipantIDs[:3]
+	_, err = VerifyStateHistory(s, VerifyStrict)
+	c.Assert(errors.As(err, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, WitnessLengthCheck)
+	c.Assert(vErr.Element, qt.Equals, "RunningProducts")
+	s.ParticipantIDs = append(s.ParticipantIDs, "git|333")
+
+	// genesis
+	s.Transcripts[1].Witness.RunningProducts[0] = s.Transcripts[1].Witness.RunningProducts[1]
+	_, err = VerifyStateHistory(s, VerifyStrict)
+	c.Assert(errors.As(err, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, GenesisCheck)
+	c.Assert(vErr.Transcript, qt.Equals, 1)
+}
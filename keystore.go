@@ -0,0 +1,147 @@
+package kzgceremony
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// EthKeyFromHex parses a raw secp256k1 private key from its hex
+// representation (with or without 0x prefix)
+func EthKeyFromHex(s string) (*secp256k1.PrivateKey, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 32 {
+		return nil, fmt.Errorf("invalid private key length: %d", len(b))
+	}
+	return secp256k1.PrivKeyFromBytes(b), nil
+}
+
+type keystoreJSON struct {
+	Address string `json:"address"`
+	Crypto  struct {
+		Cipher       string `json:"cipher"`
+		CipherText   string `json:"ciphertext"`
+		CipherParams struct {
+			IV string `json:"iv"`
+		} `json:"cipherparams"`
+		KDF       string          `json:"kdf"`
+		KDFParams json.RawMessage `json:"kdfparams"`
+		MAC       string          `json:"mac"`
+	} `json:"crypto"`
+	Version int `json:"version"`
+}
+
+type scryptParams struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+type pbkdf2Params struct {
+	C     int    `json:"c"`
+	DKLen int    `json:"dklen"`
+	PRF   string `json:"prf"`
+	Salt  string `json:"salt"`
+}
+
+// EthKeyFromKeystore decrypts a secp256k1 private key from an Ethereum
+// keystore file (Web3 Secret Storage v3, with scrypt or pbkdf2 as kdf and
+// aes-128-ctr as cipher) using the given password
+func EthKeyFromKeystore(keystore []byte, password string) (*secp256k1.PrivateKey, error) {
+	var k keystoreJSON
+	if err := json.Unmarshal(keystore, &k); err != nil {
+		return nil, err
+	}
+	if k.Version != 3 {
+		return nil, fmt.Errorf("unsupported keystore version: %d", k.Version)
+	}
+	if k.Crypto.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported keystore cipher: %s", k.Crypto.Cipher)
+	}
+
+	var derivedKey []byte
+	switch k.Crypto.KDF {
+	case "scrypt":
+		var p scryptParams
+		if err := json.Unmarshal(k.Crypto.KDFParams, &p); err != nil {
+			return nil, err
+		}
+		salt, err := hex.DecodeString(p.Salt)
+		if err != nil {
+			return nil, err
+		}
+		derivedKey, err = scrypt.Key([]byte(password), salt, p.N, p.R, p.P, p.DKLen)
+		if err != nil {
+			return nil, err
+		}
+	case "pbkdf2":
+		var p pbkdf2Params
+		if err := json.Unmarshal(k.Crypto.KDFParams, &p); err != nil {
+			return nil, err
+		}
+		if p.PRF != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported keystore pbkdf2 prf: %s", p.PRF)
+		}
+		salt, err := hex.DecodeString(p.Salt)
+		if err != nil {
+			return nil, err
+		}
+		derivedKey = pbkdf2.Key([]byte(password), salt, p.C, p.DKLen, sha256.New)
+	default:
+		return nil, fmt.Errorf("unsupported keystore kdf: %s", k.Crypto.KDF)
+	}
+	if len(derivedKey) < 32 {
+		return nil, fmt.Errorf("keystore derived key too short")
+	}
+
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := hex.DecodeString(k.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, fmt.Errorf("could not decrypt keystore: invalid password")
+	}
+
+	iv, err := hex.DecodeString(k.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid keystore iv length: %d", len(iv))
+	}
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		return nil, err
+	}
+	plainText := make([]byte, len(cipherText))
+	cipher.NewCTR(block, iv).XORKeyStream(plainText, cipherText)
+	if len(plainText) != 32 {
+		return nil, fmt.Errorf("invalid private key length: %d", len(plainText))
+	}
+	sk := secp256k1.PrivKeyFromBytes(plainText)
+
+	if k.Address != "" &&
+		strings.TrimPrefix(EthAddress(sk.PubKey()), "0x") !=
+			strings.ToLower(strings.TrimPrefix(k.Address, "0x")) {
+		return nil, fmt.Errorf("keystore address does not match the decrypted key")
+	}
+	return sk, nil
+}
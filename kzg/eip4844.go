@@ -0,0 +1,248 @@
+package kzg
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/arnaucube/eth-kzg-ceremony-alt/poly"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+const (
+	// BytesPerFieldElement is the size of the encoding of a field element
+	// in a blob
+	BytesPerFieldElement = 32
+	// BytesPerCommitment is the size of a compressed G1 commitment
+	BytesPerCommitment = 48
+	// BytesPerProof is the size of a compressed G1 proof
+	BytesPerProof = 48
+)
+
+var (
+	// fiatShamirProtocolDomain is the domain separator of the blob
+	// evaluation challenge (FIAT_SHAMIR_PROTOCOL_DOMAIN)
+	fiatShamirProtocolDomain = []byte("FSBLOBVERIFY_V1_")
+	// randomChallengeKZGBatchDomain is the domain separator of the batch
+	// verification challenge (RANDOM_CHALLENGE_KZG_BATCH_DOMAIN)
+	randomChallengeKZGBatchDomain = []byte("RCKZGBATCH___V1_")
+)
+
+// Blob is a polynomial in evaluation form over the roots of unity domain in
+// bit-reversed order, encoded as len(G1) field elements of 32 bytes
+// big-endian
+type Blob []byte
+
+// Commitment is a compressed G1 point committing to a Blob
+type Commitment [BytesPerCommitment]byte
+
+// Proof is a compressed G1 point proving the evaluation of a Blob
+type Proof [BytesPerProof]byte
+
+// BlobToKZGCommitment returns the commitment to the blob, as
+// blob_to_kzg_commitment from EIP-4844
+func (s *Setup) BlobToKZGCommitment(blob Blob) (Commitment, error) {
+	p, err := s.blobToPolynomial(blob)
+	if err != nil {
+		return Commitment{}, err
+	}
+	c, err := msmG1(s.G1LagrangeBRP, p)
+	if err != nil {
+		return Commitment{}, err
+	}
+	var out Commitment
+	copy(out[:], bls12381.NewG1().ToCompressed(c))
+	return out, nil
+}
+
+// ComputeBlobKZGProof returns the proof of the evaluation of the blob at the
+// Fiat-Shamir challenge derived from the blob and its commitment, as
+// compute_blob_kzg_proof from EIP-4844
+func (s *Setup) ComputeBlobKZGProof(blob Blob, commitment Commitment) (Proof, error) {
+	if _, err := bytesToG1(commitment[:]); err != nil {
+		return Proof{}, fmt.Errorf("invalid commitment: %w", err)
+	}
+	p, err := s.blobToPolynomial(blob)
+	if err != nil {
+		return Proof{}, err
+	}
+	z := s.computeChallenge(blob, commitment)
+	proof, _, err := s.computeKZGProof(p, z)
+	if err != nil {
+		return Proof{}, err
+	}
+	var out Proof
+	copy(out[:], bls12381.NewG1().ToCompressed(proof))
+	return out, nil
+}
+
+// VerifyBlobKZGProofBatch checks the proofs of the blobs against their
+// commitments at once, as verify_blob_kzg_proof_batch from EIP-4844. It
+// returns an error when the inputs are malformed.
+func (s *Setup) VerifyBlobKZGProofBatch(blobs []Blob, commitments []Commitment,
+	proofs []Proof) (bool, error) {
+	n := len(blobs)
+	if len(commitments) != n || len(proofs) != n {
+		return false, fmt.Errorf("different number of blobs (%d), commitments (%d) and proofs (%d)",
+			n, len(commitments), len(proofs))
+	}
+	cs := make([]*bls12381.PointG1, n)
+	zs := make([]*bls12381.Fr, n)
+	ys := make([]*bls12381.Fr, n)
+	ps := make([]*bls12381.PointG1, n)
+	for i := 0; i < n; i++ {
+		var err error
+		cs[i], err = bytesToG1(commitments[i][:])
+		if err != nil {
+			return false, fmt.Errorf("invalid commitment %d: %w", i, err)
+		}
+		p, err := s.blobToPolynomial(blobs[i])
+		if err != nil {
+			return false, fmt.Errorf("blob %d: %w", i, err)
+		}
+		zs[i] = s.computeChallenge(blobs[i], commitments[i])
+		ys[i], err = s.evaluate(p, zs[i])
+		if err != nil {
+			return false, err
+		}
+		ps[i], err = bytesToG1(proofs[i][:])
+		if err != nil {
+			return false, fmt.Errorf("invalid proof %d: %w", i, err)
+		}
+	}
+
+	// r = hash(domain ‖ n_elements ‖ n ‖ (Cᵢ ‖ zᵢ ‖ yᵢ ‖ πᵢ)...)
+	h := sha256.New()
+	_, _ = h.Write(randomChallengeKZGBatchDomain)
+	_ = binary.Write(h, binary.BigEndian, uint64(len(s.G1LagrangeBRP)))
+	_ = binary.Write(h, binary.BigEndian, uint64(n))
+	for i := 0; i < n; i++ {
+		_, _ = h.Write(commitments[i][:])
+		_, _ = h.Write(zs[i].ToBytes())
+		_, _ = h.Write(ys[i].ToBytes())
+		_, _ = h.Write(proofs[i][:])
+	}
+	r := hashToField(h.Sum(nil))
+	return s.verifyBatch(cs, zs, ys, ps, r)
+}
+
+// blobToPolynomial parses the field elements of the blob, which must be in
+// canonical form
+func (s *Setup) blobToPolynomial(blob Blob) (Polynomial, error) {
+	n := len(s.G1LagrangeBRP)
+	if len(blob) != n*BytesPerFieldElement {
+		return nil, fmt.Errorf("blob of %d bytes, expected %d",
+			len(blob), n*BytesPerFieldElement)
+	}
+	q := bls12381.NewG1().Q()
+	p := make(Polynomial, n)
+	for i := 0; i < n; i++ {
+		b := blob[i*BytesPerFieldElement : (i+1)*BytesPerFieldElement]
+		if new(big.Int).SetBytes(b).Cmp(q) >= 0 {
+			return nil, fmt.Errorf("blob field element %d is not canonical", i)
+		}
+		p[i] = bls12381.NewFr().FromBytes(b)
+	}
+	return p, nil
+}
+
+// computeChallenge returns the Fiat-Shamir evaluation challenge
+// hash(domain ‖ n_elements ‖ blob ‖ commitment)
+func (s *Setup) computeChallenge(blob Blob, commitment Commitment) *bls12381.Fr {
+	h := sha256.New()
+	_, _ = h.Write(fiatShamirProtocolDomain)
+	// the degree is encoded as a 16 bytes big-endian integer
+	var degree [16]byte
+	binary.BigEndian.PutUint64(degree[8:], uint64(len(s.G1LagrangeBRP)))
+	_, _ = h.Write(degree[:])
+	_, _ = h.Write(blob)
+	_, _ = h.Write(commitment[:])
+	return hashToField(h.Sum(nil))
+}
+
+// evaluate returns the evaluation at z of the polynomial in evaluation form
+// over the bit-reversed domain, through the barycentric formula
+func (s *Setup) evaluate(p Polynomial, z *bls12381.Fr) (*bls12381.Fr, error) {
+	evals := make([]*bls12381.Fr, len(p))
+	copy(evals, p)
+	poly.BitReverse(evals)
+	return s.domain.EvaluateLagrange(evals, z)
+}
+
+// computeKZGProof returns the proof [q(τ)]₁ of the evaluation y = p(z) of
+// the polynomial in evaluation form, computing the quotient
+// q(X) = (p(X) - y) / (X - z) in evaluation form
+func (s *Setup) computeKZGProof(p Polynomial, z *bls12381.Fr) (*bls12381.PointG1, *bls12381.Fr, error) {
+	n := len(p)
+	y, err := s.evaluate(p, z)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	quotient := make(Polynomial, n)
+	dens := make([]*bls12381.Fr, n)
+	inDomain := -1
+	for i := 0; i < n; i++ {
+		dens[i] = bls12381.NewFr()
+		dens[i].Sub(s.rootsBRP[i], z)
+		if dens[i].IsZero() {
+			// replaced below, set to one to keep the batch
+			// inversion defined
+			inDomain = i
+			dens[i].One()
+		}
+	}
+	poly.BatchInverse(dens)
+	for i := 0; i < n; i++ {
+		quotient[i] = bls12381.NewFr()
+		quotient[i].Sub(p[i], y)
+		quotient[i].Mul(quotient[i], dens[i])
+	}
+	if inDomain >= 0 {
+		quotient[inDomain] = s.quotientInDomain(p, y, inDomain)
+	}
+
+	proof, err := msmG1(s.G1LagrangeBRP, quotient)
+	if err != nil {
+		return nil, nil, err
+	}
+	return proof, y, nil
+}
+
+// quotientInDomain returns the evaluation of the quotient at z = ωₘ, which
+// is in the domain:
+// q(z) = Σ_{i≠m} (pᵢ - y)⋅ωᵢ / (z⋅(z - ωᵢ))
+func (s *Setup) quotientInDomain(p Polynomial, y *bls12381.Fr, m int) *bls12381.Fr {
+	z := s.rootsBRP[m]
+	result := bls12381.NewFr()
+	num, den := bls12381.NewFr(), bls12381.NewFr()
+	for i, w := range s.rootsBRP {
+		if i == m {
+			continue
+		}
+		num.Sub(p[i], y)
+		num.Mul(num, w)
+		den.Sub(z, w)
+		den.Mul(den, z)
+		den.Inverse(den)
+		num.Mul(num, den)
+		result.Add(result, num)
+	}
+	return result
+}
+
+// bytesToG1 parses a compressed G1 point, checking that it is in the
+// subgroup. Unlike the ceremony points, the point at infinity is valid.
+func bytesToG1(b []byte) (*bls12381.PointG1, error) {
+	// FromCompressed checks that the point is on the curve and in the
+	// correct subgroup
+	return bls12381.NewG1().FromCompressed(b)
+}
+
+// hashToField reduces the hash, as a big-endian integer, modulo r
+func hashToField(h []byte) *bls12381.Fr {
+	v := new(big.Int).SetBytes(h)
+	v.Mod(v, bls12381.NewG1().Q())
+	return bls12381.NewFr().FromBytes(v.Bytes())
+}
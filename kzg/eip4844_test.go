@@ -0,0 +1,198 @@
+package kzg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+	qt "github.com/frankban/quicktest"
+)
+
+// testdata/eip4844_vectors.json.gz contains the consensus-specs EIP-4844
+// test vectors (kzg-mainnet) of blob_to_kzg_commitment,
+// compute_blob_kzg_proof and verify_blob_kzg_proof_batch, converted to JSON
+// with the blobs deduplicated in the "blobs" map
+type testVectors struct {
+	Blobs                   map[string]string `json:"blobs"`
+	BlobToKZGCommitment     []testCase        `json:"blob_to_kzg_commitment"`
+	ComputeBlobKZGProof     []testCase        `json:"compute_blob_kzg_proof"`
+	VerifyBlobKZGProofBatch []testCase        `json:"verify_blob_kzg_proof_batch"`
+}
+
+type testCase struct {
+	Name  string `json:"name"`
+	Input struct {
+		Blob        string   `json:"blob"`
+		Blobs       []string `json:"blobs"`
+		Commitment  string   `json:"commitment"`
+		Commitments []string `json:"commitments"`
+		Proofs      []string `json:"proofs"`
+	} `json:"input"`
+	Output json.RawMessage `json:"output"`
+}
+
+var (
+	mainnetSetupOnce sync.Once
+	mainnetSetup     *Setup
+	mainnetSetupErr  error
+)
+
+// loadMainnetSetup returns the Setup of the mainnet SRS of 4096 G1 powers,
+// read from the monomial points of the published trusted setup
+func loadMainnetSetup(c *qt.C) *Setup {
+	mainnetSetupOnce.Do(func() {
+		b, err := ioutil.ReadFile("../trusted_setup_4096.txt")
+		if err != nil {
+			mainnetSetupErr = err
+			return
+		}
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		nG1, nG2 := 4096, 65
+		srs := &kzgceremony.SRS{}
+		for _, l := range lines[2+nG1+nG2:] {
+			p, err := g1.FromCompressed(mustHex(l))
+			if err != nil {
+				mainnetSetupErr = err
+				return
+			}
+			srs.G1Powers = append(srs.G1Powers, p)
+		}
+		for _, l := range lines[2+nG1 : 2+nG1+nG2] {
+			p, err := g2.FromCompressed(mustHex(l))
+			if err != nil {
+				mainnetSetupErr = err
+				return
+			}
+			srs.G2Powers = append(srs.G2Powers, p)
+		}
+		mainnetSetup, mainnetSetupErr = NewSetup(srs)
+	})
+	c.Assert(mainnetSetupErr, qt.IsNil)
+	return mainnetSetup
+}
+
+func loadTestVectors(c *qt.C) *testVectors {
+	b, err := ioutil.ReadFile("testdata/eip4844_vectors.json.gz")
+	c.Assert(err, qt.IsNil)
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	c.Assert(err, qt.IsNil)
+	var v testVectors
+	err = json.NewDecoder(r).Decode(&v)
+	c.Assert(err, qt.IsNil)
+	return &v
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// parseBytes48 parses a commitment or proof, reporting false when it has
+// the wrong length, which the spec considers an invalid input
+func parseBytes48(s string) ([48]byte, bool) {
+	var out [48]byte
+	b := mustHex(s)
+	if len(b) != len(out) {
+		return out, false
+	}
+	copy(out[:], b)
+	return out, true
+}
+
+func TestBlobToKZGCommitmentVectors(t *testing.T) {
+	c := qt.New(t)
+	setup := loadMainnetSetup(c)
+	v := loadTestVectors(c)
+	c.Assert(v.BlobToKZGCommitment, qt.Not(qt.HasLen), 0)
+
+	for _, tc := range v.BlobToKZGCommitment {
+		var expected *string
+		c.Assert(json.Unmarshal(tc.Output, &expected), qt.IsNil)
+		commitment, err := setup.BlobToKZGCommitment(mustHex(v.Blobs[tc.Input.Blob]))
+		if expected == nil {
+			c.Assert(err, qt.Not(qt.IsNil), qt.Commentf(tc.Name))
+			continue
+		}
+		c.Assert(err, qt.IsNil, qt.Commentf(tc.Name))
+		c.Assert("0x"+hex.EncodeToString(commitment[:]), qt.Equals, *expected,
+			qt.Commentf(tc.Name))
+	}
+}
+
+func TestComputeBlobKZGProofVectors(t *testing.T) {
+	c := qt.New(t)
+	setup := loadMainnetSetup(c)
+	v := loadTestVectors(c)
+	c.Assert(v.ComputeBlobKZGProof, qt.Not(qt.HasLen), 0)
+
+	for _, tc := range v.ComputeBlobKZGProof {
+		var expected *string
+		c.Assert(json.Unmarshal(tc.Output, &expected), qt.IsNil)
+		commitment, ok := parseBytes48(tc.Input.Commitment)
+		var proof Proof
+		var err error
+		if ok {
+			proof, err = setup.ComputeBlobKZGProof(
+				mustHex(v.Blobs[tc.Input.Blob]), commitment)
+		}
+		if expected == nil {
+			c.Assert(!ok || err != nil, qt.IsTrue, qt.Commentf(tc.Name))
+			continue
+		}
+		c.Assert(ok, qt.IsTrue, qt.Commentf(tc.Name))
+		c.Assert(err, qt.IsNil, qt.Commentf(tc.Name))
+		c.Assert("0x"+hex.EncodeToString(proof[:]), qt.Equals, *expected,
+			qt.Commentf(tc.Name))
+	}
+}
+
+func TestVerifyBlobKZGProofBatchVectors(t *testing.T) {
+	c := qt.New(t)
+	setup := loadMainnetSetup(c)
+	v := loadTestVectors(c)
+	c.Assert(v.VerifyBlobKZGProofBatch, qt.Not(qt.HasLen), 0)
+
+	for _, tc := range v.VerifyBlobKZGProofBatch {
+		var expected *bool
+		c.Assert(json.Unmarshal(tc.Output, &expected), qt.IsNil)
+
+		var blobs []Blob
+		for _, b := range tc.Input.Blobs {
+			blobs = append(blobs, mustHex(v.Blobs[b]))
+		}
+		valid := true
+		var commitments []Commitment
+		for _, s := range tc.Input.Commitments {
+			b, ok := parseBytes48(s)
+			valid = valid && ok
+			commitments = append(commitments, b)
+		}
+		var proofs []Proof
+		for _, s := range tc.Input.Proofs {
+			b, ok := parseBytes48(s)
+			valid = valid && ok
+			proofs = append(proofs, b)
+		}
+		var ok bool
+		var err error
+		if valid {
+			ok, err = setup.VerifyBlobKZGProofBatch(blobs, commitments, proofs)
+		}
+		if expected == nil {
+			c.Assert(!valid || err != nil, qt.IsTrue, qt.Commentf(tc.Name))
+			continue
+		}
+		c.Assert(valid, qt.IsTrue, qt.Commentf(tc.Name))
+		c.Assert(err, qt.IsNil, qt.Commentf(tc.Name))
+		c.Assert(ok, qt.Equals, *expected, qt.Commentf(tc.Name))
+	}
+}
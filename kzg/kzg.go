@@ -0,0 +1,186 @@
+// Package kzg implements KZG polynomial commitments (Kate, Zaverucha,
+// Goldberg) over BLS12-381 on top of the SRS generated by the ceremony,
+// together with the EIP-4844 blob helpers.
+package kzg
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+	"github.com/arnaucube/eth-kzg-ceremony-alt/poly"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// Polynomial is a polynomial over Fr in coefficient form, where the i-th
+// element is the coefficient of Xⁱ
+type Polynomial = poly.Polynomial
+
+// Setup contains the SRS points used to commit to polynomials, open them
+// and verify the openings. It is safe for concurrent use: the methods do not
+// modify the points, and use their own bls12381 G1 and G2 instances, which
+// keep temporary values.
+type Setup struct {
+	// G1 are the powers [τ⁰]₁, [τ¹]₁, ..., [τⁿ⁻¹]₁
+	G1 []*bls12381.PointG1
+	// G1LagrangeBRP are the points [lᵢ(τ)]₁ of the Lagrange polynomials of
+	// the roots of unity domain of size n, in bit-reversed order, used to
+	// commit to polynomials in evaluation form (as the blobs)
+	G1LagrangeBRP []*bls12381.PointG1
+	// G2 are the powers [τ⁰]₂, [τ¹]₂
+	G2 []*bls12381.PointG2
+	// domain is the roots of unity domain of size n
+	domain *poly.Domain
+	// rootsBRP are the roots of unity ω⁰, ω¹, ..., ωⁿ⁻¹ of the domain in
+	// bit-reversed order
+	rootsBRP []*bls12381.Fr
+}
+
+// NewSetup returns the Setup for the given SRS, which must contain a power
+// of two number of G1 powers and at least two G2 powers
+func NewSetup(srs *kzgceremony.SRS) (*Setup, error) {
+	if len(srs.G2Powers) < 2 {
+		return nil, fmt.Errorf("SRS needs at least 2 G2 powers, got %d",
+			len(srs.G2Powers))
+	}
+	lagrange, err := srs.G1Lagrange()
+	if err != nil {
+		return nil, err
+	}
+	poly.BitReverse(lagrange)
+
+	domain, err := poly.NewDomain(len(srs.G1Powers))
+	if err != nil {
+		return nil, err
+	}
+	roots := make([]*bls12381.Fr, domain.Size)
+	copy(roots, domain.Roots)
+	poly.BitReverse(roots)
+
+	return &Setup{
+		G1:            srs.G1Powers,
+		G1LagrangeBRP: lagrange,
+		G2:            srs.G2Powers[:2],
+		domain:        domain,
+		rootsBRP:      roots,
+	}, nil
+}
+
+// Commit returns the commitment [p(τ)]₁ to the polynomial, whose degree
+// must be smaller than the number of G1 powers of the Setup
+func (s *Setup) Commit(p Polynomial) (*bls12381.PointG1, error) {
+	if len(p) > len(s.G1) {
+		return nil, fmt.Errorf("polynomial of %d coefficients exceeds the %d G1 powers",
+			len(p), len(s.G1))
+	}
+	return msmG1(s.G1[:len(p)], p)
+}
+
+// Open returns the evaluation y = p(z) together with the proof [q(τ)]₁,
+// where q(X) = (p(X) - y) / (X - z)
+func (s *Setup) Open(p Polynomial, z *bls12381.Fr) (*bls12381.PointG1, *bls12381.Fr, error) {
+	q, y := p.DivideByLinear(z)
+	proof, err := s.Commit(q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return proof, y, nil
+}
+
+// Verify checks that the proof opens the commitment to y at z, that is
+// e(C - [y]₁, [1]₂) = e(π, [τ]₂ - [z]₂)
+func (s *Setup) Verify(commitment *bls12381.PointG1, z, y *bls12381.Fr,
+	proof *bls12381.PointG1) bool {
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+	// C - [y]₁
+	cMinusY := g1.New()
+	g1.MulScalar(cMinusY, g1.One(), y)
+	g1.Sub(cMinusY, commitment, cMinusY)
+	// [τ]₂ - [z]₂
+	tauMinusZ := g2.New()
+	g2.MulScalar(tauMinusZ, s.G2[0], z)
+	g2.Sub(tauMinusZ, s.G2[1], tauMinusZ)
+
+	pairing := bls12381.NewEngine()
+	pairing.AddPair(cMinusY, s.g2(0))
+	pairing.AddPairInv(proof, tauMinusZ)
+	return pairing.Check()
+}
+
+// BatchVerify checks all the openings at once through a random linear
+// combination with the powers of a random r, requiring only two pairings:
+// e(Σ rⁱ⋅πᵢ, [τ]₂) = e(Σ rⁱ⋅(Cᵢ - [yᵢ]₁ + zᵢ⋅πᵢ), [1]₂)
+func (s *Setup) BatchVerify(commitments []*bls12381.PointG1, zs, ys []*bls12381.Fr,
+	proofs []*bls12381.PointG1) (bool, error) {
+	n := len(commitments)
+	if len(zs) != n || len(ys) != n || len(proofs) != n {
+		return false, fmt.Errorf("different number of commitments, points, evaluations and proofs")
+	}
+	r, err := bls12381.NewFr().Rand(rand.Reader)
+	if err != nil {
+		return false, err
+	}
+	return s.verifyBatch(commitments, zs, ys, proofs, r)
+}
+
+// verifyBatch checks the openings using the powers of r as coefficients of
+// the linear combination
+func (s *Setup) verifyBatch(commitments []*bls12381.PointG1, zs, ys []*bls12381.Fr,
+	proofs []*bls12381.PointG1, r *bls12381.Fr) (bool, error) {
+	n := len(commitments)
+	if n == 0 {
+		return true, nil
+	}
+	rPowers := make([]*bls12381.Fr, n)
+	rPowers[0] = bls12381.NewFr().One()
+	for i := 1; i < n; i++ {
+		rPowers[i] = bls12381.NewFr()
+		rPowers[i].Mul(rPowers[i-1], r)
+	}
+
+	// Σ rⁱ⋅πᵢ
+	proofLincomb, err := msmG1(proofs, rPowers)
+	if err != nil {
+		return false, err
+	}
+	// Σ rⁱ⋅zᵢ⋅πᵢ
+	rz := make([]*bls12381.Fr, n)
+	for i := range rz {
+		rz[i] = bls12381.NewFr()
+		rz[i].Mul(rPowers[i], zs[i])
+	}
+	proofZLincomb, err := msmG1(proofs, rz)
+	if err != nil {
+		return false, err
+	}
+	// Σ rⁱ⋅(Cᵢ - [yᵢ]₁)
+	g1 := bls12381.NewG1()
+	cMinusYs := make([]*bls12381.PointG1, n)
+	for i := range cMinusYs {
+		cMinusYs[i] = g1.New()
+		g1.MulScalar(cMinusYs[i], g1.One(), ys[i])
+		g1.Sub(cMinusYs[i], commitments[i], cMinusYs[i])
+	}
+	cMinusYLincomb, err := msmG1(cMinusYs, rPowers)
+	if err != nil {
+		return false, err
+	}
+	rhs := g1.New()
+	g1.Add(rhs, cMinusYLincomb, proofZLincomb)
+
+	pairing := bls12381.NewEngine()
+	pairing.AddPair(proofLincomb, s.g2(1))
+	pairing.AddPairInv(rhs, s.g2(0))
+	return pairing.Check(), nil
+}
+
+// g2 returns a copy of the i-th G2 power, as the pairing engine converts
+// the points to affine form in place
+func (s *Setup) g2(i int) *bls12381.PointG2 {
+	return new(bls12381.PointG2).Set(s.G2[i])
+}
+
+// msmG1 returns Σ scalarsᵢ⋅pointsᵢ, without modifying the points
+func msmG1(points []*bls12381.PointG1, scalars []*bls12381.Fr) (*bls12381.PointG1, error) {
+	return kzgceremony.MSMG1(points, scalars)
+}
@@ -0,0 +1,179 @@
+package kzg
+
+import (
+	"crypto/rand"
+	"math/big"
+	"sync"
+	"testing"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+var (
+	g1 = bls12381.NewG1()
+	g2 = bls12381.NewG2()
+)
+
+// testSRS returns the SRS of n G1 powers and 2 G2 powers for the given τ
+func testSRS(n int, tau *bls12381.Fr) *kzgceremony.SRS {
+	srs := &kzgceremony.SRS{}
+	t := bls12381.NewFr().One()
+	for i := 0; i < n; i++ {
+		p := g1.New()
+		g1.MulScalar(p, g1.One(), t)
+		srs.G1Powers = append(srs.G1Powers, p)
+		if i < 2 {
+			q := g2.New()
+			g2.MulScalar(q, g2.One(), t)
+			srs.G2Powers = append(srs.G2Powers, q)
+		}
+		t.Mul(t, tau)
+	}
+	return srs
+}
+
+func randPolynomial(c *qt.C, n int) Polynomial {
+	p := make(Polynomial, n)
+	for i := range p {
+		var err error
+		p[i], err = bls12381.NewFr().Rand(rand.Reader)
+		c.Assert(err, qt.IsNil)
+	}
+	return p
+}
+
+func TestCommitOpenVerify(t *testing.T) {
+	c := qt.New(t)
+	tau := bls12381.NewFr().FromBytes(big.NewInt(1234567).Bytes())
+	setup, err := NewSetup(testSRS(16, tau))
+	c.Assert(err, qt.IsNil)
+
+	p := randPolynomial(c, 16)
+	commitment, err := setup.Commit(p)
+	c.Assert(err, qt.IsNil)
+	// C = [p(τ)]₁
+	expected := g1.New()
+	g1.MulScalar(expected, g1.One(), p.Evaluate(tau))
+	c.Assert(g1.Equal(commitment, expected), qt.IsTrue)
+
+	z := bls12381.NewFr().FromBytes(big.NewInt(42).Bytes())
+	proof, y, err := setup.Open(p, z)
+	c.Assert(err, qt.IsNil)
+	c.Assert(y.Equal(p.Evaluate(z)), qt.IsTrue)
+	c.Assert(setup.Verify(commitment, z, y, proof), qt.IsTrue)
+
+	wrongY := bls12381.NewFr()
+	wrongY.Add(y, bls12381.NewFr().One())
+	c.Assert(setup.Verify(commitment, z, wrongY, proof), qt.IsFalse)
+	c.Assert(setup.Verify(commitment, wrongY, y, proof), qt.IsFalse)
+
+	_, err = setup.Commit(randPolynomial(c, 17))
+	c.Assert(err, qt.ErrorMatches,
+		"polynomial of 17 coefficients exceeds the 16 G1 powers")
+}
+
+func TestBatchVerify(t *testing.T) {
+	c := qt.New(t)
+	tau := bls12381.NewFr().FromBytes(big.NewInt(7654321).Bytes())
+	setup, err := NewSetup(testSRS(8, tau))
+	c.Assert(err, qt.IsNil)
+
+	var commitments, proofs []*bls12381.PointG1
+	var zs, ys []*bls12381.Fr
+	for i := 0; i < 5; i++ {
+		p := randPolynomial(c, 8-i)
+		commitment, err := setup.Commit(p)
+		c.Assert(err, qt.IsNil)
+		z, err := bls12381.NewFr().Rand(rand.Reader)
+		c.Assert(err, qt.IsNil)
+		proof, y, err := setup.Open(p, z)
+		c.Assert(err, qt.IsNil)
+		commitments = append(commitments, commitment)
+		proofs = append(proofs, proof)
+		zs = append(zs, z)
+		ys = append(ys, y)
+	}
+	ok, err := setup.BatchVerify(commitments, zs, ys, proofs)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+
+	ok, err = setup.BatchVerify(nil, nil, nil, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+
+	// swap two evaluations
+	ys[1], ys[2] = ys[2], ys[1]
+	ok, err = setup.BatchVerify(commitments, zs, ys, proofs)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsFalse)
+
+	_, err = setup.BatchVerify(commitments, zs, ys[:4], proofs)
+	c.Assert(err, qt.Not(qt.IsNil))
+}
+
+func TestDivideByLinear(t *testing.T) {
+	c := qt.New(t)
+	p := randPolynomial(c, 10)
+	z, err := bls12381.NewFr().Rand(rand.Reader)
+	c.Assert(err, qt.IsNil)
+	q, y := p.DivideByLinear(z)
+	c.Assert(y.Equal(p.Evaluate(z)), qt.IsTrue)
+
+	// p(x) - y = q(x)⋅(x - z) at a random x
+	x, err := bls12381.NewFr().Rand(rand.Reader)
+	c.Assert(err, qt.IsNil)
+	l := bls12381.NewFr()
+	l.Sub(p.Evaluate(x), y)
+	r := bls12381.NewFr()
+	r.Sub(x, z)
+	r.Mul(r, q.Evaluate(x))
+	c.Assert(l.Equal(r), qt.IsTrue)
+}
+
+func TestConcurrentUse(t *testing.T) {
+	c := qt.New(t)
+	tau := bls12381.NewFr().FromBytes(big.NewInt(1234567).Bytes())
+	setup, err := NewSetup(testSRS(16, tau))
+	c.Assert(err, qt.IsNil)
+
+	// run with -race to detect shared temporary values
+	n := 8
+	ps := make([]Polynomial, n)
+	for i := range ps {
+		ps[i] = randPolynomial(c, 16)
+	}
+	blob := make(Blob, 16*BytesPerFieldElement)
+	blob[BytesPerFieldElement-1] = 1
+	expected, err := setup.BlobToKZGCommitment(blob)
+	c.Assert(err, qt.IsNil)
+
+	oks := make([]bool, n)
+	commitments := make([]Commitment, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			z := bls12381.NewFr().FromBytes(big.NewInt(int64(i + 1)).Bytes())
+			commitment, err := setup.Commit(ps[i])
+			if err != nil {
+				return
+			}
+			proof, y, err := setup.Open(ps[i], z)
+			if err != nil {
+				return
+			}
+			batchOK, err := setup.BatchVerify([]*bls12381.PointG1{commitment},
+				[]*bls12381.Fr{z}, []*bls12381.Fr{y}, []*bls12381.PointG1{proof})
+			oks[i] = err == nil && batchOK && setup.Verify(commitment, z, y, proof)
+			commitments[i], _ = setup.BlobToKZGCommitment(blob)
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		c.Assert(oks[i], qt.IsTrue)
+		c.Assert(commitments[i], qt.Equals, expected)
+	}
+}
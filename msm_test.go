@@ -0,0 +1,105 @@
+package kzgceremony
+
+import (
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+func randomMSMInputs(c *qt.C, n int) ([]*bls12381.PointG1, []*bls12381.PointG2, []*bls12381.Fr) {
+	ps1 := make([]*bls12381.PointG1, n)
+	ps2 := make([]*bls12381.PointG2, n)
+	ss := make([]*bls12381.Fr, n)
+	for i := 0; i < n; i++ {
+		k, err := bls12381.NewFr().Rand(rand.Reader)
+		c.Assert(err, qt.IsNil)
+		ps1[i] = g1.New()
+		g1.MulScalar(ps1[i], g1.One(), k)
+		ps2[i] = g2.New()
+		g2.MulScalar(ps2[i], g2.One(), k)
+		ss[i], err = bls12381.NewFr().Rand(rand.Reader)
+		c.Assert(err, qt.IsNil)
+	}
+	return ps1, ps2, ss
+}
+
+func TestMSM(t *testing.T) {
+	c := qt.New(t)
+
+	ps1, ps2, ss := randomMSMInputs(c, 40)
+	// zero scalars, the largest scalar (r - 1) and
+	// points at infinity
+	ss[3] = bls12381.NewFr()
+	ss[7] = bls12381.NewFr().One()
+	ss[7].Sub(bls12381.NewFr(), ss[7])
+	ps1[9], ps2[9] = g1.Zero(), g2.Zero()
+	// the inputs must not be modified
+	ps1[11].Set(g1.One())
+	g1.Add(ps1[11], ps1[11], g1.One())
+	z11 := ps1[11][2]
+
+	expected1 := g1.Zero()
+	expected2 := g2.Zero()
+	for i := range ss {
+		t1 := g1.New()
+		g1.MulScalar(t1, ps1[i], ss[i])
+		g1.Add(expected1, expected1, t1)
+		t2 := g2.New()
+		g2.MulScalar(t2, ps2[i], ss[i])
+		g2.Add(expected2, expected2, t2)
+	}
+
+	for _, cfg := range []MSMConfig{{}, {Window: 1}, {Window: 4, Workers: 1},
+		{Window: 7, Workers: 3}, {Window: 13}, {Window: 16, Workers: 2}} {
+		r1, err := cfg.MSMG1(ps1, ss)
+		c.Assert(err, qt.IsNil)
+		c.Assert(g1.Equal(r1, expected1), qt.IsTrue, qt.Commentf("%+v", cfg))
+		r2, err := cfg.MSMG2(ps2, ss)
+		c.Assert(err, qt.IsNil)
+		c.Assert(g2.Equal(r2, expected2), qt.IsTrue, qt.Commentf("%+v", cfg))
+	}
+	c.Assert(ps1[11][2], qt.Equals, z11)
+
+	r1, err := MSMG1(nil, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(g1.IsZero(r1), qt.IsTrue)
+	_, err = MSMG2(ps2, ss[1:])
+	c.Assert(err, qt.ErrorMatches, "different number of points .*")
+}
+
+func TestScalarWindow(t *testing.T) {
+	c := qt.New(t)
+
+	s := &bls12381.Fr{0xf000000000000001, 0x0123456789abcdef, 0, 0x7fffffffffffffff}
+	c.Assert(scalarWindow(s, 0, 1), qt.Equals, uint64(1))
+	c.Assert(scalarWindow(s, 60, 8), qt.Equals, uint64(0xff))
+	c.Assert(scalarWindow(s, 60, 12), qt.Equals, uint64(0xeff))
+	c.Assert(scalarWindow(s, 64, 16), qt.Equals, uint64(0xcdef))
+	c.Assert(scalarWindow(s, 252, 8), qt.Equals, uint64(0x7))
+}
+
+func BenchmarkMSM(b *testing.B) {
+	c := qt.New(b)
+	ps1, ps2, ss := randomMSMInputs(c, 32768)
+	for _, n := range []int{4096, 8192, 16384, 32768} {
+		b.Run(fmt.Sprintf("G1/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = MSMG1(ps1[:n], ss[:n])
+			}
+		})
+		b.Run(fmt.Sprintf("G1-MultiExp/%d", n), func(b *testing.B) {
+			g := bls12381.NewG1()
+			for i := 0; i < b.N; i++ {
+				_, _ = g.MultiExp(g.New(), ps1[:n], ss[:n])
+			}
+		})
+		b.Run(fmt.Sprintf("G2/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = MSMG2(ps2[:n], ss[:n])
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package kzgceremony
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -12,54 +13,7 @@ import (
 // UnmarshalJSON implements the State json unmarshaler, compatible
 // with the official Ethereum KZG Ceremony formats
 func (s *State) UnmarshalJSON(b []byte) error {
-	var sStr stateStr
-	if err := json.Unmarshal(b, &sStr); err != nil {
-		return err
-	}
-	var err error
-	s.ParticipantIDs = sStr.ParticipantIDs
-	s.ParticipantECDSASignatures = sStr.ParticipantECDSASignatures
-
-	s.Transcripts = make([]Transcript, len(sStr.Transcripts))
-	for i := 0; i < len(sStr.Transcripts); i++ {
-		if sStr.Transcripts[i].NumG1Powers != uint64(len(sStr.Transcripts[i].PowersOfTau.G1Powers)) {
-			return fmt.Errorf("wrong NumG1Powers")
-		}
-		if sStr.Transcripts[i].NumG2Powers != uint64(len(sStr.Transcripts[i].PowersOfTau.G2Powers)) {
-			return fmt.Errorf("wrong NumG2Powers")
-		}
-		s.Transcripts[i].NumG1Powers = sStr.Transcripts[i].NumG1Powers
-		s.Transcripts[i].NumG2Powers = sStr.Transcripts[i].NumG2Powers
-		s.Transcripts[i].PowersOfTau = &SRS{}
-		s.Transcripts[i].PowersOfTau.G1Powers, err =
-			stringsToPointsG1(sStr.Transcripts[i].PowersOfTau.G1Powers)
-		if err != nil {
-			return err
-		}
-		s.Transcripts[i].PowersOfTau.G2Powers, err =
-			stringsToPointsG2(sStr.Transcripts[i].PowersOfTau.G2Powers)
-		if err != nil {
-			return err
-		}
-
-		s.Transcripts[i].Witness = &Witness{}
-		s.Transcripts[i].Witness.RunningProducts, err =
-			stringsToPointsG1(sStr.Transcripts[i].Witness.RunningProducts)
-		if err != nil {
-			return err
-		}
-		s.Transcripts[i].Witness.PotPubKeys, err =
-			stringsToPointsG2(sStr.Transcripts[i].Witness.PotPubKeys)
-		if err != nil {
-			return err
-		}
-		s.Transcripts[i].Witness.BLSSignatures, err =
-			stringsToPointsG1(sStr.Transcripts[i].Witness.BLSSignatures)
-		if err != nil {
-			return err
-		}
-	}
-	return err
+	return NewStateDecoder(bytes.NewReader(b)).Decode(s)
 }
 
 // MarshalJSON implements the State json marshaler, compatible with the
@@ -104,6 +58,7 @@ func (c *BatchContribution) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	var err error
+	c.ECDSASignature = cStr.ECDSASignature
 
 	c.Contributions = make([]Contribution, len(cStr.Contributions))
 	for i := 0; i < len(cStr.Contributions); i++ {
@@ -129,6 +84,12 @@ func (c *BatchContribution) UnmarshalJSON(b []byte) error {
 		if err != nil {
 			return err
 		}
+
+		c.Contributions[i].BLSSignature, err =
+			stringToPointG1(cStr.Contributions[i].BLSSignature)
+		if err != nil {
+			return err
+		}
 	}
 	return err
 }
@@ -136,22 +97,11 @@ func (c *BatchContribution) UnmarshalJSON(b []byte) error {
 // MarshalJSON implements the BatchContribution json marshaler, compatible
 // with the official Ethereum KZG Ceremony formats
 func (c BatchContribution) MarshalJSON() ([]byte, error) {
-	var cStr batchContributionStr
-
-	cStr.Contributions = make([]contributionStr, len(c.Contributions))
-	for i := 0; i < len(c.Contributions); i++ {
-		cStr.Contributions[i].NumG1Powers = c.Contributions[i].NumG1Powers
-		cStr.Contributions[i].NumG2Powers = c.Contributions[i].NumG2Powers
-		cStr.Contributions[i].PowersOfTau = powersOfTauStr{}
-		cStr.Contributions[i].PowersOfTau.G1Powers =
-			g1PointsToStrings(c.Contributions[i].PowersOfTau.G1Powers)
-		cStr.Contributions[i].PowersOfTau.G2Powers =
-			g2PointsToStrings(c.Contributions[i].PowersOfTau.G2Powers)
-
-		cStr.Contributions[i].PotPubKey = "0x" +
-			hex.EncodeToString(g2.ToCompressed(c.Contributions[i].PotPubKey))
+	var b bytes.Buffer
+	if err := NewBatchContributionEncoder(&b).Encode(&c); err != nil {
+		return nil, err
 	}
-	return json.Marshal(cStr)
+	return b.Bytes(), nil
 }
 
 type powersOfTauStr struct {
@@ -173,14 +123,16 @@ type transcriptStr struct {
 }
 
 type contributionStr struct {
-	NumG1Powers uint64         `json:"numG1Powers"`
-	NumG2Powers uint64         `json:"numG2Powers"`
-	PowersOfTau powersOfTauStr `json:"powersOfTau"`
-	PotPubKey   string         `json:"potPubkey"`
+	NumG1Powers  uint64         `json:"numG1Powers"`
+	NumG2Powers  uint64         `json:"numG2Powers"`
+	PowersOfTau  powersOfTauStr `json:"powersOfTau"`
+	PotPubKey    string         `json:"potPubkey"`
+	BLSSignature string         `json:"blsSignature"`
 }
 
 type batchContributionStr struct {
-	Contributions []contributionStr `json:"contributions"`
+	Contributions  []contributionStr `json:"contributions"`
+	ECDSASignature string            `json:"ecdsaSignature"`
 }
 
 type stateStr struct {
@@ -193,11 +145,7 @@ func g1PointsToStrings(points []*bls12381.PointG1) []string {
 	n := len(points)
 	g1s := make([]string, n)
 	for i := 0; i < n; i++ {
-		if points[i] == nil {
-			g1s[i] = ""
-			continue
-		}
-		g1s[i] = "0x" + hex.EncodeToString(g1.ToCompressed(points[i]))
+		g1s[i] = g1PointToString(points[i])
 	}
 	return g1s
 }
@@ -206,63 +154,144 @@ func g2PointsToStrings(points []*bls12381.PointG2) []string {
 	n := len(points)
 	g2s := make([]string, n)
 	for i := 0; i < n; i++ {
-		if points[i] == nil {
-			g2s[i] = ""
-			continue
-		}
-		g2s[i] = "0x" + hex.EncodeToString(g2.ToCompressed(points[i]))
+		g2s[i] = g2PointToString(points[i])
 	}
 	return g2s
 }
 
+// g1PointToString returns the ZCash compressed format of the point as a 0x
+// prefixed hex string, or an empty string for a nil point
+func g1PointToString(p *bls12381.PointG1) string {
+	if p == nil {
+		return ""
+	}
+	return "0x" + hex.EncodeToString(g1.ToCompressed(p))
+}
+
+// g2PointToString returns the ZCash compressed format of the point as a 0x
+// prefixed hex string, or an empty string for a nil point
+func g2PointToString(p *bls12381.PointG2) string {
+	if p == nil {
+		return ""
+	}
+	return "0x" + hex.EncodeToString(g2.ToCompressed(p))
+}
+
 // stringsToPointsG1 parses the strings that represent the G1 points in the
 // ZCash compressed format into bls12381.PointG1 data structure. Additionally
 // it checks the points correctness
 func stringsToPointsG1(s []string) ([]*bls12381.PointG1, error) {
-	n := len(s)
-	g1s := make([]*bls12381.PointG1, n)
-	for i := 0; i < n; i++ {
-		if s[i] == "" {
-			continue
-		}
-		g1sBytes, err := hex.DecodeString(strings.TrimPrefix(s[i], "0x"))
-		if err != nil {
-			return nil, err
+	g1s := make([]*bls12381.PointG1, len(s))
+	if i, err := decodePointsG1(g1s, s); err != nil {
+		return nil, fmt.Errorf("G1 point %d: %w", i, err)
+	}
+	return g1s, nil
+}
+
+// decodePointsG1 parses the points of s into dst concurrently, returning the
+// lowest index that failed together with its error
+func decodePointsG1(dst []*bls12381.PointG1, s []string) (int, error) {
+	errs := make([]error, len(s))
+	parallelize(len(s), func(start, end int) {
+		// each worker uses its own G1 instance, as it contains temporary
+		// values used by the correctness checks
+		g1 := bls12381.NewG1()
+		for i := start; i < end; i++ {
+			dst[i], errs[i] = decodePointG1(g1, s[i])
+			if errs[i] != nil {
+				return
+			}
 		}
-		g1s_i, err := g1.FromCompressed(g1sBytes)
+	})
+	// each worker stops at its first error, so the first error found
+	// here is the one at the lowest index
+	for i, err := range errs {
 		if err != nil {
-			return nil, err
-		}
-		if err := checkG1PointCorrectness(g1s_i); err != nil {
-			return nil, err
+			return i, err
 		}
-		g1s[i] = g1s_i
 	}
-	return g1s, nil
+	return -1, nil
 }
 
 // stringsToPointsG2 parses the strings that represent the G2 points in the
 // ZCash compressed format into bls12381.PointG2 data structure. Additionally
 // it checks the points correctness
 func stringsToPointsG2(s []string) ([]*bls12381.PointG2, error) {
-	n := len(s)
-	g2s := make([]*bls12381.PointG2, n)
-	for i := 0; i < n; i++ {
-		if s[i] == "" {
-			continue
-		}
-		g2sBytes, err := hex.DecodeString(strings.TrimPrefix(s[i], "0x"))
-		if err != nil {
-			return nil, err
+	g2s := make([]*bls12381.PointG2, len(s))
+	if i, err := decodePointsG2(g2s, s); err != nil {
+		return nil, fmt.Errorf("G2 point %d: %w", i, err)
+	}
+	return g2s, nil
+}
+
+// decodePointsG2 acts as decodePointsG1 for G2 points
+func decodePointsG2(dst []*bls12381.PointG2, s []string) (int, error) {
+	errs := make([]error, len(s))
+	parallelize(len(s), func(start, end int) {
+		g2 := bls12381.NewG2()
+		for i := start; i < end; i++ {
+			dst[i], errs[i] = decodePointG2(g2, s[i])
+			if errs[i] != nil {
+				return
+			}
 		}
-		g2s_i, err := g2.FromCompressed(g2sBytes)
+	})
+	for i, err := range errs {
 		if err != nil {
-			return nil, err
-		}
-		if err := checkG2PointCorrectness(g2s_i); err != nil {
-			return nil, err
+			return i, err
 		}
-		g2s[i] = g2s_i
 	}
-	return g2s, nil
+	return -1, nil
+}
+
+// stringToPointG1 parses the string that represents a G1 point in the ZCash
+// compressed format, checking its correctness. An empty string is parsed as
+// a nil point.
+func stringToPointG1(s string) (*bls12381.PointG1, error) {
+	return decodePointG1(g1, s)
+}
+
+// decodePointG1 acts as stringToPointG1 using the given G1 instance
+func decodePointG1(g1 *bls12381.G1, s string) (*bls12381.PointG1, error) {
+	if s == "" {
+		return nil, nil
+	}
+	g1sBytes, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	p, err := g1.FromCompressed(g1sBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkG1Point(g1, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// stringToPointG2 parses the string that represents a G2 point in the ZCash
+// compressed format, checking its correctness. An empty string is parsed as
+// a nil point.
+func stringToPointG2(s string) (*bls12381.PointG2, error) {
+	return decodePointG2(g2, s)
+}
+
+// decodePointG2 acts as stringToPointG2 using the given G2 instance
+func decodePointG2(g2 *bls12381.G2, s string) (*bls12381.PointG2, error) {
+	if s == "" {
+		return nil, nil
+	}
+	g2sBytes, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	p, err := g2.FromCompressed(g2sBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkG2Point(g2, p); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
@@ -81,3 +81,52 @@ func TestParseCompressedG2Point(t *testing.T) {
 	// additionally check that g1Point is zero
 	c.Assert(g2.Equal(g2Point, g2.Zero()), qt.IsTrue)
 }
+
+func TestStringsToPointsFirstError(t *testing.T) {
+	c := qt.New(t)
+
+	srs := newEmptySRS(64, 64)
+	g1s := g1PointsToStrings(srs.G1Powers)
+	g2s := g2PointsToStrings(srs.G2Powers)
+	// index 40 fails in the decoding and 5 in the correctness check, the
+	// lowest index must be the one reported
+	zeroG1 := "0xc" + strings.Repeat("0", 95)
+	zeroG2 := "0xc" + strings.Repeat("0", 191)
+	g1s[40], g2s[40] = "0xzz", "0xzz"
+	g1s[5], g2s[5] = zeroG1, zeroG2
+
+	defer func(n int) { NumWorkers = n }(NumWorkers)
+	for _, workers := range []int{1, 3, 8, 64} {
+		NumWorkers = workers
+		_, err := stringsToPointsG1(g1s)
+		c.Assert(err, qt.ErrorMatches, "G1 point 5: point can not be zero")
+		_, err = stringsToPointsG2(g2s)
+		c.Assert(err, qt.ErrorMatches, "G2 point 5: point can not be zero")
+	}
+
+	g1s[5], g2s[5] = g1s[4], g2s[4]
+	_, err := stringsToPointsG1(g1s)
+	c.Assert(err, qt.ErrorMatches, "G1 point 40: encoding/hex: .*")
+
+	g1s[40], g2s[40] = g1s[4], g2s[4]
+	points1, err := stringsToPointsG1(g1s)
+	c.Assert(err, qt.IsNil)
+	c.Assert(g1PointsToStrings(points1), qt.DeepEquals, g1s)
+	points2, err := stringsToPointsG2(g2s)
+	c.Assert(err, qt.IsNil)
+	c.Assert(g2PointsToStrings(points2), qt.DeepEquals, g2s)
+}
+
+func BenchmarkParseState(b *testing.B) {
+	j, err := ioutil.ReadFile("current_state_10.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		state := &State{}
+		if err := json.Unmarshal(j, state); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package poly
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+	"runtime"
+	"sync"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// PrimitiveRootOfUnity is the generator of the multiplicative group of Fr
+// used by the consensus specs (PRIMITIVE_ROOT_OF_UNITY) to compute the roots
+// of unity of the evaluation domains
+const PrimitiveRootOfUnity = 7
+
+// Domain is the multiplicative subgroup of Fr of the n-th roots of unity
+// ω⁰, ω¹, ..., ωⁿ⁻¹, for a power of two n, with ω as in the consensus specs
+type Domain struct {
+	// Size is the number n of elements of the domain
+	Size int
+	// Generator is the primitive n-th root of unity ω
+	Generator *bls12381.Fr
+	// GeneratorInv is ω⁻¹
+	GeneratorInv *bls12381.Fr
+	// SizeInv is 1/n
+	SizeInv *bls12381.Fr
+	// Roots are the elements ωⁱ of the domain in natural order
+	Roots []*bls12381.Fr
+	// Workers is the number of goroutines used by the FFTs over G1, when
+	// it is not a positive number runtime.NumCPU() is used
+	Workers int
+}
+
+// NewDomain returns the Domain of the n-th roots of unity, where n must be a
+// power of two
+func NewDomain(n int) (*Domain, error) {
+	if n <= 0 || n&(n-1) != 0 {
+		return nil, fmt.Errorf("domain size %d is not a power of two", n)
+	}
+	omega := RootOfUnity(n)
+	omegaInv := bls12381.NewFr()
+	omegaInv.Inverse(omega)
+	nInv := bls12381.NewFr().FromBytes(big.NewInt(int64(n)).Bytes())
+	nInv.Inverse(nInv)
+
+	roots := make([]*bls12381.Fr, n)
+	roots[0] = bls12381.NewFr().One()
+	for i := 1; i < n; i++ {
+		roots[i] = bls12381.NewFr()
+		roots[i].Mul(roots[i-1], omega)
+	}
+	return &Domain{
+		Size:         n,
+		Generator:    omega,
+		GeneratorInv: omegaInv,
+		SizeInv:      nInv,
+		Roots:        roots,
+	}, nil
+}
+
+// RootOfUnity returns a primitive n-th root of unity of Fr, as computed by
+// the consensus specs: 7^((r - 1) / n). n must divide r - 1, that is, be a
+// power of two up to 2³².
+func RootOfUnity(n int) *bls12381.Fr {
+	exp := new(big.Int).Sub(bls12381.NewG1().Q(), big.NewInt(1))
+	exp.Div(exp, big.NewInt(int64(n)))
+	omega := bls12381.NewFr()
+	omega.Exp(bls12381.NewFr().FromBytes(big.NewInt(PrimitiveRootOfUnity).Bytes()), exp)
+	return omega
+}
+
+// FFT returns the evaluations p(ω⁰), p(ω¹), ..., p(ωⁿ⁻¹) of the polynomial,
+// which must have at most n coefficients
+func (d *Domain) FFT(p Polynomial) ([]*bls12381.Fr, error) {
+	if len(p) > d.Size {
+		return nil, fmt.Errorf("polynomial of %d coefficients exceeds the domain size %d",
+			len(p), d.Size)
+	}
+	evals := make([]*bls12381.Fr, d.Size)
+	for i := range evals {
+		evals[i] = bls12381.NewFr()
+		if i < len(p) {
+			evals[i].Set(p[i])
+		}
+	}
+	frFFT(evals, d.Generator)
+	return evals, nil
+}
+
+// IFFT returns the polynomial of the n evaluations p(ω⁰), p(ω¹), ...,
+// p(ωⁿ⁻¹), that is, its coefficients pᵢ = 1/n ⋅ Σⱼ p(ωʲ)⋅ω⁻ⁱʲ
+func (d *Domain) IFFT(evals []*bls12381.Fr) (Polynomial, error) {
+	if len(evals) != d.Size {
+		return nil, fmt.Errorf("%d evaluations for a domain of size %d",
+			len(evals), d.Size)
+	}
+	p := make(Polynomial, d.Size)
+	for i := range evals {
+		p[i] = bls12381.NewFr().Set(evals[i])
+	}
+	frFFT(p, d.GeneratorInv)
+	for i := range p {
+		p[i].Mul(p[i], d.SizeInv)
+	}
+	return p, nil
+}
+
+// FFTG1 returns the points Σⱼ ωⁱʲ⋅pointsⱼ for each i < n, which for the
+// points [cⱼ]₁ are the evaluations [p(ωⁱ)]₁ of the polynomial p with
+// coefficients cⱼ. There must be at most n points.
+func (d *Domain) FFTG1(points []*bls12381.PointG1) ([]*bls12381.PointG1, error) {
+	if len(points) > d.Size {
+		return nil, fmt.Errorf("%d points exceed the domain size %d",
+			len(points), d.Size)
+	}
+	g1 := bls12381.NewG1()
+	out := make([]*bls12381.PointG1, d.Size)
+	for i := range out {
+		out[i] = g1.Zero()
+		if i < len(points) {
+			out[i].Set(points[i])
+		}
+	}
+	g1FFT(out, d.Generator, d.Workers)
+	return out, nil
+}
+
+// IFFTG1 returns the points 1/n ⋅ Σⱼ ω⁻ⁱʲ⋅pointsⱼ for each i < n, the
+// inverse of FFTG1. For the monomial powers [τⁱ]₁ of an SRS it returns its
+// Lagrange form [l₀(τ)]₁, [l₁(τ)]₁, ..., [lₙ₋₁(τ)]₁.
+func (d *Domain) IFFTG1(points []*bls12381.PointG1) ([]*bls12381.PointG1, error) {
+	if len(points) != d.Size {
+		return nil, fmt.Errorf("%d points for a domain of size %d",
+			len(points), d.Size)
+	}
+	g1 := bls12381.NewG1()
+	out := make([]*bls12381.PointG1, d.Size)
+	for i := range out {
+		out[i] = g1.New().Set(points[i])
+	}
+	g1FFT(out, d.GeneratorInv, d.Workers)
+	parallelize(d.Workers, d.Size, func(start, end int) {
+		g1 := bls12381.NewG1()
+		for i := start; i < end; i++ {
+			g1.MulScalar(out[i], out[i], d.SizeInv)
+		}
+	})
+	return out, nil
+}
+
+// EvaluateLagrange returns p(z) for the polynomial given by its n
+// evaluations p(ω⁰), p(ω¹), ..., p(ωⁿ⁻¹), through the barycentric formula
+//
+//	p(z) = (zⁿ - 1) / n ⋅ Σ p(ωⁱ)⋅ωⁱ / (z - ωⁱ)
+//
+// or directly the evaluation when z is in the domain
+func (d *Domain) EvaluateLagrange(evals []*bls12381.Fr, z *bls12381.Fr) (*bls12381.Fr, error) {
+	if len(evals) != d.Size {
+		return nil, fmt.Errorf("%d evaluations for a domain of size %d",
+			len(evals), d.Size)
+	}
+	dens := make([]*bls12381.Fr, d.Size)
+	for i, w := range d.Roots {
+		dens[i] = bls12381.NewFr()
+		dens[i].Sub(z, w)
+		if dens[i].IsZero() {
+			return bls12381.NewFr().Set(evals[i]), nil
+		}
+	}
+	BatchInverse(dens)
+
+	result := bls12381.NewFr()
+	t := bls12381.NewFr()
+	for i, w := range d.Roots {
+		t.Mul(evals[i], w)
+		t.Mul(t, dens[i])
+		result.Add(result, t)
+	}
+	zn := bls12381.NewFr()
+	zn.Exp(z, big.NewInt(int64(d.Size)))
+	zn.Sub(zn, bls12381.NewFr().One())
+	result.Mul(result, zn)
+	result.Mul(result, d.SizeInv)
+	return result, nil
+}
+
+// BitReverse permutes in place the elements of s, whose length must be a
+// power of two, moving the element at index i to the index whose bits are
+// the ones of i in reverse order, as bit_reversal_permutation from the
+// consensus specs
+func BitReverse[T any](s []T) {
+	n := len(s)
+	if n <= 1 {
+		return
+	}
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := 0; i < n; i++ {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			s[i], s[j] = s[j], s[i]
+		}
+	}
+}
+
+// twiddles returns ω⁰, ω¹, ..., ωⁿ/²⁻¹
+func twiddles(n int, omega *bls12381.Fr) []bls12381.Fr {
+	t := make([]bls12381.Fr, n/2)
+	t[0].One()
+	for j := 1; j < n/2; j++ {
+		t[j].Mul(&t[j-1], omega)
+	}
+	return t
+}
+
+// frFFT computes in place the DFT of the elements over the domain generated
+// by omega, an n-th root of unity where n = len(elems) is a power of two,
+// using the iterative radix-2 Cooley-Tukey algorithm
+func frFFT(elems []*bls12381.Fr, omega *bls12381.Fr) {
+	n := len(elems)
+	if n <= 1 {
+		return
+	}
+	tw := twiddles(n, omega)
+	BitReverse(elems)
+	t := bls12381.NewFr()
+	for m := 2; m <= n; m <<= 1 {
+		half := m / 2
+		stride := n / m
+		for k := 0; k < n; k += m {
+			for j := 0; j < half; j++ {
+				t.Mul(elems[k+j+half], &tw[j*stride])
+				elems[k+j+half].Sub(elems[k+j], t)
+				elems[k+j].Add(elems[k+j], t)
+			}
+		}
+	}
+}
+
+// g1FFT computes in place the DFT of the points as frFFT. The butterflies of
+// each stage are computed concurrently.
+func g1FFT(points []*bls12381.PointG1, omega *bls12381.Fr, workers int) {
+	n := len(points)
+	if n <= 1 {
+		return
+	}
+	tw := twiddles(n, omega)
+	BitReverse(points)
+	for m := 2; m <= n; m <<= 1 {
+		half := m / 2
+		stride := n / m
+		parallelize(workers, n/2, func(start, end int) {
+			g1 := bls12381.NewG1()
+			t := g1.New()
+			for b := start; b < end; b++ {
+				k := (b/half)*m + b%half
+				j := b % half
+				g1.MulScalar(t, points[k+half], &tw[j*stride])
+				g1.Sub(points[k+half], points[k], t)
+				g1.Add(points[k], points[k], t)
+			}
+		})
+	}
+}
+
+// parallelize splits the range [0, n) into chunks and calls f for each chunk
+// from a pool of goroutines, waiting until all of them finish
+func parallelize(workers, n int, f func(start, end int)) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		f(0, n)
+		return
+	}
+	chunk := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += chunk {
+		end := start + chunk
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			f(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
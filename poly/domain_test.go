@@ -0,0 +1,162 @@
+package poly
+
+import (
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+var g1 = bls12381.NewG1()
+
+func TestNewDomain(t *testing.T) {
+	c := qt.New(t)
+
+	for _, n := range []int{0, 3, 6, 100} {
+		_, err := NewDomain(n)
+		c.Assert(err, qt.ErrorMatches, "domain size .* is not a power of two")
+	}
+
+	for n := 1; n <= 1<<12; n <<= 1 {
+		d, err := NewDomain(n)
+		c.Assert(err, qt.IsNil)
+		c.Assert(d.Roots, qt.HasLen, n)
+		// ω is a primitive n-th root of unity
+		e := bls12381.NewFr()
+		e.Exp(d.Generator, big.NewInt(int64(n)))
+		c.Assert(e.Equal(frFromInt(1)), qt.IsTrue)
+		if n > 1 {
+			e.Exp(d.Generator, big.NewInt(int64(n/2)))
+			c.Assert(e.Equal(frFromInt(1)), qt.IsFalse)
+		}
+		e.Mul(d.Generator, d.GeneratorInv)
+		c.Assert(e.Equal(frFromInt(1)), qt.IsTrue)
+		e.Mul(d.SizeInv, frFromInt(int64(n)))
+		c.Assert(e.Equal(frFromInt(1)), qt.IsTrue)
+	}
+
+	// 4096-th root of unity from the consensus specs (ROOTS_OF_UNITY[1])
+	omega, ok := new(big.Int).SetString(
+		"39033254847818212395286706435128746857159659164139250548781411570340225835782", 10)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(RootOfUnity(4096).Equal(bls12381.NewFr().FromBytes(omega.Bytes())), qt.IsTrue)
+}
+
+func TestBitReverse(t *testing.T) {
+	c := qt.New(t)
+
+	s := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	BitReverse(s)
+	c.Assert(s, qt.DeepEquals, []int{0, 4, 2, 6, 1, 5, 3, 7})
+	BitReverse(s)
+	c.Assert(s, qt.DeepEquals, []int{0, 1, 2, 3, 4, 5, 6, 7})
+
+	one := []int{9}
+	BitReverse(one)
+	c.Assert(one, qt.DeepEquals, []int{9})
+}
+
+func TestFFT(t *testing.T) {
+	c := qt.New(t)
+
+	for n := 1; n <= 64; n <<= 1 {
+		d, err := NewDomain(n)
+		c.Assert(err, qt.IsNil)
+		// full and shorter polynomials, which are padded with zeros
+		for _, size := range []int{n, n/2 + 1, 1, 0} {
+			p := randPolynomial(c, size)
+			evals, err := d.FFT(p)
+			c.Assert(err, qt.IsNil)
+			for i, w := range d.Roots {
+				c.Assert(evals[i].Equal(naiveEvaluate(p, w)), qt.IsTrue,
+					qt.Commentf("n=%d size=%d i=%d", n, size, i))
+			}
+
+			q, err := d.IFFT(evals)
+			c.Assert(err, qt.IsNil)
+			c.Assert(q.Degree(), qt.Equals, p.Degree())
+			for i := range p {
+				c.Assert(q[i].Equal(p[i]), qt.IsTrue)
+			}
+		}
+	}
+
+	d, err := NewDomain(4)
+	c.Assert(err, qt.IsNil)
+	_, err = d.FFT(randPolynomial(c, 5))
+	c.Assert(err, qt.ErrorMatches,
+		"polynomial of 5 coefficients exceeds the domain size 4")
+	_, err = d.IFFT(randPolynomial(c, 3))
+	c.Assert(err, qt.ErrorMatches, "3 evaluations for a domain of size 4")
+}
+
+func TestFFTG1(t *testing.T) {
+	c := qt.New(t)
+
+	for n := 1; n <= 32; n <<= 1 {
+		d, err := NewDomain(n)
+		c.Assert(err, qt.IsNil)
+		for _, workers := range []int{0, 1, 3} {
+			d.Workers = workers
+			p := randPolynomial(c, n)
+			points := make([]*bls12381.PointG1, n)
+			for i := range p {
+				points[i] = g1.New()
+				g1.MulScalar(points[i], g1.One(), p[i])
+			}
+
+			// [p(ωⁱ)]₁
+			evals, err := d.FFTG1(points)
+			c.Assert(err, qt.IsNil)
+			for i, w := range d.Roots {
+				e := g1.New()
+				g1.MulScalar(e, g1.One(), naiveEvaluate(p, w))
+				c.Assert(g1.Equal(evals[i], e), qt.IsTrue)
+			}
+
+			back, err := d.IFFTG1(evals)
+			c.Assert(err, qt.IsNil)
+			for i := range points {
+				c.Assert(g1.Equal(back[i], points[i]), qt.IsTrue)
+			}
+		}
+	}
+
+	d, err := NewDomain(4)
+	c.Assert(err, qt.IsNil)
+	_, err = d.FFTG1(make([]*bls12381.PointG1, 5))
+	c.Assert(err, qt.ErrorMatches, "5 points exceed the domain size 4")
+	_, err = d.IFFTG1(make([]*bls12381.PointG1, 2))
+	c.Assert(err, qt.ErrorMatches, "2 points for a domain of size 4")
+}
+
+func TestEvaluateLagrange(t *testing.T) {
+	c := qt.New(t)
+
+	for n := 1; n <= 64; n <<= 1 {
+		d, err := NewDomain(n)
+		c.Assert(err, qt.IsNil)
+		p := randPolynomial(c, n)
+		evals, err := d.FFT(p)
+		c.Assert(err, qt.IsNil)
+
+		for k := 0; k < 4; k++ {
+			z := randFr(c)
+			y, err := d.EvaluateLagrange(evals, z)
+			c.Assert(err, qt.IsNil)
+			c.Assert(y.Equal(naiveEvaluate(p, z)), qt.IsTrue)
+		}
+		// in the domain
+		for i, w := range d.Roots {
+			y, err := d.EvaluateLagrange(evals, w)
+			c.Assert(err, qt.IsNil)
+			c.Assert(y.Equal(evals[i]), qt.IsTrue)
+		}
+	}
+
+	d, err := NewDomain(4)
+	c.Assert(err, qt.IsNil)
+	_, err = d.EvaluateLagrange(randPolynomial(c, 2), randFr(c))
+	c.Assert(err, qt.ErrorMatches, "2 evaluations for a domain of size 4")
+}
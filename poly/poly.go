@@ -0,0 +1,131 @@
+// Package poly implements dense polynomials over the scalar field Fr of
+// BLS12-381 and the power-of-two evaluation domains over the roots of unity
+// used to move between the coefficient and the evaluation forms, both for
+// field elements and for G1 points.
+package poly
+
+import (
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// Polynomial is a dense polynomial over Fr in coefficient form, where the
+// i-th element is the coefficient of Xⁱ
+type Polynomial []*bls12381.Fr
+
+// Degree returns the degree of the polynomial, ignoring the leading zero
+// coefficients, or -1 for the zero polynomial
+func (p Polynomial) Degree() int {
+	for i := len(p) - 1; i >= 0; i-- {
+		if !p[i].IsZero() {
+			return i
+		}
+	}
+	return -1
+}
+
+// Clone returns a deep copy of the polynomial
+func (p Polynomial) Clone() Polynomial {
+	q := make(Polynomial, len(p))
+	for i := range p {
+		q[i] = bls12381.NewFr().Set(p[i])
+	}
+	return q
+}
+
+// Evaluate returns p(z), through the Horner method
+func (p Polynomial) Evaluate(z *bls12381.Fr) *bls12381.Fr {
+	y := bls12381.NewFr()
+	for i := len(p) - 1; i >= 0; i-- {
+		y.Mul(y, z)
+		y.Add(y, p[i])
+	}
+	return y
+}
+
+// Add returns p(X) + q(X)
+func (p Polynomial) Add(q Polynomial) Polynomial {
+	if len(p) < len(q) {
+		p, q = q, p
+	}
+	r := p.Clone()
+	for i := range q {
+		r[i].Add(r[i], q[i])
+	}
+	return r
+}
+
+// Sub returns p(X) - q(X)
+func (p Polynomial) Sub(q Polynomial) Polynomial {
+	n := len(p)
+	if len(q) > n {
+		n = len(q)
+	}
+	r := make(Polynomial, n)
+	for i := range r {
+		r[i] = bls12381.NewFr()
+		if i < len(p) {
+			r[i].Set(p[i])
+		}
+		if i < len(q) {
+			r[i].Sub(r[i], q[i])
+		}
+	}
+	return r
+}
+
+// Mul returns p(X)⋅q(X), through the schoolbook product
+func (p Polynomial) Mul(q Polynomial) Polynomial {
+	if len(p) == 0 || len(q) == 0 {
+		return Polynomial{}
+	}
+	r := make(Polynomial, len(p)+len(q)-1)
+	for i := range r {
+		r[i] = bls12381.NewFr()
+	}
+	t := bls12381.NewFr()
+	for i := range p {
+		for j := range q {
+			t.Mul(p[i], q[j])
+			r[i+j].Add(r[i+j], t)
+		}
+	}
+	return r
+}
+
+// DivideByLinear returns the quotient q(X) = (p(X) - p(z)) / (X - z)
+// together with the remainder p(z), computed through synthetic division
+func (p Polynomial) DivideByLinear(z *bls12381.Fr) (Polynomial, *bls12381.Fr) {
+	if len(p) == 0 {
+		return Polynomial{}, bls12381.NewFr()
+	}
+	q := make(Polynomial, len(p)-1)
+	acc := bls12381.NewFr().Set(p[len(p)-1])
+	for i := len(p) - 2; i >= 0; i-- {
+		q[i] = bls12381.NewFr().Set(acc)
+		acc.Mul(acc, z)
+		acc.Add(acc, p[i])
+	}
+	return q, acc
+}
+
+// BatchInverse replaces each element by its inverse, using a single field
+// inversion (Montgomery's trick). All the elements must be non-zero.
+func BatchInverse(elems []*bls12381.Fr) {
+	n := len(elems)
+	if n == 0 {
+		return
+	}
+	prods := make([]*bls12381.Fr, n)
+	acc := bls12381.NewFr().One()
+	for i, e := range elems {
+		prods[i] = bls12381.NewFr().Set(acc)
+		acc.Mul(acc, e)
+	}
+	acc.Inverse(acc)
+	t := bls12381.NewFr()
+	for i := n - 1; i >= 0; i-- {
+		t.Mul(acc, prods[i])
+		acc.Mul(acc, elems[i])
+		elems[i].Set(t)
+	}
+}
@@ -0,0 +1,130 @@
+package poly
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+func randFr(c *qt.C) *bls12381.Fr {
+	e, err := bls12381.NewFr().Rand(rand.Reader)
+	c.Assert(err, qt.IsNil)
+	return e
+}
+
+func randPolynomial(c *qt.C, n int) Polynomial {
+	p := make(Polynomial, n)
+	for i := range p {
+		p[i] = randFr(c)
+	}
+	return p
+}
+
+func frFromInt(v int64) *bls12381.Fr {
+	return bls12381.NewFr().FromBytes(big.NewInt(v).Bytes())
+}
+
+// naiveEvaluate returns Σ pᵢ⋅zⁱ computing each power independently
+func naiveEvaluate(p Polynomial, z *bls12381.Fr) *bls12381.Fr {
+	y := bls12381.NewFr()
+	t := bls12381.NewFr()
+	for i := range p {
+		t.Exp(z, big.NewInt(int64(i)))
+		t.Mul(t, p[i])
+		y.Add(y, t)
+	}
+	return y
+}
+
+func TestEvaluate(t *testing.T) {
+	c := qt.New(t)
+
+	// p(X) = 1 + 2X + 3X² at 5 = 86
+	p := Polynomial{frFromInt(1), frFromInt(2), frFromInt(3)}
+	c.Assert(p.Evaluate(frFromInt(5)).Equal(frFromInt(86)), qt.IsTrue)
+	c.Assert(Polynomial{}.Evaluate(frFromInt(5)).IsZero(), qt.IsTrue)
+
+	for n := 0; n < 20; n++ {
+		p := randPolynomial(c, n)
+		z := randFr(c)
+		c.Assert(p.Evaluate(z).Equal(naiveEvaluate(p, z)), qt.IsTrue)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	c := qt.New(t)
+
+	for _, sizes := range [][2]int{{0, 0}, {0, 3}, {1, 1}, {5, 3}, {3, 7}, {8, 8}} {
+		p := randPolynomial(c, sizes[0])
+		q := randPolynomial(c, sizes[1])
+		z := randFr(c)
+		pz, qz := naiveEvaluate(p, z), naiveEvaluate(q, z)
+
+		e := bls12381.NewFr()
+		e.Add(pz, qz)
+		c.Assert(p.Add(q).Evaluate(z).Equal(e), qt.IsTrue)
+		e.Sub(pz, qz)
+		c.Assert(p.Sub(q).Evaluate(z).Equal(e), qt.IsTrue)
+		e.Mul(pz, qz)
+		c.Assert(p.Mul(q).Evaluate(z).Equal(e), qt.IsTrue)
+	}
+
+	// the inputs are not modified
+	p := Polynomial{frFromInt(1), frFromInt(2)}
+	_ = p.Add(Polynomial{frFromInt(3)})
+	c.Assert(p[0].Equal(frFromInt(1)), qt.IsTrue)
+}
+
+func TestDegree(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(Polynomial{}.Degree(), qt.Equals, -1)
+	c.Assert(Polynomial{bls12381.NewFr()}.Degree(), qt.Equals, -1)
+	c.Assert(Polynomial{frFromInt(1), frFromInt(2), bls12381.NewFr()}.Degree(),
+		qt.Equals, 1)
+}
+
+func TestDivideByLinear(t *testing.T) {
+	c := qt.New(t)
+
+	// X² - 1 = (X + 1)⋅(X - 1)
+	minusOne := bls12381.NewFr()
+	minusOne.Sub(minusOne, frFromInt(1))
+	q, y := Polynomial{minusOne, bls12381.NewFr(), frFromInt(1)}.DivideByLinear(frFromInt(1))
+	c.Assert(y.IsZero(), qt.IsTrue)
+	c.Assert(len(q), qt.Equals, 2)
+	c.Assert(q[0].Equal(frFromInt(1)), qt.IsTrue)
+	c.Assert(q[1].Equal(frFromInt(1)), qt.IsTrue)
+
+	for n := 0; n < 20; n++ {
+		p := randPolynomial(c, n)
+		z := randFr(c)
+		q, y := p.DivideByLinear(z)
+		c.Assert(y.Equal(naiveEvaluate(p, z)), qt.IsTrue)
+
+		// q(X)⋅(X - z) + y = p(X)
+		minusZ := bls12381.NewFr()
+		minusZ.Sub(minusZ, z)
+		r := q.Mul(Polynomial{minusZ, frFromInt(1)}).Add(Polynomial{y})
+		for i := 0; i < n; i++ {
+			c.Assert(r[i].Equal(p[i]), qt.IsTrue)
+		}
+	}
+}
+
+func TestBatchInverse(t *testing.T) {
+	c := qt.New(t)
+
+	BatchInverse(nil)
+	elems := randPolynomial(c, 17)
+	inverses := elems.Clone()
+	BatchInverse(inverses)
+	for i := range elems {
+		e := bls12381.NewFr()
+		e.Mul(elems[i], inverses[i])
+		c.Assert(e.Equal(frFromInt(1)), qt.IsTrue)
+	}
+}
@@ -1,10 +1,10 @@
 package kzgceremony
 
 import (
+	"crypto/rand"
 	"fmt"
-	"math/big"
-
-	"golang.org/x/crypto/blake2b"
+	"runtime"
+	"sync"
 
 	bls12381 "github.com/kilic/bls12-381"
 )
@@ -13,6 +13,10 @@ import (
 // randomness
 const MinRandomnessLen = 64
 
+// NumWorkers defines the number of goroutines used to compute the powers of
+// the new SRS. When it is not a positive number, runtime.NumCPU() is used
+var NumWorkers = 0
+
 var g1 *bls12381.G1
 var g2 *bls12381.G2
 
@@ -33,13 +37,17 @@ type State struct {
 // at the /contribute endpoint
 type BatchContribution struct {
 	Contributions []Contribution
+	// ECDSASignature is the optional EIP-712 signature of the PotPubKeys,
+	// done by the Ethereum participants (0x prefixed hex), see SignECDSA
+	ECDSASignature string
 }
 
 type Contribution struct {
-	NumG1Powers uint64
-	NumG2Powers uint64
-	PowersOfTau *SRS
-	PotPubKey   *bls12381.PointG2
+	NumG1Powers  uint64
+	NumG2Powers  uint64
+	PowersOfTau  *SRS
+	PotPubKey    *bls12381.PointG2
+	BLSSignature *bls12381.PointG1
 }
 
 type Transcript struct {
@@ -63,27 +71,49 @@ type SRS struct {
 	G2Powers []*bls12381.PointG2
 }
 
+// toxicWaste contains the secret τ of a contribution, which must be
+// zeroized once the contribution is computed
 type toxicWaste struct {
-	tau   *big.Int
-	TauG2 *bls12381.PointG2 // Proof.G2P
+	secret *secretScalars // backing buffer of tau
+	tau    *bls12381.Fr
+	TauG2  *bls12381.PointG2 // Proof.G2P
 }
 
-// Proof contains g₂ᵖ and g₂^τ', used by the verifier
+// zeroize overwrites τ with zeroes and releases its buffer
+func (t *toxicWaste) zeroize() {
+	t.secret.release()
+	t.tau = nil
+}
+
+// Proof contains g₂ᵖ and g₂^τ', used by the verifier, together with the BLS
+// signature of the participant identity
 type Proof struct {
 	G2P    *bls12381.PointG2 // g₂ᵖ
 	G1PTau *bls12381.PointG1 // g₂^τ' = g₂^{p ⋅ τ}
+	// BLSSignature is the signature of the participant identity using p as
+	// secret key, nil when no identity is given
+	BLSSignature *bls12381.PointG1
 }
 
 // Contribute takes the last State and computes a new State using the defined
-// randomness
+// randomness, without participant identity
 func (cs *State) Contribute(randomness []byte) (*State, error) {
+	return cs.ContributeWithIdentity(randomness, "")
+}
+
+// ContributeWithIdentity takes the last State and computes a new State using
+// the defined randomness, appending the given participant identity (eg.
+// "git|1234|username" or "eth|0x...") and its BLS signature to the witness.
+// An empty identity appends the empty placeholders instead.
+func (cs *State) ContributeWithIdentity(randomness []byte, identity string) (*State, error) {
 	ns := State{}
 	ns.Transcripts = make([]Transcript, len(cs.Transcripts))
 	for i := 0; i < len(cs.Transcripts); i++ {
 		ns.Transcripts[i].NumG1Powers = cs.Transcripts[i].NumG1Powers
 		ns.Transcripts[i].NumG2Powers = cs.Transcripts[i].NumG2Powers
 
-		newSRS, proof, err := Contribute(cs.Transcripts[i].PowersOfTau, i, randomness)
+		newSRS, proof, err := ContributeWithIdentity(cs.Transcripts[i].PowersOfTau,
+			i, randomness, identity)
 		if err != nil {
 			return nil, err
 		}
@@ -94,30 +124,44 @@ func (cs *State) Contribute(randomness []byte) (*State, error) {
 			append(cs.Transcripts[i].Witness.RunningProducts, proof.G1PTau)
 		ns.Transcripts[i].Witness.PotPubKeys =
 			append(cs.Transcripts[i].Witness.PotPubKeys, proof.G2P)
-		ns.Transcripts[i].Witness.BLSSignatures = cs.Transcripts[i].Witness.BLSSignatures
+		ns.Transcripts[i].Witness.BLSSignatures =
+			append(cs.Transcripts[i].Witness.BLSSignatures, proof.BLSSignature)
 	}
-	ns.ParticipantIDs = cs.ParticipantIDs // TODO add github id (id_token.sub)
-	ns.ParticipantECDSASignatures = cs.ParticipantECDSASignatures
+	ns.ParticipantIDs = append(cs.ParticipantIDs, identity)
+	ns.ParticipantECDSASignatures = append(cs.ParticipantECDSASignatures, "")
 
 	return &ns, nil
 }
 
 // Contribute takes the last BatchContribution and computes a new
-// BatchContribution using the defined randomness
+// BatchContribution using the defined randomness, without participant
+// identity
 func (pb *BatchContribution) Contribute(randomness []byte) (*BatchContribution, error) {
+	return pb.ContributeWithIdentity(randomness, "")
+}
+
+// ContributeWithIdentity takes the last BatchContribution and computes a new
+// BatchContribution using the defined randomness, where each Contribution
+// contains the BLS signature of the given participant identity (eg.
+// "git|1234|username" or "eth|0x..."). An empty identity leaves the
+// signatures empty.
+func (pb *BatchContribution) ContributeWithIdentity(randomness []byte,
+	identity string) (*BatchContribution, error) {
 	nb := BatchContribution{}
 	nb.Contributions = make([]Contribution, len(pb.Contributions))
 	for i := 0; i < len(pb.Contributions); i++ {
 		nb.Contributions[i].NumG1Powers = pb.Contributions[i].NumG1Powers
 		nb.Contributions[i].NumG2Powers = pb.Contributions[i].NumG2Powers
 
-		newSRS, proof, err := Contribute(pb.Contributions[i].PowersOfTau, i, randomness)
+		newSRS, proof, err := ContributeWithIdentity(pb.Contributions[i].PowersOfTau,
+			i, randomness, identity)
 		if err != nil {
 			return nil, err
 		}
 		nb.Contributions[i].PowersOfTau = newSRS
 
 		nb.Contributions[i].PotPubKey = proof.G2P
+		nb.Contributions[i].BLSSignature = proof.BLSSignature
 	}
 
 	return &nb, nil
@@ -137,64 +181,187 @@ func newEmptySRS(nG1, nG2 int) *SRS {
 	return &SRS{g1s, g2s}
 }
 
-func tau(round int, randomness []byte) *toxicWaste {
-	val := blake2b.Sum256(append(randomness, byte(round)))
-	tau := new(big.Int).Mod(
-		new(big.Int).SetBytes(val[:]),
-		g2.Q())
-	tau_Fr := bls12381.NewFr().FromBytes(tau.Bytes())
+// NewEmptyState creates a State with a Transcript for each of the given
+// sizes, where each Transcript contains an empty SRS and the Witness only
+// contains the generators (the state before any contribution), which is the
+// genesis of a new ceremony
+func NewEmptyState(nG1s, nG2s []int) *State {
+	s := &State{}
+	s.Transcripts = make([]Transcript, len(nG1s))
+	for i := 0; i < len(nG1s); i++ {
+		s.Transcripts[i].NumG1Powers = uint64(nG1s[i])
+		s.Transcripts[i].NumG2Powers = uint64(nG2s[i])
+		s.Transcripts[i].PowersOfTau = newEmptySRS(nG1s[i], nG2s[i])
+		s.Transcripts[i].Witness = &Witness{
+			RunningProducts: []*bls12381.PointG1{g1.One()},
+			PotPubKeys:      []*bls12381.PointG2{g2.One()},
+			BLSSignatures:   []*bls12381.PointG1{nil},
+		}
+	}
+	s.ParticipantIDs = []string{""}
+	s.ParticipantECDSASignatures = []string{""}
+	return s
+}
+
+// tau derives the toxic waste of the sub-ceremony with the given index
+// (round) and sizes from the randomness, see deriveTau
+func tau(round, nG1, nG2 int, randomness []byte) (*toxicWaste, error) {
+	val, err := deriveTau(randomness, round, nG1, nG2)
+	if err != nil {
+		return nil, err
+	}
+	secret := newSecretScalars(1)
+	tau := &secret.frs[0]
+	frFromWideBytes(tau, (*[64]byte)(val))
+	zero(val)
+	if tau.IsZero() {
+		secret.release()
+		return nil, fmt.Errorf("derived tau is zero")
+	}
 	TauG2 := g2.New()
-	g2.MulScalar(TauG2, g2.One(), tau_Fr)
+	g2.MulScalar(TauG2, g2.One(), tau)
 
-	return &toxicWaste{tau, TauG2}
+	return &toxicWaste{secret: secret, tau: tau, TauG2: TauG2}, nil
 }
 
 func computeContribution(t *toxicWaste, prevSRS *SRS) *SRS {
 	srs := newEmptySRS(len(prevSRS.G1Powers), len(prevSRS.G2Powers))
-	Q := g1.Q() // Q = |G1| == |G2|
 
-	// fmt.Println("Computing [τ'⁰]₁, [τ'¹]₁, [τ'²]₁, ..., [τ'ⁿ⁻¹]₁, for n =", len(prevSRS.G1s))
-	for i := 0; i < len(prevSRS.G1Powers); i++ {
-		tau_i := new(big.Int).Exp(t.tau, big.NewInt(int64(i)), Q)
-		tau_i_Fr := bls12381.NewFr().FromBytes(tau_i.Bytes())
-		g1.MulScalar(srs.G1Powers[i], prevSRS.G1Powers[i], tau_i_Fr)
+	// compute τ⁰, τ¹, τ², ..., τⁿ⁻¹ as a running product, where
+	// n = max(len(G1Powers), len(G2Powers))
+	n := len(prevSRS.G1Powers)
+	if len(prevSRS.G2Powers) > n {
+		n = len(prevSRS.G2Powers)
 	}
+	secret := newSecretScalars(n)
+	defer secret.release()
+	taus := secret.frs
+	powersOfTau(taus, t.tau)
+
+	// fmt.Println("Computing [τ'⁰]₁, [τ'¹]₁, [τ'²]₁, ..., [τ'ⁿ⁻¹]₁, for n =", len(prevSRS.G1s))
+	parallelize(len(prevSRS.G1Powers), func(start, end int) {
+		// each worker uses its own G1 instance, as it contains temporary
+		// values used during the computation
+		g1 := bls12381.NewG1()
+		for i := start; i < end; i++ {
+			g1.MulScalar(srs.G1Powers[i], prevSRS.G1Powers[i], &taus[i])
+		}
+	})
 	// fmt.Println("Computing [τ'⁰]₂, [τ'¹]₂, [τ'²]₂, ..., [τ'ⁿ⁻¹]₂, for n =", len(prevSRS.G2s))
-	for i := 0; i < len(prevSRS.G2Powers); i++ {
-		tau_i := new(big.Int).Exp(t.tau, big.NewInt(int64(i)), Q)
-		tau_i_Fr := bls12381.NewFr().FromBytes(tau_i.Bytes())
-		g2.MulScalar(srs.G2Powers[i], prevSRS.G2Powers[i], tau_i_Fr)
-	}
+	parallelize(len(prevSRS.G2Powers), func(start, end int) {
+		g2 := bls12381.NewG2()
+		for i := start; i < end; i++ {
+			g2.MulScalar(srs.G2Powers[i], prevSRS.G2Powers[i], &taus[i])
+		}
+	})
 
 	return srs
 }
 
+// powersOfTau sets taus to the scalars τ⁰, τ¹, τ², ..., τⁿ⁻¹, where
+// n = len(taus)
+func powersOfTau(taus []bls12381.Fr, tau *bls12381.Fr) {
+	if len(taus) == 0 {
+		return
+	}
+	taus[0].One()
+	for i := 1; i < len(taus); i++ {
+		taus[i].Mul(&taus[i-1], tau)
+	}
+}
+
+// parallelize splits the range [0, n) into chunks and calls f for each chunk
+// from a pool of NumWorkers goroutines, waiting until all of them finish
+func parallelize(n int, f func(start, end int)) {
+	parallelizeWith(NumWorkers, n, f)
+}
+
+// parallelizeWith acts as parallelize using the given number of workers,
+// where a non positive number means runtime.NumCPU()
+func parallelizeWith(workers, n int, f func(start, end int)) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		f(0, n)
+		return
+	}
+
+	chunkSize := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += chunkSize {
+		end := start + chunkSize
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			f(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
+
 func genProof(toxicWaste *toxicWaste, prevSRS, newSRS *SRS) *Proof {
 	G1_p := g1.New()
-	tau_Fr := bls12381.NewFr().FromBytes(toxicWaste.tau.Bytes())
-	g1.MulScalar(G1_p, prevSRS.G1Powers[1], tau_Fr) // g_1^{tau'} = g_1^{p * tau}, where p=toxicWaste.tau
+	g1.MulScalar(G1_p, prevSRS.G1Powers[1], toxicWaste.tau) // g_1^{tau'} = g_1^{p * tau}, where p=toxicWaste.tau
 
-	return &Proof{toxicWaste.TauG2, G1_p}
+	return &Proof{G2P: toxicWaste.TauG2, G1PTau: G1_p}
 }
 
 // Contribute takes as input the previous SRS and a random
 // byte slice, and returns the new SRS together with the Proof
 func Contribute(prevSRS *SRS, round int, randomness []byte) (*SRS, *Proof, error) {
+	return ContributeWithIdentity(prevSRS, round, randomness, "")
+}
+
+// ContributeWithIdentity acts as Contribute, additionally signing the given
+// participant identity with the toxic waste as BLS secret key, which is
+// returned in Proof.BLSSignature. An empty identity is not signed.
+//
+// The toxic waste is zeroized before returning, both on success and on error.
+func ContributeWithIdentity(prevSRS *SRS, round int, randomness []byte,
+	identity string) (*SRS, *Proof, error) {
 	if len(randomness) < MinRandomnessLen {
 		return nil, nil, fmt.Errorf("err: randomness length < %d",
 			MinRandomnessLen)
 	}
+	if len(prevSRS.G1Powers) < 2 || len(prevSRS.G2Powers) < 2 {
+		return nil, nil, fmt.Errorf("err: SRS needs at least 2 G1 and 2 G2 powers")
+	}
 	// set tau from randomness
-	tw := tau(round, randomness)
+	tw, err := tau(round, len(prevSRS.G1Powers), len(prevSRS.G2Powers), randomness)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tw.zeroize()
 
 	newSRS := computeContribution(tw, prevSRS)
 
 	proof := genProof(tw, prevSRS, newSRS)
 
+	if identity != "" {
+		sig, err := signIdentity(tw, identity)
+		if err != nil {
+			return nil, nil, err
+		}
+		proof.BLSSignature = sig
+	}
+
 	return newSRS, proof, nil
 }
 
 func checkG1PointCorrectness(p *bls12381.PointG1) error {
+	return checkG1Point(g1, p)
+}
+
+// checkG1Point acts as checkG1PointCorrectness using the given G1 instance,
+// which allows checking points concurrently
+func checkG1Point(g1 *bls12381.G1, p *bls12381.PointG1) error {
 	// i) non-empty
 	if p == nil {
 		return fmt.Errorf("empty point value")
@@ -203,7 +370,9 @@ func checkG1PointCorrectness(p *bls12381.PointG1) error {
 	if g1.IsZero(p) {
 		return fmt.Errorf("point can not be zero")
 	}
-	// iii) in the correct prime order of subgroups
+	// iii) in the correct prime order of subgroups. InCorrectSubgroup uses
+	// the endomorphism based check from "Faster Subgroup Checks for
+	// BLS12-381" (S. Bowe), instead of multiplying the point by the order
 	if !g1.IsOnCurve(p) {
 		return fmt.Errorf("point not on curve")
 	}
@@ -214,6 +383,11 @@ func checkG1PointCorrectness(p *bls12381.PointG1) error {
 }
 
 func checkG2PointCorrectness(p *bls12381.PointG2) error {
+	return checkG2Point(g2, p)
+}
+
+// checkG2Point acts as checkG2PointCorrectness using the given G2 instance
+func checkG2Point(g2 *bls12381.G2, p *bls12381.PointG2) error {
 	// i) non-empty
 	if p == nil {
 		return fmt.Errorf("empty point value")
@@ -222,7 +396,8 @@ func checkG2PointCorrectness(p *bls12381.PointG2) error {
 	if g2.IsZero(p) {
 		return fmt.Errorf("point can not be zero")
 	}
-	// iii) in the correct prime order of subgroups
+	// iii) in the correct prime order of subgroups, using the ψ
+	// endomorphism based check
 	if !g2.IsOnCurve(p) {
 		return fmt.Errorf("point not on curve")
 	}
@@ -232,27 +407,49 @@ func checkG2PointCorrectness(p *bls12381.PointG2) error {
 	return nil
 }
 
+// VerificationMode defines how the powers of tau structure of an SRS is
+// checked by the verifiers
+type VerificationMode int
+
+const (
+	// VerifyStrict checks every pair of adjacent powers with its own
+	// pairings, which requires O(n) pairings
+	VerifyStrict VerificationMode = iota
+	// VerifyBatch checks all the powers at once through a random linear
+	// combination, which requires a constant number of pairings
+	VerifyBatch
+)
+
 // VerifyNewSRSFromPrevSRS checks the correct computation of the new SRS
 // respectively from the previous SRS. These are the checks that the Sequencer
 // would do.
 func VerifyNewSRSFromPrevSRS(prevSRS, newSRS *SRS, proof *Proof) bool {
+	return VerifyNewSRSFromPrevSRSWithMode(prevSRS, newSRS, proof, VerifyStrict)
+}
+
+// VerifyNewSRSFromPrevSRSWithMode acts as VerifyNewSRSFromPrevSRS, checking
+// the powers of tau structure of the new SRS with the given VerificationMode
+func VerifyNewSRSFromPrevSRSWithMode(prevSRS, newSRS *SRS, proof *Proof,
+	mode VerificationMode) bool {
+	return CheckNewSRSFromPrevSRS(prevSRS, newSRS, proof, mode) == nil
+}
+
+// CheckNewSRSFromPrevSRS does the same checks than
+// VerifyNewSRSFromPrevSRSWithMode, returning a *VerificationError describing
+// the first check that failed, or nil if the new SRS is valid
+func CheckNewSRSFromPrevSRS(prevSRS, newSRS *SRS, proof *Proof,
+	mode VerificationMode) error {
 	pairing := bls12381.NewEngine()
 
 	// 1. check that elements of the newSRS are valid points
-	for i := 0; i < len(newSRS.G1Powers); i++ {
-		if err := checkG1PointCorrectness(newSRS.G1Powers[i]); err != nil {
-			return false
-		}
-	}
-	for i := 0; i < len(newSRS.G2Powers); i++ {
-		if err := checkG2PointCorrectness(newSRS.G2Powers[i]); err != nil {
-			return false
-		}
+	if err := checkSRSPoints(newSRS); err != nil {
+		return err
 	}
 
 	// 2. check proof.G1PTau == newSRS.G1Powers[1]
 	if !g1.Equal(proof.G1PTau, newSRS.G1Powers[1]) {
-		return false
+		return &VerificationError{Check: RunningProductCheck, Transcript: -1,
+			Element: "G1Powers", Index: 1, Point: g1PointToString(newSRS.G1Powers[1])}
 	}
 
 	// 3. check newSRS.G1s[1] (g₁^τ'), is correctly related to prevSRS.G1s[1] (g₁^τ)
@@ -260,82 +457,340 @@ func VerifyNewSRSFromPrevSRS(prevSRS, newSRS *SRS, proof *Proof) bool {
 	eL := pairing.AddPair(prevSRS.G1Powers[1], proof.G2P).Result()
 	eR := pairing.AddPair(newSRS.G1Powers[1], g2.One()).Result()
 	if !eL.Equal(eR) {
-		return false
+		return &VerificationError{Check: PubKeyCheck, Transcript: -1,
+			Element: "G2P", Index: -1, Point: g2PointToString(proof.G2P)}
 	}
 
 	// 4. check newSRS following the powers of tau structure
-	for i := 0; i < len(newSRS.G1Powers)-1; i++ {
-		// i) e([τ'ⁱ]₁, [τ']₂) == e([τ'ⁱ⁺¹]₁, [1]₂), for i ∈ [1, n−1]
-		eL := pairing.AddPair(newSRS.G1Powers[i], newSRS.G2Powers[1]).Result()
-		eR := pairing.AddPair(newSRS.G1Powers[i+1], g2.One()).Result()
-		if !eL.Equal(eR) {
-			return false
+	return verifySRSStructure(newSRS, mode)
+}
+
+// VerifyBatchContribution checks that the new BatchContribution is correctly
+// computed from the previous one (the batch handed out by the Sequencer):
+// for each Contribution, that the sizes match, that all the points are
+// valid, that the new PowersOfTau follow the powers of tau structure, and
+// that the PotPubKey relates the previous and the new [τ]₁:
+//
+//	e(prev.G1Powers[1], PotPubKey) == e(next.G1Powers[1], [1]₂)
+//
+// The signatures are not checked, as they depend on the participant
+// identity, see CheckBatchContributionWithIdentity.
+func VerifyBatchContribution(prev, next *BatchContribution) bool {
+	return VerifyBatchContributionWithMode(prev, next, VerifyStrict)
+}
+
+// VerifyBatchContributionWithMode acts as VerifyBatchContribution, checking
+// the powers of tau structure with the given VerificationMode
+func VerifyBatchContributionWithMode(prev, next *BatchContribution,
+	mode VerificationMode) bool {
+	return CheckBatchContribution(prev, next, mode) == nil
+}
+
+// CheckBatchContribution does the same checks than
+// VerifyBatchContributionWithMode, returning a *VerificationError describing
+// the first check that failed, where Transcript is the index of the failing
+// Contribution, or nil if the new BatchContribution is valid
+func CheckBatchContribution(prev, next *BatchContribution,
+	mode VerificationMode) error {
+	if len(next.Contributions) != len(prev.Contributions) {
+		return &VerificationError{Check: SizeCheck, Transcript: -1,
+			Element: "Contributions", Index: -1,
+			Err: fmt.Errorf("expected %d contributions, got %d",
+				len(prev.Contributions), len(next.Contributions))}
+	}
+	for i := range next.Contributions {
+		if err := checkContribution(&prev.Contributions[i],
+			&next.Contributions[i], mode); err != nil {
+			return withTranscript(err, i)
 		}
 	}
+	return nil
+}
 
-	for i := 0; i < len(newSRS.G2Powers)-1; i++ {
-		// ii) e([τ']₁, [τ'ʲ]₂) == e([1]₁, [τ'ʲ⁺¹]₂), for j ∈ [1, m−1]
-		eL := pairing.AddPair(newSRS.G1Powers[1], newSRS.G2Powers[i]).Result()
-		eR := pairing.AddPair(g1.One(), newSRS.G2Powers[i+1]).Result()
-		if !eL.Equal(eR) {
-			return false
-		}
+// CheckBatchContributionWithIdentity does the checks of
+// CheckBatchContribution and the ones of the signatures of the given
+// participant identity (eg. "git|1234|username" or "eth|0x..."): the BLS
+// signatures of the identity, which must be present in all the Contributions
+// or in none of them, and the ECDSA signature of the PotPubKeys, which is
+// only accepted from Ethereum participants. These are the checks that the
+// Sequencer does (see State.Apply), and the ones that a participant can do
+// before uploading its contribution.
+func CheckBatchContributionWithIdentity(prev, next *BatchContribution,
+	identity string, mode VerificationMode) error {
+	if err := CheckBatchContribution(prev, next, mode); err != nil {
+		return err
 	}
+	return next.checkSignatures(identity)
+}
 
-	return true
+// checkContribution checks that the new Contribution has the same sizes than
+// the previous one, and that its PowersOfTau are computed from the previous
+// ones with its PotPubKey
+func checkContribution(prev, next *Contribution, mode VerificationMode) error {
+	nG1, nG2 := prev.NumG1Powers, prev.NumG2Powers
+	if prev.PowersOfTau == nil ||
+		uint64(len(prev.PowersOfTau.G1Powers)) != nG1 ||
+		uint64(len(prev.PowersOfTau.G2Powers)) != nG2 || nG1 < 2 || nG2 < 2 {
+		return &VerificationError{Check: SizeCheck, Transcript: -1,
+			Element: "PowersOfTau", Index: -1,
+			Err: fmt.Errorf("previous powers of tau do not match %d G1 and %d"+
+				" G2 powers, or there are less than 2", nG1, nG2)}
+	}
+	if next.NumG1Powers != nG1 || next.NumG2Powers != nG2 {
+		return &VerificationError{Check: SizeCheck, Transcript: -1,
+			Element: "PowersOfTau", Index: -1,
+			Err: fmt.Errorf("expected %d G1 and %d G2 powers, got %d and %d",
+				nG1, nG2, next.NumG1Powers, next.NumG2Powers)}
+	}
+	if next.PowersOfTau == nil ||
+		uint64(len(next.PowersOfTau.G1Powers)) != nG1 ||
+		uint64(len(next.PowersOfTau.G2Powers)) != nG2 {
+		return &VerificationError{Check: SizeCheck, Transcript: -1,
+			Element: "PowersOfTau", Index: -1,
+			Err: fmt.Errorf("powers of tau do not match %d G1 and %d G2 powers",
+				nG1, nG2)}
+	}
+	if err := checkG2PointCorrectness(next.PotPubKey); err != nil {
+		return &VerificationError{Check: PointValidityCheck, Transcript: -1,
+			Element: "PotPubKey", Index: -1,
+			Point: g2PointToString(next.PotPubKey), Err: err}
+	}
+	proof := &Proof{G2P: next.PotPubKey, G1PTau: next.PowersOfTau.G1Powers[1]}
+	err := CheckNewSRSFromPrevSRS(prev.PowersOfTau, next.PowersOfTau, proof, mode)
+	if vErr, ok := err.(*VerificationError); ok && vErr.Element == "G2P" {
+		// the Proof.G2P is the PotPubKey of the Contribution
+		vErr.Element = "PotPubKey"
+	}
+	return err
+}
+
+// CheckSRS checks a standalone SRS, such as one imported from another
+// ceremony: the correctness of its points, that its first powers are the
+// generators, and that it follows the powers of tau structure. It returns a
+// *VerificationError describing the first check that failed, or nil if the
+// SRS is valid.
+func CheckSRS(srs *SRS, mode VerificationMode) error {
+	if err := checkSRSPoints(srs); err != nil {
+		return err
+	}
+	if len(srs.G1Powers) > 0 && !g1.Equal(srs.G1Powers[0], g1.One()) {
+		return &VerificationError{Check: GenesisCheck, Transcript: -1,
+			Element: "G1Powers", Index: 0, Point: g1PointToString(srs.G1Powers[0])}
+	}
+	if len(srs.G2Powers) > 0 && !g2.Equal(srs.G2Powers[0], g2.One()) {
+		return &VerificationError{Check: GenesisCheck, Transcript: -1,
+			Element: "G2Powers", Index: 0, Point: g2PointToString(srs.G2Powers[0])}
+	}
+	return verifySRSStructure(srs, mode)
 }
 
 // VerifyState acts similarly to VerifyNewSRSFromPrevSRS, but verifying the
 // given State (which can be obtained from the Sequencer)
 func VerifyState(s *State) bool {
+	return VerifyStateWithMode(s, VerifyStrict)
+}
+
+// VerifyStateWithMode acts as VerifyState, checking the powers of tau
+// structure of each Transcript with the given VerificationMode
+func VerifyStateWithMode(s *State, mode VerificationMode) bool {
+	return CheckState(s, mode) == nil
+}
+
+// CheckState does the same checks than VerifyStateWithMode, returning a
+// *VerificationError describing the first check that failed, or nil if the
+// State is valid
+func CheckState(s *State, mode VerificationMode) error {
 	pairing := bls12381.NewEngine()
 
-	for _, t := range s.Transcripts {
+	for ti, t := range s.Transcripts {
 		// 1. check that elements of the SRS are valid points
-		for i := 0; i < len(t.PowersOfTau.G1Powers); i++ {
-			if err := checkG1PointCorrectness(t.PowersOfTau.G1Powers[i]); err != nil {
-				return false
-			}
+		if err := checkSRSPoints(t.PowersOfTau); err != nil {
+			return withTranscript(err, ti)
 		}
-		for i := 0; i < len(t.PowersOfTau.G2Powers); i++ {
-			if err := checkG2PointCorrectness(t.PowersOfTau.G2Powers[i]); err != nil {
-				return false
-			}
+
+		nRP := len(t.Witness.RunningProducts)
+		nPK := len(t.Witness.PotPubKeys)
+		if nRP < 2 || nPK < 1 {
+			return &VerificationError{Check: RunningProductCheck, Transcript: ti,
+				Element: "RunningProducts", Index: -1,
+				Err: fmt.Errorf("witness does not contain any contribution")}
 		}
 
 		// 2. check t.Witness.RunningProducts[last] == t.PowersOfTau.G1Powers[1]
-		if !g1.Equal(t.Witness.RunningProducts[len(t.Witness.RunningProducts)-1],
-			t.PowersOfTau.G1Powers[1]) {
-			return false
+		if !g1.Equal(t.Witness.RunningProducts[nRP-1], t.PowersOfTau.G1Powers[1]) {
+			return &VerificationError{Check: RunningProductCheck, Transcript: ti,
+				Element: "RunningProducts", Index: nRP - 1,
+				Point: g1PointToString(t.Witness.RunningProducts[nRP-1])}
 		}
 
 		// 3. check newSRS.G1s[1] (g₁^τ'), is correctly related to prevSRS.G1s[1] (g₁^τ)
 		//   e([τ]₁, [p]₂) == e([τ']₁, [1]₂)
-		eL := pairing.AddPair(t.Witness.RunningProducts[len(t.Witness.RunningProducts)-2], t.Witness.PotPubKeys[len(t.Witness.PotPubKeys)-1]).Result()
-		eR := pairing.AddPair(t.Witness.RunningProducts[len(t.Witness.RunningProducts)-1], g2.One()).Result()
+		eL := pairing.AddPair(t.Witness.RunningProducts[nRP-2], t.Witness.PotPubKeys[nPK-1]).Result()
+		eR := pairing.AddPair(t.Witness.RunningProducts[nRP-1], g2.One()).Result()
 		if !eL.Equal(eR) {
-			return false
+			return &VerificationError{Check: PubKeyCheck, Transcript: ti,
+				Element: "PotPubKeys", Index: nPK - 1,
+				Point: g2PointToString(t.Witness.PotPubKeys[nPK-1])}
 		}
 
 		// 4. check newSRS following the powers of tau structure
-		for i := 0; i < len(t.PowersOfTau.G1Powers)-1; i++ {
-			// i) e([τ'ⁱ]₁, [τ']₂) == e([τ'ⁱ⁺¹]₁, [1]₂), for i ∈ [1, n−1]
-			eL := pairing.AddPair(t.PowersOfTau.G1Powers[i], t.PowersOfTau.G2Powers[1]).Result()
-			eR := pairing.AddPair(t.PowersOfTau.G1Powers[i+1], g2.One()).Result()
-			if !eL.Equal(eR) {
-				return false
-			}
+		if err := verifySRSStructure(t.PowersOfTau, mode); err != nil {
+			return withTranscript(err, ti)
 		}
+	}
 
-		for i := 0; i < len(t.PowersOfTau.G2Powers)-1; i++ {
-			// ii) e([τ']₁, [τ'ʲ]₂) == e([1]₁, [τ'ʲ⁺¹]₂), for j ∈ [1, m−1]
-			eL := pairing.AddPair(t.PowersOfTau.G1Powers[1], t.PowersOfTau.G2Powers[i]).Result()
-			eR := pairing.AddPair(g1.One(), t.PowersOfTau.G2Powers[i+1]).Result()
-			if !eL.Equal(eR) {
-				return false
-			}
+	return nil
+}
+
+// withTranscript sets the given Transcript index to err when it is a
+// *VerificationError
+func withTranscript(err error, transcript int) error {
+	if vErr, ok := err.(*VerificationError); ok {
+		vErr.Transcript = transcript
+	}
+	return err
+}
+
+// checkSRSPoints checks the correctness of all the points of the SRS
+func checkSRSPoints(srs *SRS) error {
+	for i := 0; i < len(srs.G1Powers); i++ {
+		if err := checkG1PointCorrectness(srs.G1Powers[i]); err != nil {
+			return &VerificationError{Check: PointValidityCheck, Transcript: -1,
+				Element: "G1Powers", Index: i,
+				Point: g1PointToString(srs.G1Powers[i]), Err: err}
+		}
+	}
+	for i := 0; i < len(srs.G2Powers); i++ {
+		if err := checkG2PointCorrectness(srs.G2Powers[i]); err != nil {
+			return &VerificationError{Check: PointValidityCheck, Transcript: -1,
+				Element: "G2Powers", Index: i,
+				Point: g2PointToString(srs.G2Powers[i]), Err: err}
 		}
 	}
+	return nil
+}
+
+// verifySRSStructure checks that the given SRS follows the powers of tau
+// structure, using the given VerificationMode
+func verifySRSStructure(srs *SRS, mode VerificationMode) error {
+	if len(srs.G1Powers) < 2 || len(srs.G2Powers) < 2 {
+		return &VerificationError{Check: G1StructureCheck, Transcript: -1,
+			Index: -1, Err: fmt.Errorf("SRS needs at least 2 powers in G1 & G2")}
+	}
+	if mode == VerifyBatch {
+		return verifySRSStructureBatch(srs)
+	}
+	if err := verifyG1StructureStrict(srs); err != nil {
+		return err
+	}
+	return verifyG2StructureStrict(srs)
+}
+
+func verifyG1StructureStrict(srs *SRS) error {
+	pairing := bls12381.NewEngine()
 
-	return true
+	for i := 0; i < len(srs.G1Powers)-1; i++ {
+		// i) e([τ'ⁱ]₁, [τ']₂) == e([τ'ⁱ⁺¹]₁, [1]₂), for i ∈ [1, n−1]
+		eL := pairing.AddPair(srs.G1Powers[i], srs.G2Powers[1]).Result()
+		eR := pairing.AddPair(srs.G1Powers[i+1], g2.One()).Result()
+		if !eL.Equal(eR) {
+			return &VerificationError{Check: G1StructureCheck, Transcript: -1,
+				Element: "G1Powers", Index: i + 1,
+				Point: g1PointToString(srs.G1Powers[i+1])}
+		}
+	}
+	return nil
+}
+
+func verifyG2StructureStrict(srs *SRS) error {
+	pairing := bls12381.NewEngine()
+
+	for i := 0; i < len(srs.G2Powers)-1; i++ {
+		// ii) e([τ']₁, [τ'ʲ]₂) == e([1]₁, [τ'ʲ⁺¹]₂), for j ∈ [1, m−1]
+		eL := pairing.AddPair(srs.G1Powers[1], srs.G2Powers[i]).Result()
+		eR := pairing.AddPair(g1.One(), srs.G2Powers[i+1]).Result()
+		if !eL.Equal(eR) {
+			return &VerificationError{Check: G2StructureCheck, Transcript: -1,
+				Element: "G2Powers", Index: i + 1,
+				Point: g2PointToString(srs.G2Powers[i+1])}
+		}
+	}
+	return nil
+}
+
+// verifySRSStructureBatch checks the powers of tau structure by taking random
+// rᵢ, sⱼ and checking
+//
+//	i)  e(∑ rᵢ⋅[τ'ⁱ]₁, [τ']₂) == e(∑ rᵢ⋅[τ'ⁱ⁺¹]₁, [1]₂)
+//	ii) e([τ']₁, ∑ sⱼ⋅[τ'ʲ]₂) == e([1]₁, ∑ sⱼ⋅[τ'ʲ⁺¹]₂)
+//
+// which hold for all the rᵢ, sⱼ only if each pair of adjacent powers is
+// correctly related, and otherwise fail with overwhelming probability. When
+// one of the checks fails, the strict check is used to locate the failing
+// power.
+func verifySRSStructureBatch(srs *SRS) error {
+	pairing := bls12381.NewEngine()
+
+	n := len(srs.G1Powers) - 1
+	r, err := randomScalars(n)
+	if err != nil {
+		return err
+	}
+	lhs, err := MSMG1(srs.G1Powers[:n], r)
+	if err != nil {
+		return err
+	}
+	rhs, err := MSMG1(srs.G1Powers[1:], r)
+	if err != nil {
+		return err
+	}
+	// e(∑ rᵢ⋅[τ'ⁱ]₁, [τ']₂) ⋅ e(-∑ rᵢ⋅[τ'ⁱ⁺¹]₁, [1]₂) == 1
+	if !pairing.AddPair(lhs, srs.G2Powers[1]).
+		AddPairInv(rhs, g2.One()).Check() {
+		if err := verifyG1StructureStrict(srs); err != nil {
+			return err
+		}
+		return &VerificationError{Check: G1StructureCheck, Transcript: -1,
+			Element: "G1Powers", Index: -1}
+	}
+	pairing.Reset()
+
+	m := len(srs.G2Powers) - 1
+	s, err := randomScalars(m)
+	if err != nil {
+		return err
+	}
+	lhs2, err := MSMG2(srs.G2Powers[:m], s)
+	if err != nil {
+		return err
+	}
+	rhs2, err := MSMG2(srs.G2Powers[1:], s)
+	if err != nil {
+		return err
+	}
+	// e([τ']₁, ∑ sⱼ⋅[τ'ʲ]₂) ⋅ e(-[1]₁, ∑ sⱼ⋅[τ'ʲ⁺¹]₂) == 1
+	if !pairing.AddPair(srs.G1Powers[1], lhs2).
+		AddPairInv(g1.One(), rhs2).Check() {
+		if err := verifyG2StructureStrict(srs); err != nil {
+			return err
+		}
+		return &VerificationError{Check: G2StructureCheck, Transcript: -1,
+			Element: "G2Powers", Index: -1}
+	}
+
+	return nil
+}
+
+// randomScalars returns n random non-zero scalars from crypto/rand
+func randomScalars(n int) ([]*bls12381.Fr, error) {
+	r := make([]*bls12381.Fr, n)
+	for i := 0; i < n; i++ {
+		r[i] = bls12381.NewFr()
+		for r[i].IsZero() {
+			if _, err := r[i].Rand(rand.Reader); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r, nil
 }
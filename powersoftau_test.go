@@ -3,9 +3,11 @@ package kzgceremony
 import (
 	"encoding/json"
 	"io/ioutil"
+	"math/big"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
 )
 
 func TestContribution(t *testing.T) {
@@ -62,6 +64,179 @@ func TestBatchContribution(t *testing.T) {
 	c.Assert(g2.Equal(nb.Contributions[0].PotPubKey, nb.Contributions[2].PotPubKey), qt.IsFalse)
 	c.Assert(g2.Equal(nb.Contributions[0].PotPubKey, nb.Contributions[3].PotPubKey), qt.IsFalse)
 
+	c.Assert(VerifyBatchContribution(bc, nb), qt.IsTrue)
+	c.Assert(VerifyBatchContributionWithMode(bc, nb, VerifyBatch), qt.IsTrue)
+	// the batch is not computed from itself
+	c.Assert(VerifyBatchContribution(nb, nb), qt.IsFalse)
+
 	_, err = json.Marshal(nb)
 	c.Assert(err, qt.IsNil)
 }
+
+// computeContributionNaive is the straightforward (single-threaded,
+// exponentiation per power) computation of the new SRS, used as reference
+func computeContributionNaive(t *toxicWaste, prevSRS *SRS) *SRS {
+	srs := newEmptySRS(len(prevSRS.G1Powers), len(prevSRS.G2Powers))
+	Q := g1.Q()
+	for i := 0; i < len(prevSRS.G1Powers); i++ {
+		tau_i := new(big.Int).Exp(t.tau.ToBig(), big.NewInt(int64(i)), Q)
+		tau_i_Fr := bls12381.NewFr().FromBytes(tau_i.Bytes())
+		g1.MulScalar(srs.G1Powers[i], prevSRS.G1Powers[i], tau_i_Fr)
+	}
+	for i := 0; i < len(prevSRS.G2Powers); i++ {
+		tau_i := new(big.Int).Exp(t.tau.ToBig(), big.NewInt(int64(i)), Q)
+		tau_i_Fr := bls12381.NewFr().FromBytes(tau_i.Bytes())
+		g2.MulScalar(srs.G2Powers[i], prevSRS.G2Powers[i], tau_i_Fr)
+	}
+	return srs
+}
+
+func TestComputeContributionMatchesNaive(t *testing.T) {
+	c := qt.New(t)
+
+	j, err := ioutil.ReadFile("batch_contribution_10.json")
+	c.Assert(err, qt.IsNil)
+	bc := &BatchContribution{}
+	err = json.Unmarshal(j, bc)
+	c.Assert(err, qt.IsNil)
+	prevSRS := bc.Contributions[0].PowersOfTau
+
+	tw, err := tau(0, len(prevSRS.G1Powers), len(prevSRS.G2Powers),
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+	defer tw.zeroize()
+	expected := computeContributionNaive(tw, prevSRS)
+
+	defer func(n int) { NumWorkers = n }(NumWorkers)
+	for _, workers := range []int{1, 3, 4, 16} {
+		NumWorkers = workers
+		srs := computeContribution(tw, prevSRS)
+		c.Assert(g1PointsToStrings(srs.G1Powers), qt.DeepEquals,
+			g1PointsToStrings(expected.G1Powers))
+		c.Assert(g2PointsToStrings(srs.G2Powers), qt.DeepEquals,
+			g2PointsToStrings(expected.G2Powers))
+	}
+}
+
+func benchmarkComputeContribution(b *testing.B, nG1, nG2 int) {
+	prevSRS := newEmptySRS(nG1, nG2)
+	tw, err := tau(0, nG1, nG2,
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer tw.zeroize()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		computeContribution(tw, prevSRS)
+	}
+}
+
+func BenchmarkComputeContribution4096(b *testing.B) {
+	benchmarkComputeContribution(b, 4096, 65)
+}
+
+func BenchmarkComputeContribution8192(b *testing.B) {
+	benchmarkComputeContribution(b, 8192, 65)
+}
+
+func BenchmarkComputeContribution16384(b *testing.B) {
+	benchmarkComputeContribution(b, 16384, 65)
+}
+
+func BenchmarkComputeContribution32768(b *testing.B) {
+	benchmarkComputeContribution(b, 32768, 65)
+}
+
+func TestVerificationModes(t *testing.T) {
+	c := qt.New(t)
+
+	srs_0 := newEmptySRS(10, 10)
+	srs_1, proof_1, err := Contribute(srs_0, 0,
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+	srs_2, proof_2, err := Contribute(srs_1, 0,
+		[]byte("2222222222222222222222222222222222222222222222222222222222222222"))
+	c.Assert(err, qt.IsNil)
+
+	for _, mode := range []VerificationMode{VerifyStrict, VerifyBatch} {
+		c.Assert(VerifyNewSRSFromPrevSRSWithMode(srs_0, srs_1, proof_1, mode), qt.IsTrue)
+		c.Assert(VerifyNewSRSFromPrevSRSWithMode(srs_1, srs_2, proof_2, mode), qt.IsTrue)
+	}
+
+	// malformed SRSs, which keep valid points (and G1Powers[1], so the
+	// proof is still valid) but break the powers of tau structure
+	copySRS := func(srs *SRS) *SRS {
+		return &SRS{
+			G1Powers: append([]*bls12381.PointG1{}, srs.G1Powers...),
+			G2Powers: append([]*bls12381.PointG2{}, srs.G2Powers...),
+		}
+	}
+	swappedG1 := copySRS(srs_2)
+	swappedG1.G1Powers[3], swappedG1.G1Powers[4] =
+		swappedG1.G1Powers[4], swappedG1.G1Powers[3]
+	replacedG1 := copySRS(srs_2)
+	replacedG1.G1Powers[9] = srs_1.G1Powers[9]
+	swappedG2 := copySRS(srs_2)
+	swappedG2.G2Powers[5], swappedG2.G2Powers[6] =
+		swappedG2.G2Powers[6], swappedG2.G2Powers[5]
+	replacedG2 := copySRS(srs_2)
+	replacedG2.G2Powers[9] = srs_1.G2Powers[9]
+	doubledG1 := copySRS(srs_2)
+	doubledG1.G1Powers[2] = g1.New()
+	g1.Double(doubledG1.G1Powers[2], srs_2.G1Powers[2])
+
+	for _, mode := range []VerificationMode{VerifyStrict, VerifyBatch} {
+		for _, srs := range []*SRS{swappedG1, replacedG1, swappedG2, replacedG2, doubledG1} {
+			c.Assert(VerifyNewSRSFromPrevSRSWithMode(srs_1, srs, proof_2, mode), qt.IsFalse)
+		}
+		// proof that does not correspond to the previous SRS
+		c.Assert(VerifyNewSRSFromPrevSRSWithMode(srs_0, srs_2, proof_2, mode), qt.IsFalse)
+	}
+}
+
+func TestVerifyState(t *testing.T) {
+	c := qt.New(t)
+
+	for _, mode := range []VerificationMode{VerifyStrict, VerifyBatch} {
+		s := NewEmptyState([]int{16, 8, 8, 4}, []int{4, 4, 4, 4})
+		s, err := s.Contribute(
+			[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+		c.Assert(err, qt.IsNil)
+		c.Assert(VerifyStateWithMode(s, mode), qt.IsTrue)
+
+		newState, err := s.Contribute(
+			[]byte("2222222222222222222222222222222222222222222222222222222222222222"))
+		c.Assert(err, qt.IsNil)
+		c.Assert(VerifyStateWithMode(newState, mode), qt.IsTrue)
+
+		// break the structure of the first Transcript
+		powers := newState.Transcripts[0].PowersOfTau.G1Powers
+		powers[5], powers[6] = powers[6], powers[5]
+		c.Assert(VerifyStateWithMode(newState, mode), qt.IsFalse)
+	}
+}
+
+func BenchmarkVerifyStrict(b *testing.B) {
+	benchmarkVerifySRSStructure(b, VerifyStrict)
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	benchmarkVerifySRSStructure(b, VerifyBatch)
+}
+
+func benchmarkVerifySRSStructure(b *testing.B, mode VerificationMode) {
+	srs, _, err := Contribute(newEmptySRS(4096, 65), 0,
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := verifySRSStructure(srs, mode); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package kzgceremony
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"math/bits"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// The .ptau format of snarkjs (https://github.com/iden3/snarkjs) is a binary
+// file made of the magic "ptau", a version and a list of sections, each one
+// with its id and its size in bytes. The integers are little-endian. The
+// header section contains the field modulus q of the curve and the power p
+// of the file, which then contains the sections:
+//
+//	2: tauG1, the 2ᵖ⁺¹-1 powers [τⁱ]₁
+//	3: tauG2, the 2ᵖ powers [τⁱ]₂
+//	4: alphaTauG1, 5: betaTauG1, 6: betaG2, the Groth16 [α⋅τⁱ]₁, [β⋅τⁱ]₁
+//	   and [β]₂
+//	7: contributions, and 12 to 15 the Lagrange forms of a prepared file
+//
+// The points are stored in affine form, each coordinate of Fq as n8 bytes
+// little-endian in Montgomery form (x⋅R mod q, with R = 2⁸ⁿ⁸), the Fq2
+// coordinates as c0 followed by c1, and the point at infinity as zeros.
+//
+// Only the header and the tauG1/tauG2 sections map to the SRS: this
+// ceremony has no α and β, so the written files contain only those sections.
+// They are not complete ptau files: the snarkjs commands that expect the
+// sections 4 to 7 (eg. powersoftau verify and prepare phase2) do not accept
+// them, and they have not been checked with snarkjs. They are meant for the
+// tools that only read the tauG1 and tauG2 sections. The files of the
+// Hermez/perpetual ceremony are over BN254 and can not be read, only the
+// files over the bls12381 curve are supported.
+
+var ptauMagic = [4]byte{'p', 't', 'a', 'u'}
+
+const ptauVersion = 1
+
+const (
+	ptauSectionHeader = 1
+	ptauSectionTauG1  = 2
+	ptauSectionTauG2  = 3
+)
+
+// ptauMaxPower is the maximum power of the files generated by snarkjs
+const ptauMaxPower = 28
+
+// fpSize is the size in bytes of an element of Fq, the base field of
+// BLS12-381
+const fpSize = 48
+
+var (
+	// fpModulus is the modulus q of the base field of BLS12-381
+	fpModulus, _ = new(big.Int).SetString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+	// fpMontR is the Montgomery factor R = 2³⁸⁴ mod q, and fpMontRInv its
+	// inverse
+	fpMontR    = new(big.Int).Mod(new(big.Int).Lsh(big.NewInt(1), 8*fpSize), fpModulus)
+	fpMontRInv = new(big.Int).ModInverse(fpMontR, fpModulus)
+)
+
+// WritePtau writes the SRS as a snarkjs .ptau file of power p, containing
+// only the header, tauG1 and tauG2 sections (see the limitations above). The
+// SRS must have the shape of a .ptau file: 2ᵖ⁺¹-1 G1 powers and 2ᵖ G2 powers
+// (the SRS of a larger ceremony can be sliced to that shape).
+func WritePtau(w io.Writer, srs *SRS) error {
+	power, err := ptauPower(len(srs.G1Powers), len(srs.G2Powers))
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	_, _ = bw.Write(ptauMagic[:])
+	_ = binary.Write(bw, binary.LittleEndian, uint32(ptauVersion))
+	_ = binary.Write(bw, binary.LittleEndian, uint32(3))
+
+	// header: n8, q, power, ceremonyPower
+	writePtauSectionStart(bw, ptauSectionHeader, 4+fpSize+4+4)
+	_ = binary.Write(bw, binary.LittleEndian, uint32(fpSize))
+	_, _ = bw.Write(fpToLE(fpModulus))
+	_ = binary.Write(bw, binary.LittleEndian, uint32(power))
+	_ = binary.Write(bw, binary.LittleEndian, uint32(power))
+
+	g1 := bls12381.NewG1()
+	writePtauSectionStart(bw, ptauSectionTauG1, len(srs.G1Powers)*2*fpSize)
+	for _, p := range srs.G1Powers {
+		// x ‖ y, big-endian
+		_, _ = bw.Write(bytesToLEM(g1.ToBytes(g1.New().Set(p))))
+	}
+	g2 := bls12381.NewG2()
+	writePtauSectionStart(bw, ptauSectionTauG2, len(srs.G2Powers)*4*fpSize)
+	for _, p := range srs.G2Powers {
+		// x.c1 ‖ x.c0 ‖ y.c1 ‖ y.c0, big-endian
+		b := g2.ToBytes(g2.New().Set(p))
+		_, _ = bw.Write(bytesToLEM(b[fpSize : 2*fpSize]))
+		_, _ = bw.Write(bytesToLEM(b[:fpSize]))
+		_, _ = bw.Write(bytesToLEM(b[3*fpSize:]))
+		_, _ = bw.Write(bytesToLEM(b[2*fpSize : 3*fpSize]))
+	}
+	return bw.Flush()
+}
+
+// ReadPtau reads the tauG1 and tauG2 sections of a snarkjs .ptau file over
+// BLS12-381 into an SRS, checking the correctness of all the points. The
+// header must be the first section, as in the files written by snarkjs, and
+// the other sections are skipped. The powers of tau structure is not
+// checked, CheckSRS can be used for it.
+func ReadPtau(r io.Reader) (*SRS, error) {
+	br := bufio.NewReader(r)
+	var fileHeader struct {
+		Magic     [4]byte
+		Version   uint32
+		NSections uint32
+	}
+	if err := binary.Read(br, binary.LittleEndian, &fileHeader); err != nil {
+		return nil, fmt.Errorf("reading ptau header: %w", err)
+	}
+	if fileHeader.Magic != ptauMagic {
+		return nil, fmt.Errorf("not a ptau file, wrong magic %x", fileHeader.Magic[:])
+	}
+	if fileHeader.Version != ptauVersion {
+		return nil, fmt.Errorf("unsupported ptau version %d", fileHeader.Version)
+	}
+
+	power := -1
+	srs := &SRS{}
+	for i := 0; i < int(fileHeader.NSections); i++ {
+		var section struct {
+			ID   uint32
+			Size uint64
+		}
+		if err := binary.Read(br, binary.LittleEndian, &section); err != nil {
+			return nil, fmt.Errorf("reading ptau section %d: %w", i, err)
+		}
+		if power < 0 && section.ID != ptauSectionHeader {
+			return nil, fmt.Errorf("ptau section %d found before the header", section.ID)
+		}
+		switch section.ID {
+		case ptauSectionHeader:
+			if power >= 0 {
+				return nil, fmt.Errorf("duplicated ptau header section")
+			}
+			var err error
+			power, err = readPtauHeader(br, section.Size)
+			if err != nil {
+				return nil, err
+			}
+		case ptauSectionTauG1:
+			n := 1<<(power+1) - 1
+			if section.Size != uint64(n*2*fpSize) {
+				return nil, fmt.Errorf("tauG1 section of %d bytes, expected %d points",
+					section.Size, n)
+			}
+			points, err := readPtauPointsG1(br, n)
+			if err != nil {
+				return nil, fmt.Errorf("tauG1: %w", err)
+			}
+			srs.G1Powers = points
+		case ptauSectionTauG2:
+			n := 1 << power
+			if section.Size != uint64(n*4*fpSize) {
+				return nil, fmt.Errorf("tauG2 section of %d bytes, expected %d points",
+					section.Size, n)
+			}
+			points, err := readPtauPointsG2(br, n)
+			if err != nil {
+				return nil, fmt.Errorf("tauG2: %w", err)
+			}
+			srs.G2Powers = points
+		default:
+			if _, err := io.CopyN(io.Discard, br, int64(section.Size)); err != nil {
+				return nil, fmt.Errorf("skipping ptau section %d: %w", section.ID, err)
+			}
+		}
+	}
+	if srs.G1Powers == nil || srs.G2Powers == nil {
+		return nil, fmt.Errorf("ptau file without tauG1 and tauG2 sections")
+	}
+	return srs, nil
+}
+
+// ptauPower returns the power p of a .ptau file with the given number of G1
+// and G2 powers
+func ptauPower(nG1, nG2 int) (int, error) {
+	if nG2 < 1 || nG2&(nG2-1) != 0 || nG1 != 2*nG2-1 {
+		return 0, fmt.Errorf("SRS of %d G1 and %d G2 powers does not have the "+
+			"ptau shape of 2ᵖ⁺¹-1 G1 and 2ᵖ G2 powers", nG1, nG2)
+	}
+	return bits.TrailingZeros(uint(nG2)), nil
+}
+
+func writePtauSectionStart(w io.Writer, id, size int) {
+	_ = binary.Write(w, binary.LittleEndian, uint32(id))
+	_ = binary.Write(w, binary.LittleEndian, uint64(size))
+}
+
+// readPtauHeader reads the header section, returning the power of the file
+func readPtauHeader(r io.Reader, size uint64) (int, error) {
+	if size < 4 || size > 4+fpSize+4+4 {
+		return 0, fmt.Errorf("ptau header section of %d bytes", size)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, fmt.Errorf("reading ptau header section: %w", err)
+	}
+	n8 := binary.LittleEndian.Uint32(b)
+	if n8 != fpSize || size < 4+fpSize+4 ||
+		new(big.Int).SetBytes(reverseBytes(b[4:4+fpSize])).Cmp(fpModulus) != 0 {
+		return 0, fmt.Errorf("ptau file over an unsupported curve, only BLS12-381 is supported")
+	}
+	power := binary.LittleEndian.Uint32(b[4+fpSize:])
+	if power < 1 || power > ptauMaxPower {
+		return 0, fmt.Errorf("unsupported ptau power %d", power)
+	}
+	return int(power), nil
+}
+
+// pointsChunkSize is the number of points read at once from the binary
+// files, so that a wrong size does not allocate more memory than the
+// available data
+const pointsChunkSize = 1 << 12
+
+func readPtauPointsG1(r io.Reader, n int) ([]*bls12381.PointG1, error) {
+	var points []*bls12381.PointG1
+	buf := make([]byte, pointsChunkSize*2*fpSize)
+	for len(points) < n {
+		m := n - len(points)
+		if m > pointsChunkSize {
+			m = pointsChunkSize
+		}
+		b := buf[:m*2*fpSize]
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		chunk := make([]*bls12381.PointG1, m)
+		errs := make([]error, m)
+		parallelize(m, func(start, end int) {
+			g1 := bls12381.NewG1()
+			for i := start; i < end; i++ {
+				chunk[i], errs[i] = lemToPointG1(g1, b[i*2*fpSize:(i+1)*2*fpSize])
+				if errs[i] != nil {
+					return
+				}
+			}
+		})
+		for i, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("G1 point %d: %w", len(points)+i, err)
+			}
+		}
+		points = append(points, chunk...)
+	}
+	return points, nil
+}
+
+func readPtauPointsG2(r io.Reader, n int) ([]*bls12381.PointG2, error) {
+	var points []*bls12381.PointG2
+	buf := make([]byte, pointsChunkSize*4*fpSize)
+	for len(points) < n {
+		m := n - len(points)
+		if m > pointsChunkSize {
+			m = pointsChunkSize
+		}
+		b := buf[:m*4*fpSize]
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		chunk := make([]*bls12381.PointG2, m)
+		errs := make([]error, m)
+		parallelize(m, func(start, end int) {
+			g2 := bls12381.NewG2()
+			for i := start; i < end; i++ {
+				chunk[i], errs[i] = lemToPointG2(g2, b[i*4*fpSize:(i+1)*4*fpSize])
+				if errs[i] != nil {
+					return
+				}
+			}
+		})
+		for i, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("G2 point %d: %w", len(points)+i, err)
+			}
+		}
+		points = append(points, chunk...)
+	}
+	return points, nil
+}
+
+// lemToPointG1 parses a G1 point stored as x ‖ y in little-endian
+// Montgomery form, checking its correctness
+func lemToPointG1(g1 *bls12381.G1, b []byte) (*bls12381.PointG1, error) {
+	x, err := lemToBytes(b[:fpSize])
+	if err != nil {
+		return nil, err
+	}
+	y, err := lemToBytes(b[fpSize:])
+	if err != nil {
+		return nil, err
+	}
+	p, err := g1.FromBytes(append(x, y...))
+	if err != nil {
+		return nil, err
+	}
+	return p, checkG1Point(g1, p)
+}
+
+// lemToPointG2 parses a G2 point stored as x.c0 ‖ x.c1 ‖ y.c0 ‖ y.c1 in
+// little-endian Montgomery form, checking its correctness
+func lemToPointG2(g2 *bls12381.G2, b []byte) (*bls12381.PointG2, error) {
+	// FromBytes expects x.c1 ‖ x.c0 ‖ y.c1 ‖ y.c0
+	in := make([]byte, 0, 4*fpSize)
+	for _, i := range []int{1, 0, 3, 2} {
+		c, err := lemToBytes(b[i*fpSize : (i+1)*fpSize])
+		if err != nil {
+			return nil, err
+		}
+		in = append(in, c...)
+	}
+	p, err := g2.FromBytes(in)
+	if err != nil {
+		return nil, err
+	}
+	return p, checkG2Point(g2, p)
+}
+
+// bytesToLEM converts the big-endian Fq elements of b to little-endian
+// Montgomery form
+func bytesToLEM(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	v := new(big.Int)
+	for i := 0; i < len(b); i += fpSize {
+		v.SetBytes(b[i : i+fpSize])
+		v.Mul(v, fpMontR)
+		v.Mod(v, fpModulus)
+		out = append(out, fpToLE(v)...)
+	}
+	return out
+}
+
+// lemToBytes converts an Fq element from little-endian Montgomery form to
+// big-endian, checking that it is canonical
+func lemToBytes(b []byte) ([]byte, error) {
+	v := new(big.Int).SetBytes(reverseBytes(b))
+	if v.Cmp(fpModulus) >= 0 {
+		return nil, fmt.Errorf("coordinate is not a canonical field element")
+	}
+	v.Mul(v, fpMontRInv)
+	v.Mod(v, fpModulus)
+	out := make([]byte, fpSize)
+	return v.FillBytes(out), nil
+}
+
+// fpToLE returns the fpSize bytes little-endian encoding of v
+func fpToLE(v *big.Int) []byte {
+	return reverseBytes(v.FillBytes(make([]byte, fpSize)))
+}
+
+// reverseBytes returns a copy of b in reverse order
+func reverseBytes(b []byte) []byte {
+	out := append([]byte(nil), b...)
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
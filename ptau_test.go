@@ -0,0 +1,183 @@
+package kzgceremony
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestPtauRoundTrip(t *testing.T) {
+	c := qt.New(t)
+
+	srs, _, err := Contribute(newEmptySRS(15, 8), 0,
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+
+	var b bytes.Buffer
+	c.Assert(WritePtau(&b, srs), qt.IsNil)
+	c.Assert(b.Len(), qt.Equals, 12+(12+60)+(12+15*96)+(12+8*192))
+	encoded := append([]byte(nil), b.Bytes()...)
+
+	imported, err := ReadPtau(&b)
+	c.Assert(err, qt.IsNil)
+	c.Assert(imported.G1Powers, qt.HasLen, 15)
+	c.Assert(imported.G2Powers, qt.HasLen, 8)
+	for i := range srs.G1Powers {
+		c.Assert(g1.Equal(imported.G1Powers[i], srs.G1Powers[i]), qt.IsTrue)
+	}
+	for i := range srs.G2Powers {
+		c.Assert(g2.Equal(imported.G2Powers[i], srs.G2Powers[i]), qt.IsTrue)
+	}
+	c.Assert(CheckSRS(imported, VerifyStrict), qt.IsNil)
+	c.Assert(CheckSRS(imported, VerifyBatch), qt.IsNil)
+
+	b.Reset()
+	c.Assert(WritePtau(&b, imported), qt.IsNil)
+	c.Assert(b.Bytes(), qt.DeepEquals, encoded)
+
+	err = WritePtau(&b, newEmptySRS(16, 8))
+	c.Assert(err, qt.ErrorMatches, "SRS of 16 G1 and 8 G2 powers does not have the ptau shape .*")
+	err = WritePtau(&b, newEmptySRS(11, 6))
+	c.Assert(err, qt.ErrorMatches, "SRS of 11 G1 and 6 G2 powers does not have the ptau shape .*")
+}
+
+func TestPtauLayout(t *testing.T) {
+	c := qt.New(t)
+
+	var b bytes.Buffer
+	c.Assert(WritePtau(&b, newEmptySRS(3, 2)), qt.IsNil)
+	f := b.Bytes()
+
+	c.Assert(string(f[:4]), qt.Equals, "ptau")
+	c.Assert(binary.LittleEndian.Uint32(f[4:]), qt.Equals, uint32(1))
+	c.Assert(binary.LittleEndian.Uint32(f[8:]), qt.Equals, uint32(3))
+
+	// header: id 1, 60 bytes, n8 = 48, q, power = ceremonyPower = 1
+	h := f[12:]
+	c.Assert(binary.LittleEndian.Uint32(h), qt.Equals, uint32(1))
+	c.Assert(binary.LittleEndian.Uint64(h[4:]), qt.Equals, uint64(60))
+	c.Assert(binary.LittleEndian.Uint32(h[12:]), qt.Equals, uint32(48))
+	q := make([]byte, 48)
+	for i := range q {
+		q[i] = h[16+47-i]
+	}
+	c.Assert(new(big.Int).SetBytes(q).Cmp(fpModulus), qt.Equals, 0)
+	c.Assert(binary.LittleEndian.Uint32(h[64:]), qt.Equals, uint32(1))
+	c.Assert(binary.LittleEndian.Uint32(h[68:]), qt.Equals, uint32(1))
+
+	// tauG1: id 2, 3 points. The first one is the generator, whose
+	// coordinates in little-endian Montgomery form (R = 2³⁸⁴) match the
+	// limbs used internally by kilic/bls12-381
+	s := h[72:]
+	c.Assert(binary.LittleEndian.Uint32(s), qt.Equals, uint32(2))
+	c.Assert(binary.LittleEndian.Uint64(s[4:]), qt.Equals, uint64(3*96))
+	one := g1.One()
+	for k := 0; k < 6; k++ {
+		c.Assert(binary.LittleEndian.Uint64(s[12+8*k:]), qt.Equals, one[0][k])
+		c.Assert(binary.LittleEndian.Uint64(s[12+48+8*k:]), qt.Equals, one[1][k])
+	}
+
+	// tauG2: id 3, 2 points, with the Fq2 coordinates as c0 ‖ c1
+	s = s[12+3*96:]
+	c.Assert(binary.LittleEndian.Uint32(s), qt.Equals, uint32(3))
+	c.Assert(binary.LittleEndian.Uint64(s[4:]), qt.Equals, uint64(2*192))
+	one2 := g2.One()
+	for k := 0; k < 6; k++ {
+		c.Assert(binary.LittleEndian.Uint64(s[12+8*k:]), qt.Equals, one2[0][0][k])
+		c.Assert(binary.LittleEndian.Uint64(s[12+48+8*k:]), qt.Equals, one2[0][1][k])
+		c.Assert(binary.LittleEndian.Uint64(s[12+96+8*k:]), qt.Equals, one2[1][0][k])
+		c.Assert(binary.LittleEndian.Uint64(s[12+144+8*k:]), qt.Equals, one2[1][1][k])
+	}
+	c.Assert(s[12+2*192:], qt.HasLen, 0)
+}
+
+func TestReadPtauErrors(t *testing.T) {
+	c := qt.New(t)
+
+	var b bytes.Buffer
+	c.Assert(WritePtau(&b, newEmptySRS(7, 4)), qt.IsNil)
+	valid := b.Bytes()
+	modified := func(f func(b []byte) []byte) []byte {
+		return f(append([]byte(nil), valid...))
+	}
+
+	// an extra section (eg. the contributions) is skipped
+	extra := modified(func(b []byte) []byte {
+		binary.LittleEndian.PutUint32(b[8:], 4)
+		b = append(b, 7, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0)
+		return append(b, 0, 0, 0, 0)
+	})
+	srs, err := ReadPtau(bytes.NewReader(extra))
+	c.Assert(err, qt.IsNil)
+	c.Assert(srs.G1Powers, qt.HasLen, 7)
+
+	for _, tc := range []struct {
+		name string
+		file []byte
+		err  string
+	}{
+		{"magic", modified(func(b []byte) []byte { b[0] = 'z'; return b }),
+			"not a ptau file, wrong magic .*"},
+		{"version", modified(func(b []byte) []byte { b[4] = 2; return b }),
+			"unsupported ptau version 2"},
+		{"truncated", valid[:len(valid)-10], "tauG2: unexpected EOF"},
+		{"missing section", modified(func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[8:], 2)
+			return b
+		}), "ptau file without tauG1 and tauG2 sections"},
+		// a BN254 file, as the ones of the Hermez/perpetual ceremony
+		{"curve", modified(func(b []byte) []byte {
+			bn254, _ := new(big.Int).SetString("21888242871839275222246405745257275088696311157297823662689037894645226208583", 10)
+			h := []byte{1, 0, 0, 0, 44, 0, 0, 0, 0, 0, 0, 0, 32, 0, 0, 0}
+			h = append(h, reverseBytes(bn254.FillBytes(make([]byte, 32)))...)
+			h = append(h, 2, 0, 0, 0, 2, 0, 0, 0)
+			return append(b[:12], h...)
+		}), "ptau file over an unsupported curve, only BLS12-381 is supported"},
+		{"header", modified(func(b []byte) []byte {
+			b[12] = 2
+			return b
+		}), "ptau section 2 found before the header"},
+		{"section size", modified(func(b []byte) []byte {
+			b[12+72+4]++
+			return b
+		}), "tauG1 section of 673 bytes, expected 7 points"},
+		{"point", modified(func(b []byte) []byte {
+			// y of the second G1 point
+			b[12+72+12+96+48]++
+			return b
+		}), "tauG1: G1 point 1: point is not on curve"},
+		{"infinity", modified(func(b []byte) []byte {
+			copy(b[12+72+12+96:], make([]byte, 96))
+			return b
+		}), "tauG1: G1 point 1: point can not be zero"},
+	} {
+		_, err := ReadPtau(bytes.NewReader(tc.file))
+		c.Assert(err, qt.ErrorMatches, tc.err, qt.Commentf(tc.name))
+	}
+}
+
+func TestCheckSRS(t *testing.T) {
+	c := qt.New(t)
+
+	srs, _, err := Contribute(newEmptySRS(7, 4), 0,
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(CheckSRS(srs, VerifyStrict), qt.IsNil)
+
+	srs.G1Powers[3], srs.G1Powers[4] = srs.G1Powers[4], srs.G1Powers[3]
+	var vErr *VerificationError
+	c.Assert(errors.As(CheckSRS(srs, VerifyBatch), &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, G1StructureCheck)
+	c.Assert(vErr.Index, qt.Equals, 3)
+	srs.G1Powers[3], srs.G1Powers[4] = srs.G1Powers[4], srs.G1Powers[3]
+
+	// powers of tau of a different base point
+	srs.G1Powers[0] = srs.G1Powers[1]
+	c.Assert(errors.As(CheckSRS(srs, VerifyStrict), &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, GenesisCheck)
+	c.Assert(vErr.Element, qt.Equals, "G1Powers")
+}
@@ -0,0 +1,113 @@
+package kzgceremony
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// secretScalars is a fixed-size buffer of Fr elements holding secret values
+// (the toxic waste τ and its powers). The backing memory is locked when the
+// platform allows it, so that it is not swapped to disk, and it is zeroed when
+// the buffer is released.
+//
+// Note that the scalar arithmetic on the buffer (Fr.Add, Fr.Mul) is constant
+// time, but the point scalar multiplications of the bls12-381 library are
+// not guaranteed to be.
+type secretScalars struct {
+	frs []bls12381.Fr
+	// mem is the memory backing frs, nil when it is allocated by the Go
+	// runtime
+	mem []byte
+	// locked is true when mem could be locked in memory
+	locked   bool
+	released bool
+}
+
+// secretScalarsAllocHook and secretScalarsReleaseHook are called, when set,
+// each time a secretScalars is allocated and right before its memory is
+// returned, so that the tests can check that all the secrets are zeroed
+var (
+	secretScalarsAllocHook   func(s *secretScalars)
+	secretScalarsReleaseHook func(s *secretScalars)
+)
+
+// newSecretScalars returns a buffer of n zero Fr elements
+func newSecretScalars(n int) *secretScalars {
+	s := allocSecretScalars(n)
+	if secretScalarsAllocHook != nil {
+		secretScalarsAllocHook(s)
+	}
+	return s
+}
+
+// zero overwrites all the elements of the buffer with zeroes
+func (s *secretScalars) zero() {
+	for i := range s.frs {
+		s.frs[i] = bls12381.Fr{}
+	}
+}
+
+// isZero reports whether all the elements of the buffer are zero, in time
+// independent of their values
+func (s *secretScalars) isZero() bool {
+	var acc uint64
+	for i := range s.frs {
+		for _, l := range s.frs[i] {
+			acc |= l
+		}
+	}
+	return acc == 0
+}
+
+// release zeroes the buffer and returns its memory. It is safe to call it
+// more than once.
+func (s *secretScalars) release() {
+	if s == nil || s.released {
+		return
+	}
+	s.zero()
+	if secretScalarsReleaseHook != nil {
+		secretScalarsReleaseHook(s)
+	}
+	freeSecretScalars(s)
+	s.frs = nil
+	s.released = true
+}
+
+var (
+	// frWideR1 = 2²⁴⁸ mod r, frWideR2 = 2⁴⁹⁶ mod r
+	frWideR1 = bls12381.NewFr().FromBytes(
+		new(big.Int).Mod(new(big.Int).Lsh(big.NewInt(1), 248), g2.Q()).Bytes())
+	frWideR2 = bls12381.NewFr().FromBytes(
+		new(big.Int).Mod(new(big.Int).Lsh(big.NewInt(1), 496), g2.Q()).Bytes())
+)
+
+// frFromWideBytes sets e to the 64 bytes big-endian value in mod r, without
+// going through big.Int (whose operations are not constant time). The value
+// is split in chunks of at most 31 bytes, v = a₂⋅2⁴⁹⁶ + a₁⋅2²⁴⁸ + a₀, each of
+// them smaller than r, which are combined with Fr arithmetic.
+func frFromWideBytes(e *bls12381.Fr, in *[64]byte) {
+	var a1, a2 bls12381.Fr
+	frFromShortBytes(e, in[33:64])
+	frFromShortBytes(&a1, in[2:33])
+	frFromShortBytes(&a2, in[0:2])
+	a1.Mul(&a1, frWideR1)
+	a2.Mul(&a2, frWideR2)
+	e.Add(e, &a1)
+	e.Add(e, &a2)
+	a1 = bls12381.Fr{}
+	a2 = bls12381.Fr{}
+}
+
+// frFromShortBytes sets e to the big-endian value in, which must be at most
+// 31 bytes long so that it is smaller than r
+func frFromShortBytes(e *bls12381.Fr, in []byte) {
+	var buf [32]byte
+	copy(buf[32-len(in):], in)
+	for i := 0; i < 4; i++ {
+		e[i] = binary.BigEndian.Uint64(buf[32-8*(i+1) : 32-8*i])
+	}
+	zero(buf[:])
+}
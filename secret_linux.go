@@ -0,0 +1,51 @@
+//go:build linux
+
+package kzgceremony
+
+import (
+	"syscall"
+	"unsafe"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// madvDontDump excludes the pages from core dumps
+const madvDontDump = 0x10
+
+// allocSecretScalars maps anonymous memory outside of the Go heap for the
+// buffer and locks it with mlock, so that the secrets are never swapped to
+// disk nor moved or copied by the runtime. Locking is best effort, as it can
+// fail when exceeding RLIMIT_MEMLOCK; on any mmap failure the buffer falls
+// back to Go memory.
+func allocSecretScalars(n int) *secretScalars {
+	size := n * int(unsafe.Sizeof(bls12381.Fr{}))
+	if size == 0 {
+		return &secretScalars{}
+	}
+	mem, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return &secretScalars{frs: make([]bls12381.Fr, n)}
+	}
+	s := &secretScalars{
+		frs: unsafe.Slice((*bls12381.Fr)(unsafe.Pointer(&mem[0])), n),
+		mem: mem,
+	}
+	s.locked = syscall.Mlock(mem) == nil
+	_ = syscall.Madvise(mem, madvDontDump)
+	return s
+}
+
+// freeSecretScalars unlocks and unmaps the memory of the (already zeroed)
+// buffer
+func freeSecretScalars(s *secretScalars) {
+	if s.mem == nil {
+		return
+	}
+	if s.locked {
+		_ = syscall.Munlock(s.mem)
+	}
+	_ = syscall.Munmap(s.mem)
+	s.mem = nil
+	s.locked = false
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package kzgceremony
+
+import bls12381 "github.com/kilic/bls12-381"
+
+// allocSecretScalars allocates the buffer in Go memory, as locking it is
+// only supported on Linux
+func allocSecretScalars(n int) *secretScalars {
+	return &secretScalars{frs: make([]bls12381.Fr, n)}
+}
+
+// freeSecretScalars is a no-op, as the buffer is already zeroed and its
+// memory is managed by the Go runtime
+func freeSecretScalars(s *secretScalars) {}
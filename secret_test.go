@@ -0,0 +1,86 @@
+package kzgceremony
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+func TestFrFromWideBytes(t *testing.T) {
+	c := qt.New(t)
+
+	var in [64]byte
+	for i := range in {
+		in[i] = 0xff
+	}
+	vectors := [][64]byte{{}, in}
+	for i := 0; i < 100; i++ {
+		_, err := rand.Read(in[:])
+		c.Assert(err, qt.IsNil)
+		vectors = append(vectors, in)
+	}
+	for _, v := range vectors {
+		v := v
+		expected := new(big.Int).Mod(new(big.Int).SetBytes(v[:]), g1.Q())
+		e := bls12381.NewFr()
+		frFromWideBytes(e, &v)
+		c.Assert(e.ToBig().Cmp(expected), qt.Equals, 0)
+	}
+}
+
+// trackSecretScalars records the secretScalars allocated until the returned
+// function is called, which checks that all of them were released zeroed
+func trackSecretScalars(c *qt.C) func() {
+	var allocated, released []*secretScalars
+	secretScalarsAllocHook = func(s *secretScalars) {
+		allocated = append(allocated, s)
+	}
+	secretScalarsReleaseHook = func(s *secretScalars) {
+		c.Assert(s.isZero(), qt.IsTrue)
+		released = append(released, s)
+	}
+	return func() {
+		secretScalarsAllocHook = nil
+		secretScalarsReleaseHook = nil
+		c.Assert(len(allocated) > 0, qt.IsTrue)
+		c.Assert(released, qt.HasLen, len(allocated))
+		for _, s := range allocated {
+			c.Assert(s.released, qt.IsTrue)
+			c.Assert(s.frs, qt.IsNil)
+		}
+	}
+}
+
+func TestContributeZeroizesToxicWaste(t *testing.T) {
+	c := qt.New(t)
+
+	j, err := ioutil.ReadFile("batch_contribution_10.json")
+	c.Assert(err, qt.IsNil)
+	randomness := []byte("1111111111111111111111111111111111111111111111111111111111111111")
+
+	// success
+	bc := &BatchContribution{}
+	err = json.Unmarshal(j, bc)
+	c.Assert(err, qt.IsNil)
+	check := trackSecretScalars(c)
+	_, err = bc.ContributeWithIdentity(randomness, "eth|0x0000000000000000000000000000000000000000")
+	c.Assert(err, qt.IsNil)
+	check()
+
+	// error on the last contribution of the batch, once the toxic waste of
+	// the previous ones was already computed
+	bc = &BatchContribution{}
+	err = json.Unmarshal(j, bc)
+	c.Assert(err, qt.IsNil)
+	last := bc.Contributions[len(bc.Contributions)-1].PowersOfTau
+	last.G2Powers = last.G2Powers[:1]
+	check = trackSecretScalars(c)
+	_, err = bc.Contribute(randomness)
+	c.Assert(err, qt.Not(qt.IsNil))
+	check()
+}
@@ -0,0 +1,170 @@
+package sequencer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arnaucube/eth-kzg-ceremony-alt/client"
+)
+
+// sessionTTL is the validity of the id tokens issued by the fake auth
+// provider
+const sessionTTL = 24 * time.Hour
+
+var errAlreadyContributed = errors.New("user has already contributed")
+
+// The fake auth provider replaces the Github and Ethereum OAuth flows of the
+// official sequencer. The links of /auth/request_link point directly to the
+// callbacks of the Server, and the participant completes them by appending
+// the code parameter, which the fake provider takes as the user:
+//
+//	<github_auth_url>&code=<github nickname>
+//	<eth_auth_url>&code=<ethereum address>
+//
+// The callback answers with the same json than the official sequencer (see
+// client.MsgAuthCallback), which contains the session id used by the rest of
+// the endpoints.
+
+func (s *Server) handleRequestLink(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	state, err := randomHex(16)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.mu.Lock()
+	s.csrf[state] = true
+	s.mu.Unlock()
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	base := scheme + "://" + r.Host + "/auth/callback/"
+	writeJSON(w, http.StatusOK, client.MsgRequestLink{
+		EthAuthURL:    base + "eth?state=" + state,
+		GithubAuthURL: base + "github?state=" + state,
+	})
+}
+
+func (s *Server) handleAuthCallback(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	state := r.URL.Query().Get("state")
+	code := r.URL.Query().Get("code")
+
+	s.mu.Lock()
+	if !s.csrf[state] {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, CodeInvalidCsrfToken,
+			"invalid csrf token")
+		return
+	}
+	if code == "" {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, CodeInvalidAuthCode,
+			"missing auth code")
+		return
+	}
+	delete(s.csrf, state)
+
+	var token client.IDToken
+	if strings.HasSuffix(r.URL.Path, "/eth") {
+		token = client.IDToken{Provider: "Ethereum", Sub: strings.ToLower(code),
+			Nickname: strings.ToLower(code)}
+	} else {
+		// each new nickname gets the next numeric Github id
+		id, ok := s.githubID[code]
+		if !ok {
+			s.lastGithubID++
+			id = s.lastGithubID
+			s.githubID[code] = id
+		}
+		token = client.IDToken{Provider: "Github", Nickname: code,
+			Sub: strconv.FormatUint(id, 10)}
+	}
+	msg, err := s.login(token)
+	s.mu.Unlock()
+	if errors.Is(err, errAlreadyContributed) {
+		writeError(w, http.StatusBadRequest, CodeUserAlreadyContributed,
+			err.Error())
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, msg)
+}
+
+// Login opens a session for the given id token without going through the
+// auth callbacks, returning the session id
+func (s *Server) Login(token client.IDToken) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, err := s.login(token)
+	if err != nil {
+		return "", err
+	}
+	return msg.SessionID, nil
+}
+
+// login opens a session, failing if the participant is already in the
+// State. It must be called with the lock held.
+func (s *Server) login(token client.IDToken) (*client.MsgAuthCallback, error) {
+	identity := token.Identity()
+	for _, id := range s.state.ParticipantIDs {
+		if id == identity {
+			return nil, errAlreadyContributed
+		}
+	}
+	if token.Exp == 0 {
+		token.Exp = uint64(time.Now().Add(sessionTTL).Unix())
+	}
+	sessionID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	s.sessions[sessionID] = &session{token: token}
+	return &client.MsgAuthCallback{IDToken: token, SessionID: sessionID}, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// githubIDs returns the numeric Github ids of the nicknames of the
+// "git|<id>|<nickname>" participants, and the highest one, so that a Server
+// continuing a State gives the same ids to the past participants and new ids
+// to the other nicknames
+func githubIDs(participantIDs []string) (map[string]uint64, uint64) {
+	ids := make(map[string]uint64)
+	var last uint64
+	for _, p := range participantIDs {
+		parts := strings.SplitN(p, "|", 3)
+		if len(parts) != 3 || parts[0] != "git" {
+			continue
+		}
+		id, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		ids[parts[2]] = id
+		if id > last {
+			last = id
+		}
+	}
+	return ids, last
+}
@@ -0,0 +1,61 @@
+package sequencer
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+)
+
+// WriteStateFile stores the json encoding of the State at path atomically:
+// it is written to a temporary file of the same directory, synced to disk,
+// and then renamed to path, so that path always contains a complete State,
+// either the previous or the new one
+func WriteStateFile(path string, state *kzgceremony.State) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err = f.Write(b); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	// sync the directory so that the rename is durable, which is not
+	// supported on every platform, so its errors are ignored
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
+
+// ReadStateFile reads the State stored at path by WriteStateFile
+func ReadStateFile(path string) (*kzgceremony.State, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	state := &kzgceremony.State{}
+	if err := kzgceremony.NewStateDecoder(bufio.NewReader(f)).Decode(state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -0,0 +1,122 @@
+package sequencer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+	"github.com/arnaucube/eth-kzg-ceremony-alt/client"
+)
+
+func TestPersistState(t *testing.T) {
+	c := qt.New(t)
+	path := filepath.Join(c.TempDir(), "state.json")
+	srv, ts := newTestServer(c, Config{StatePath: path})
+	cl := client.NewClient(ts.URL)
+
+	// nothing is written until the first contribution
+	_, err := os.Stat(path)
+	c.Assert(os.IsNotExist(err), qt.IsTrue)
+
+	alice, err := srv.Login(client.IDToken{Provider: "Github", Sub: "1", Nickname: "alice"})
+	c.Assert(err, qt.IsNil)
+	prevBatch, _, err := cl.PostTryContribute(alice)
+	c.Assert(err, qt.IsNil)
+	newBatch, err := prevBatch.ContributeWithIdentity([]byte(testRandomness), "git|1|alice")
+	c.Assert(err, qt.IsNil)
+	_, err = cl.PostContribute(alice, newBatch)
+	c.Assert(err, qt.IsNil)
+
+	// a new Server continues from the stored State
+	state, err := ReadStateFile(path)
+	c.Assert(err, qt.IsNil)
+	c.Assert(state.ParticipantIDs, qt.DeepEquals,
+		[]string{"", "git|1214109|kustosz", "git|1|alice"})
+	c.Assert(kzgceremony.VerifyState(state), qt.IsTrue)
+	srv2, err := New(state, Config{StatePath: path})
+	c.Assert(err, qt.IsNil)
+	_, err = srv2.Login(client.IDToken{Provider: "Github", Sub: "1", Nickname: "alice"})
+	c.Assert(err, qt.ErrorMatches, "user has already contributed")
+
+	// no temporary files are left
+	entries, err := os.ReadDir(filepath.Dir(path))
+	c.Assert(err, qt.IsNil)
+	c.Assert(len(entries), qt.Equals, 1)
+}
+
+func TestRestartGithubIDs(t *testing.T) {
+	c := qt.New(t)
+	path := filepath.Join(c.TempDir(), "state.json")
+	srv, ts := newTestServer(c, Config{StatePath: path})
+	cl := client.NewClient(ts.URL)
+
+	login := func(cl *client.Client, nickname string) client.MsgAuthCallback {
+		link, err := cl.GetRequestLink()
+		c.Assert(err, qt.IsNil)
+		resp, body := authCallback(c, link.GithubAuthURL, nickname)
+		c.Assert(resp.StatusCode, qt.Equals, http.StatusOK)
+		var msg client.MsgAuthCallback
+		c.Assert(json.Unmarshal(body, &msg), qt.IsNil)
+		return msg
+	}
+	alice := login(cl, "alice")
+	c.Assert(alice.IDToken.Identity(), qt.Equals, "git|1214110|alice")
+	prevBatch, _, err := cl.PostTryContribute(alice.SessionID)
+	c.Assert(err, qt.IsNil)
+	newBatch, err := prevBatch.ContributeWithIdentity([]byte(testRandomness),
+		alice.IDToken.Identity())
+	c.Assert(err, qt.IsNil)
+	_, err = cl.PostContribute(alice.SessionID, newBatch)
+	c.Assert(err, qt.IsNil)
+	c.Assert(srv.State().ParticipantIDs, qt.HasLen, 3)
+
+	// after a restart, alice gets the same id and can not contribute again,
+	// and a new nickname does not reuse the ids of the past participants
+	state, err := ReadStateFile(path)
+	c.Assert(err, qt.IsNil)
+	srv2, err := New(state, Config{StatePath: path})
+	c.Assert(err, qt.IsNil)
+	ts2 := httptest.NewServer(srv2)
+	defer ts2.Close()
+	cl2 := client.NewClient(ts2.URL)
+
+	link, err := cl2.GetRequestLink()
+	c.Assert(err, qt.IsNil)
+	resp, body := authCallback(c, link.GithubAuthURL, "alice")
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusBadRequest)
+	var errResp ErrorResponse
+	c.Assert(json.Unmarshal(body, &errResp), qt.IsNil)
+	c.Assert(errResp.Code, qt.Equals, CodeUserAlreadyContributed)
+	bob := login(cl2, "bob")
+	c.Assert(bob.IDToken.Identity(), qt.Equals, "git|1214111|bob")
+}
+
+func TestPersistStateError(t *testing.T) {
+	c := qt.New(t)
+	// the directory of the State does not exist
+	path := filepath.Join(c.TempDir(), "missing", "state.json")
+	srv, ts := newTestServer(c, Config{StatePath: path})
+	cl := client.NewClient(ts.URL)
+	prevState := srv.State()
+
+	alice, err := srv.Login(client.IDToken{Provider: "Github", Sub: "1", Nickname: "alice"})
+	c.Assert(err, qt.IsNil)
+	prevBatch, _, err := cl.PostTryContribute(alice)
+	c.Assert(err, qt.IsNil)
+	newBatch, err := prevBatch.ContributeWithIdentity([]byte(testRandomness), "git|1|alice")
+	c.Assert(err, qt.IsNil)
+	_, err = cl.PostContribute(alice, newBatch)
+	c.Assert(err, qt.ErrorMatches, "unexpected http code: 500")
+
+	// the contribution is not accepted, and alice keeps the slot
+	c.Assert(srv.State(), qt.Equals, prevState)
+	_, st, err := cl.PostTryContribute(alice)
+	c.Assert(err, qt.IsNil)
+	c.Assert(st, qt.Equals, client.StatusProceed)
+}
@@ -0,0 +1,82 @@
+package sequencer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+	"github.com/arnaucube/eth-kzg-ceremony-alt/client"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	bls12381 "github.com/kilic/bls12-381"
+	"golang.org/x/crypto/sha3"
+)
+
+// Receipt is the content of the receipt string of client.MsgContributeReceipt,
+// as issued by the official sequencer
+type Receipt struct {
+	// Identity is the participant identity, eg. "git|1234|username"
+	Identity string `json:"identity"`
+	// Witness contains the PotPubKeys of the contribution (0x prefixed
+	// compressed hex), one for each Transcript
+	Witness []string `json:"witness"`
+}
+
+// newReceipt returns the receipt of the BatchContribution, signed with the
+// given key
+func newReceipt(key *secp256k1.PrivateKey, identity string,
+	bc *kzgceremony.BatchContribution) (*client.MsgContributeReceipt, error) {
+	g2 := bls12381.NewG2()
+	receipt := Receipt{Identity: identity,
+		Witness: make([]string, len(bc.Contributions))}
+	for i, c := range bc.Contributions {
+		receipt.Witness[i] = "0x" + hex.EncodeToString(g2.ToCompressed(c.PotPubKey))
+	}
+	b, err := json.Marshal(receipt)
+	if err != nil {
+		return nil, err
+	}
+	compact := ecdsa.SignCompact(key, personalMessageHash(b), false)
+	// compact signature is v || r || s, while Ethereum uses r || s || v
+	sig := append(compact[1:], compact[0])
+	return &client.MsgContributeReceipt{
+		Receipt:   string(b),
+		Signature: "0x" + hex.EncodeToString(sig),
+	}, nil
+}
+
+// RecoverReceiptSigner returns the Ethereum address (0x prefixed lower case
+// hex) of the key that signed the receipt, which is the sequencer_address of
+// the sequencer that issued it
+func RecoverReceiptSigner(msg *client.MsgContributeReceipt) (string, error) {
+	sig, err := hex.DecodeString(strings.TrimPrefix(msg.Signature, "0x"))
+	if err != nil {
+		return "", err
+	}
+	if len(sig) != 65 {
+		return "", fmt.Errorf("invalid ECDSA signature length: %d", len(sig))
+	}
+	v := sig[64]
+	if v < 27 {
+		v += 27
+	}
+	compact := append([]byte{v}, sig[:64]...)
+	pk, _, err := ecdsa.RecoverCompact(compact,
+		personalMessageHash([]byte(msg.Receipt)))
+	if err != nil {
+		return "", err
+	}
+	return kzgceremony.EthAddress(pk), nil
+}
+
+// personalMessageHash returns the EIP-191 hash of the message, as signed by
+// the Ethereum personal_sign method
+func personalMessageHash(msg []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	_, _ = h.Write([]byte("\x19Ethereum Signed Message:\n" + strconv.Itoa(len(msg))))
+	_, _ = h.Write(msg)
+	return h.Sum(nil)
+}
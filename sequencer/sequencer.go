@@ -0,0 +1,411 @@
+// Package sequencer implements an in-process stand-in of the official
+// Ethereum KZG Ceremony sequencer
+// (https://github.com/ethereum/kzg-ceremony-sequencer), serving the same HTTP
+// API, JSON messages and error codes. It holds a real State, verifying each
+// received BatchContribution against it before updating it, and uses a fake
+// auth provider instead of the Github and Ethereum OAuth flows.
+//
+// The Server implements http.Handler, so it can be used with httptest:
+//
+//	srv, err := sequencer.New(state, sequencer.Config{})
+//	ts := httptest.NewServer(srv)
+//	c := client.NewClient(ts.URL)
+package sequencer
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+	"github.com/arnaucube/eth-kzg-ceremony-alt/client"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// Error codes of the ErrorResponse messages, as used by the official
+// sequencer
+const (
+	CodeUnknownSessionID              = "TryContributeError::UnknownSessionId"
+	CodeRateLimited                   = "TryContributeError::RateLimited"
+	CodeAnotherContributionInProgress = "TryContributeError::AnotherContributionInProgress"
+	CodeNotUsersTurn                  = "ContributeError::NotUsersTurn"
+	CodeInvalidContribution           = "ContributeError::InvalidContribution"
+	CodeInvalidSessionID              = "SessionError::InvalidSessionId"
+	CodeInvalidCsrfToken              = "AuthError::InvalidCsrfToken"
+	CodeInvalidAuthCode               = "AuthError::InvalidAuthCode"
+	CodeUserAlreadyContributed        = "AuthError::UserAlreadyContributed"
+)
+
+// ErrorResponse is the body of the error answers of the sequencer
+type ErrorResponse struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// Config contains the optional parameters of the Server
+type Config struct {
+	// Key is the secp256k1 key used to sign the contribution receipts, its
+	// address is reported as sequencer_address. A new random key is used
+	// when nil.
+	Key *secp256k1.PrivateKey
+	// Mode is the VerificationMode used to check the powers of tau
+	// structure of the received contributions
+	Mode kzgceremony.VerificationMode
+	// TryContributeInterval is the minimum time between two try_contribute
+	// calls of the same session, earlier calls are rate limited. Zero
+	// disables the rate limit.
+	TryContributeInterval time.Duration
+	// ComputeDeadline is the time that the participant holding the
+	// contribution slot has to upload its contribution, after which the
+	// slot is released. Zero disables the deadline.
+	ComputeDeadline time.Duration
+	// LobbyCheckinTimeout is the time after which the participants of the
+	// lobby that did not call try_contribute again are removed from the
+	// lobby. Zero keeps them forever.
+	LobbyCheckinTimeout time.Duration
+	// StatePath is the file where the State is stored (see WriteStateFile)
+	// after every accepted contribution, before answering with the
+	// receipt. The State is only kept in memory when empty.
+	StatePath string
+}
+
+// session is an authenticated participant
+type session struct {
+	token   client.IDToken
+	lastTry time.Time
+}
+
+// Server is the stand-in sequencer. It is safe for concurrent use, while the
+// received contributions are processed one at a time.
+type Server struct {
+	cfg     Config
+	address string
+	mux     *http.ServeMux
+
+	mu    sync.Mutex
+	state *kzgceremony.State
+	// stateJSON is the JSON encoding of state served by
+	// /info/current_state
+	stateJSON []byte
+	// uploading is set while a contribution is received and verified.
+	// The decoding and verification use package-level instances of
+	// kzgceremony that are not safe for concurrent use, so a second upload
+	// is rejected until the first one is done.
+	uploading bool
+	sessions  map[string]*session
+	// lobby contains the sessions waiting for their turn
+	lobby map[string]bool
+	// current is the session id of the participant holding the
+	// contribution slot until deadline, empty when nobody is contributing
+	current  string
+	deadline time.Time
+	// csrf contains the issued auth states not used yet
+	csrf map[string]bool
+	// githubID contains the numeric Github id given to each nickname, and
+	// lastGithubID the highest one
+	githubID     map[string]uint64
+	lastGithubID uint64
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// New returns a Server that continues the ceremony from the given State. The
+// State is not modified, each accepted contribution produces a new State.
+func New(state *kzgceremony.State, cfg Config) (*Server, error) {
+	if cfg.Key == nil {
+		key, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Key = key
+	}
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	githubID, lastGithubID := githubIDs(state.ParticipantIDs)
+	s := &Server{
+		cfg:          cfg,
+		address:      kzgceremony.EthAddress(cfg.Key.PubKey()),
+		state:        state,
+		stateJSON:    stateJSON,
+		sessions:     make(map[string]*session),
+		lobby:        make(map[string]bool),
+		csrf:         make(map[string]bool),
+		githubID:     githubID,
+		lastGithubID: lastGithubID,
+		now:          time.Now,
+	}
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/info/status", s.handleStatus)
+	s.mux.HandleFunc("/info/current_state", s.handleCurrentState)
+	s.mux.HandleFunc("/auth/request_link", s.handleRequestLink)
+	s.mux.HandleFunc("/auth/callback/github", s.handleAuthCallback)
+	s.mux.HandleFunc("/auth/callback/eth", s.handleAuthCallback)
+	s.mux.HandleFunc("/lobby/try_contribute", s.handleTryContribute)
+	s.mux.HandleFunc("/contribute", s.handleContribute)
+	s.mux.HandleFunc("/contribution/abort", s.handleAbort)
+	return s, nil
+}
+
+// ServeHTTP implements the http.Handler interface
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Address returns the Ethereum address of the key that signs the receipts
+func (s *Server) Address() string {
+	return s.address
+}
+
+// State returns the current State of the ceremony, which must not be
+// modified
+func (s *Server) State() *kzgceremony.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	s.mu.Lock()
+	s.expire()
+	msg := client.MsgStatus{
+		LobbySize:        uint64(len(s.lobby)),
+		NumContributions: numContributions(s.state),
+		SequencerAddress: s.address,
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, msg)
+}
+
+func (s *Server) handleCurrentState(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	s.mu.Lock()
+	b := s.stateJSON
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+func (s *Server) handleTryContribute(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	id := bearer(r)
+
+	s.mu.Lock()
+	s.expire()
+	sess, ok := s.sessions[id]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusUnauthorized, CodeUnknownSessionID,
+			"unknown session id")
+		return
+	}
+	now := s.now()
+	if s.cfg.TryContributeInterval > 0 && !sess.lastTry.IsZero() &&
+		now.Sub(sess.lastTry) < s.cfg.TryContributeInterval {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, CodeRateLimited,
+			"call came too early. rate limited")
+		return
+	}
+	sess.lastTry = now
+	if s.current != "" && s.current != id {
+		s.lobby[id] = true
+		s.mu.Unlock()
+		// the official sequencer answers with a 200 code in this case
+		writeError(w, http.StatusOK, CodeAnotherContributionInProgress,
+			"another contribution in progress")
+		return
+	}
+	delete(s.lobby, id)
+	if s.current != id {
+		s.current = id
+		s.deadline = now.Add(s.cfg.ComputeDeadline)
+	}
+	bc := s.state.NextBatch()
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	// the status code is already sent when the encoding fails, so the
+	// client detects the error when parsing the truncated body
+	_ = kzgceremony.NewBatchContributionEncoder(w).Encode(bc)
+}
+
+func (s *Server) handleContribute(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	id := bearer(r)
+
+	s.mu.Lock()
+	s.expire()
+	sess, ok := s.sessions[id]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusUnauthorized, CodeInvalidSessionID,
+			"unknown session id")
+		return
+	}
+	if s.current != id {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, CodeNotUsersTurn,
+			"not your turn to participate")
+		return
+	}
+	if s.uploading {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, CodeAnotherContributionInProgress,
+			"another contribution in progress")
+		return
+	}
+	s.uploading = true
+	prev := s.state
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.uploading = false
+		s.mu.Unlock()
+	}()
+
+	bc := &kzgceremony.BatchContribution{}
+	if err := json.NewDecoder(r.Body).Decode(bc); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidContribution,
+			"contribution invalid: "+err.Error())
+		return
+	}
+	identity := sess.token.Identity()
+	// the verification is done without holding the lock, as only the
+	// current participant can update the State
+	next, err := prev.ApplyWithMode(bc, identity, s.cfg.Mode)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidContribution,
+			"contribution invalid: "+err.Error())
+		return
+	}
+	nextJSON, err := json.Marshal(next)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	// the deadline is not checked again, as the contribution arrived on
+	// time, but the slot could have been aborted while verifying
+	if s.current != id || s.state != prev {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, CodeNotUsersTurn,
+			"not your turn to participate")
+		return
+	}
+	if s.cfg.StatePath != "" {
+		// the State is stored before accepting the contribution, so
+		// that the receipts are only issued for persisted contributions
+		if err := WriteStateFile(s.cfg.StatePath, next); err != nil {
+			s.mu.Unlock()
+			http.Error(w, "storing the state: "+err.Error(),
+				http.StatusInternalServerError)
+			return
+		}
+	}
+	s.state = next
+	s.stateJSON = nextJSON
+	s.current = ""
+	delete(s.sessions, id)
+	s.mu.Unlock()
+
+	receipt, err := newReceipt(s.cfg.Key, identity, bc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, receipt)
+}
+
+func (s *Server) handleAbort(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	id := bearer(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	if _, ok := s.sessions[id]; !ok {
+		writeError(w, http.StatusUnauthorized, CodeInvalidSessionID,
+			"unknown session id")
+		return
+	}
+	if s.current != id {
+		writeError(w, http.StatusBadRequest, CodeNotUsersTurn,
+			"not your turn to participate")
+		return
+	}
+	s.current = ""
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// expire releases the contribution slot once its deadline has passed, and
+// removes from the lobby the participants that did not check in on time. It
+// must be called with the lock held.
+func (s *Server) expire() {
+	now := s.now()
+	if s.current != "" && s.cfg.ComputeDeadline > 0 && now.After(s.deadline) {
+		s.current = ""
+	}
+	if s.cfg.LobbyCheckinTimeout > 0 {
+		for id := range s.lobby {
+			if now.Sub(s.sessions[id].lastTry) > s.cfg.LobbyCheckinTimeout {
+				delete(s.lobby, id)
+			}
+		}
+	}
+}
+
+// numContributions returns the number of contributions of the State,
+// excluding the genesis entry
+func numContributions(state *kzgceremony.State) uint64 {
+	if len(state.ParticipantIDs) == 0 {
+		return 0
+	}
+	return uint64(len(state.ParticipantIDs) - 1)
+}
+
+// bearer returns the session id of the Authorization header
+func bearer(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// allowMethod answers with 405 (Method Not Allowed) when the method of the
+// request is not the given one
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, code int, errCode, msg string) {
+	writeJSON(w, code, ErrorResponse{Code: errCode, Error: msg})
+}
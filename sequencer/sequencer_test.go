@@ -0,0 +1,439 @@
+package sequencer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	kzgceremony "github.com/arnaucube/eth-kzg-ceremony-alt"
+	"github.com/arnaucube/eth-kzg-ceremony-alt/client"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+const testRandomness = "1111111111111111111111111111111111111111111111111111111111111111"
+
+// newTestState returns a State with four Transcripts and a single
+// contribution, done by "git|1214109|kustosz"
+func newTestState(c *qt.C) *kzgceremony.State {
+	s := kzgceremony.NewEmptyState([]int{16, 8, 8, 4}, []int{4, 4, 4, 4})
+	s, err := s.ContributeWithIdentity([]byte(testRandomness), "git|1214109|kustosz")
+	c.Assert(err, qt.IsNil)
+	return s
+}
+
+func newTestServer(c *qt.C, cfg Config) (*Server, *httptest.Server) {
+	srv, err := New(newTestState(c), cfg)
+	c.Assert(err, qt.IsNil)
+	ts := httptest.NewServer(srv)
+	c.Cleanup(ts.Close)
+	// PostTryContribute stores the received batch in the current directory
+	c.Cleanup(func() { _ = os.Remove("prevBatchContribution.json") })
+	return srv, ts
+}
+
+// authCallback completes the fake auth flow with the given code
+func authCallback(c *qt.C, authURL, code string) (*http.Response, []byte) {
+	resp, err := http.Get(authURL + "&code=" + code)
+	c.Assert(err, qt.IsNil)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, qt.IsNil)
+	return resp, body
+}
+
+func post(c *qt.C, url, sessionID string, body []byte) (int, ErrorResponse) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	c.Assert(err, qt.IsNil)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, qt.IsNil)
+	defer resp.Body.Close()
+	var errResp ErrorResponse
+	_ = json.NewDecoder(resp.Body).Decode(&errResp)
+	return resp.StatusCode, errResp
+}
+
+func TestContributionFlow(t *testing.T) {
+	c := qt.New(t)
+	srv, ts := newTestServer(c, Config{})
+	cl := client.NewClient(ts.URL)
+
+	status, err := cl.GetCurrentStatus()
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.LobbySize, qt.Equals, uint64(0))
+	c.Assert(status.NumContributions, qt.Equals, uint64(1))
+	c.Assert(status.SequencerAddress, qt.Equals, srv.Address())
+
+	link, err := cl.GetRequestLink()
+	c.Assert(err, qt.IsNil)
+	resp, body := authCallback(c, link.GithubAuthURL, "alice")
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusOK)
+	var alice client.MsgAuthCallback
+	c.Assert(json.Unmarshal(body, &alice), qt.IsNil)
+	// the numeric ids continue from the ones of the State
+	c.Assert(alice.IDToken.Identity(), qt.Equals, "git|1214110|alice")
+
+	bob, err := srv.Login(client.IDToken{Provider: "Github", Sub: "2", Nickname: "bob"})
+	c.Assert(err, qt.IsNil)
+
+	// alice gets the slot, bob waits in the lobby
+	prevBatch, st, err := cl.PostTryContribute(alice.SessionID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(st, qt.Equals, client.StatusProceed)
+	c.Assert(len(prevBatch.Contributions), qt.Equals, 4)
+	_, st, err = cl.PostTryContribute(bob)
+	c.Assert(err, qt.ErrorMatches, "another contribution in progress")
+	c.Assert(st, qt.Equals, client.StatusWait)
+	status, err = cl.GetCurrentStatus()
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.LobbySize, qt.Equals, uint64(1))
+
+	newBatch, err := prevBatch.ContributeWithIdentity([]byte(testRandomness),
+		alice.IDToken.Identity())
+	c.Assert(err, qt.IsNil)
+
+	// bob can not contribute nor abort during the turn of alice
+	_, err = cl.PostContribute(bob, newBatch)
+	c.Assert(err, qt.ErrorMatches, "invalid request: .*"+CodeNotUsersTurn+".*")
+	code, errResp := post(c, ts.URL+"/contribution/abort", bob, nil)
+	c.Assert(code, qt.Equals, http.StatusBadRequest)
+	c.Assert(errResp.Code, qt.Equals, CodeNotUsersTurn)
+
+	receipt, err := cl.PostContribute(alice.SessionID, newBatch)
+	c.Assert(err, qt.IsNil)
+	signer, err := RecoverReceiptSigner(receipt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(signer, qt.Equals, srv.Address())
+	var r Receipt
+	c.Assert(json.Unmarshal([]byte(receipt.Receipt), &r), qt.IsNil)
+	c.Assert(r.Identity, qt.Equals, "git|1214110|alice")
+	c.Assert(len(r.Witness), qt.Equals, 4)
+
+	state, err := cl.GetCurrentState()
+	c.Assert(err, qt.IsNil)
+	c.Assert(state.ParticipantIDs, qt.DeepEquals,
+		[]string{"", "git|1214109|kustosz", "git|1214110|alice"})
+	c.Assert(kzgceremony.VerifyState(state), qt.IsTrue)
+	history, err := kzgceremony.VerifyStateHistory(state, kzgceremony.VerifyBatch)
+	c.Assert(err, qt.IsNil)
+	c.Assert(history.Valid(), qt.IsTrue)
+	reports, err := kzgceremony.VerifyBLSSignatures(state)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reports[1].Status, qt.Equals, kzgceremony.SignatureValid)
+
+	// the session of alice is closed after contributing
+	_, st, err = cl.PostTryContribute(alice.SessionID)
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(st, qt.Equals, client.StatusReauth)
+	link, err = cl.GetRequestLink()
+	c.Assert(err, qt.IsNil)
+	resp, body = authCallback(c, link.GithubAuthURL, "alice")
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusBadRequest)
+	c.Assert(json.Unmarshal(body, &errResp), qt.IsNil)
+	c.Assert(errResp.Code, qt.Equals, CodeUserAlreadyContributed)
+
+	// bob gets the batch of the new State, and aborts
+	bobBatch, st, err := cl.PostTryContribute(bob)
+	c.Assert(err, qt.IsNil)
+	c.Assert(st, qt.Equals, client.StatusProceed)
+	c.Assert(bobBatch.Contributions[0].PowersOfTau.G1Powers[1],
+		qt.DeepEquals, state.Transcripts[0].PowersOfTau.G1Powers[1])
+	status, err = cl.GetCurrentStatus()
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.LobbySize, qt.Equals, uint64(0))
+	c.Assert(status.NumContributions, qt.Equals, uint64(2))
+	_, err = cl.PostAbortContribution(bob)
+	c.Assert(err, qt.IsNil)
+	_, err = cl.PostAbortContribution(bob)
+	c.Assert(err, qt.ErrorMatches, "invalid request")
+}
+
+func TestInvalidContribution(t *testing.T) {
+	c := qt.New(t)
+	srv, ts := newTestServer(c, Config{Mode: kzgceremony.VerifyBatch})
+	cl := client.NewClient(ts.URL)
+	prevState := srv.State()
+
+	sessionID, err := srv.Login(client.IDToken{Provider: "Github", Sub: "1", Nickname: "alice"})
+	c.Assert(err, qt.IsNil)
+	prevBatch, _, err := cl.PostTryContribute(sessionID)
+	c.Assert(err, qt.IsNil)
+	newBatch, err := prevBatch.ContributeWithIdentity([]byte(testRandomness),
+		"git|1|alice")
+	c.Assert(err, qt.IsNil)
+
+	contribute := func(bc *kzgceremony.BatchContribution) (int, ErrorResponse) {
+		b, err := json.Marshal(bc)
+		c.Assert(err, qt.IsNil)
+		return post(c, ts.URL+"/contribute", sessionID, b)
+	}
+
+	// the powers of tau structure is broken
+	broken := *newBatch
+	broken.Contributions = append([]kzgceremony.Contribution(nil), newBatch.Contributions...)
+	srs := *broken.Contributions[2].PowersOfTau
+	srs.G1Powers = append([]*bls12381.PointG1(nil), srs.G1Powers...)
+	srs.G1Powers[3], srs.G1Powers[4] = srs.G1Powers[4], srs.G1Powers[3]
+	broken.Contributions[2].PowersOfTau = &srs
+	code, errResp := contribute(&broken)
+	c.Assert(code, qt.Equals, http.StatusBadRequest)
+	c.Assert(errResp.Code, qt.Equals, CodeInvalidContribution)
+	c.Assert(errResp.Error, qt.Matches, "contribution invalid: verification failed:"+
+		" G1 powers structure check, transcript 2.*")
+
+	// the contribution is not computed from the given batch
+	other, err := prevBatch.ContributeWithIdentity([]byte(testRandomness+"2"),
+		"git|1|alice")
+	c.Assert(err, qt.IsNil)
+	broken.Contributions[2] = other.Contributions[2]
+	broken.Contributions[2].PotPubKey = newBatch.Contributions[2].PotPubKey
+	code, errResp = contribute(&broken)
+	c.Assert(code, qt.Equals, http.StatusBadRequest)
+	c.Assert(errResp.Error, qt.Matches, ".*pot pubkey pairing check, transcript 2.*")
+
+	// the BLS signature is not of the participant identity
+	signedByOther, err := prevBatch.ContributeWithIdentity([]byte(testRandomness),
+		"git|2|bob")
+	c.Assert(err, qt.IsNil)
+	code, errResp = contribute(signedByOther)
+	c.Assert(code, qt.Equals, http.StatusBadRequest)
+	c.Assert(errResp.Error, qt.Matches, ".*bls signature check.*")
+
+	// wrong number of contributions
+	short := *newBatch
+	short.Contributions = newBatch.Contributions[:3]
+	code, errResp = contribute(&short)
+	c.Assert(code, qt.Equals, http.StatusBadRequest)
+	c.Assert(errResp.Error, qt.Equals, "contribution invalid: verification failed:"+
+		" size check, Contributions: expected 4 contributions, got 3")
+
+	// unparseable body
+	code, errResp = post(c, ts.URL+"/contribute", sessionID, []byte("{"))
+	c.Assert(code, qt.Equals, http.StatusBadRequest)
+	c.Assert(errResp.Code, qt.Equals, CodeInvalidContribution)
+
+	// the State did not change, and the participant keeps its turn
+	c.Assert(srv.State(), qt.Equals, prevState)
+	code, _ = contribute(newBatch)
+	c.Assert(code, qt.Equals, http.StatusOK)
+	c.Assert(srv.State(), qt.Not(qt.Equals), prevState)
+	c.Assert(len(prevState.ParticipantIDs), qt.Equals, 2)
+}
+
+func TestEthereumParticipant(t *testing.T) {
+	c := qt.New(t)
+	_, ts := newTestServer(c, Config{})
+	cl := client.NewClient(ts.URL)
+
+	key, err := secp256k1.GeneratePrivateKey()
+	c.Assert(err, qt.IsNil)
+	otherKey, err := secp256k1.GeneratePrivateKey()
+	c.Assert(err, qt.IsNil)
+	address := kzgceremony.EthAddress(key.PubKey())
+
+	link, err := cl.GetRequestLink()
+	c.Assert(err, qt.IsNil)
+	_, body := authCallback(c, link.EthAuthURL, address)
+	var auth client.MsgAuthCallback
+	c.Assert(json.Unmarshal(body, &auth), qt.IsNil)
+	c.Assert(auth.IDToken.Identity(), qt.Equals, "eth|"+address)
+
+	prevBatch, _, err := cl.PostTryContribute(auth.SessionID)
+	c.Assert(err, qt.IsNil)
+	newBatch, err := prevBatch.ContributeWithIdentity([]byte(testRandomness),
+		auth.IDToken.Identity())
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(newBatch.SignECDSA(otherKey), qt.IsNil)
+	_, err = cl.PostContribute(auth.SessionID, newBatch)
+	c.Assert(err, qt.ErrorMatches, "invalid request: .*"+CodeInvalidContribution+".*")
+
+	c.Assert(newBatch.SignECDSA(key), qt.IsNil)
+	_, err = cl.PostContribute(auth.SessionID, newBatch)
+	c.Assert(err, qt.IsNil)
+
+	state, err := cl.GetCurrentState()
+	c.Assert(err, qt.IsNil)
+	reports, err := kzgceremony.VerifyECDSASignatures(state)
+	c.Assert(err, qt.IsNil)
+	last := reports[len(reports)-1]
+	c.Assert(last.ParticipantID, qt.Equals, "eth|"+address)
+	c.Assert(last.Status, qt.Equals, kzgceremony.SignatureValid)
+}
+
+func TestRequestErrors(t *testing.T) {
+	c := qt.New(t)
+	srv, ts := newTestServer(c, Config{TryContributeInterval: time.Hour})
+	cl := client.NewClient(ts.URL)
+
+	// unknown sessions
+	code, errResp := post(c, ts.URL+"/lobby/try_contribute", "unknown", nil)
+	c.Assert(code, qt.Equals, http.StatusUnauthorized)
+	c.Assert(errResp.Code, qt.Equals, CodeUnknownSessionID)
+	code, errResp = post(c, ts.URL+"/contribute", "unknown", nil)
+	c.Assert(code, qt.Equals, http.StatusUnauthorized)
+	c.Assert(errResp.Code, qt.Equals, CodeInvalidSessionID)
+	_, err := cl.PostAbortContribution("unknown")
+	c.Assert(err, qt.ErrorMatches, "unkown session id. unauthorized access")
+
+	// rate limit
+	alice, err := srv.Login(client.IDToken{Provider: "Github", Sub: "1", Nickname: "alice"})
+	c.Assert(err, qt.IsNil)
+	bob, err := srv.Login(client.IDToken{Provider: "Github", Sub: "2", Nickname: "bob"})
+	c.Assert(err, qt.IsNil)
+	_, st, err := cl.PostTryContribute(alice)
+	c.Assert(err, qt.IsNil)
+	c.Assert(st, qt.Equals, client.StatusProceed)
+	_, st, _ = cl.PostTryContribute(bob)
+	c.Assert(st, qt.Equals, client.StatusWait)
+	code, errResp = post(c, ts.URL+"/lobby/try_contribute", bob, nil)
+	c.Assert(code, qt.Equals, http.StatusBadRequest)
+	c.Assert(errResp.Code, qt.Equals, CodeRateLimited)
+
+	// auth callbacks
+	link, err := cl.GetRequestLink()
+	c.Assert(err, qt.IsNil)
+	resp, body := authCallback(c, ts.URL+"/auth/callback/github?state=wrong", "carol")
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusBadRequest)
+	c.Assert(json.Unmarshal(body, &errResp), qt.IsNil)
+	c.Assert(errResp.Code, qt.Equals, CodeInvalidCsrfToken)
+	resp, body = authCallback(c, link.GithubAuthURL, "")
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusBadRequest)
+	c.Assert(json.Unmarshal(body, &errResp), qt.IsNil)
+	c.Assert(errResp.Code, qt.Equals, CodeInvalidAuthCode)
+	_, err = srv.Login(client.IDToken{Provider: "Github", Sub: "1214109", Nickname: "kustosz"})
+	c.Assert(err, qt.ErrorMatches, "user has already contributed")
+
+	// methods
+	resp, err = http.Get(ts.URL + "/contribute")
+	c.Assert(err, qt.IsNil)
+	_ = resp.Body.Close()
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusMethodNotAllowed)
+}
+
+func TestSlotLeasing(t *testing.T) {
+	c := qt.New(t)
+	srv, ts := newTestServer(c, Config{ComputeDeadline: 3 * time.Minute,
+		LobbyCheckinTimeout: time.Minute})
+	cl := client.NewClient(ts.URL)
+	now := time.Now()
+	srv.now = func() time.Time { return now }
+
+	alice, err := srv.Login(client.IDToken{Provider: "Github", Sub: "1", Nickname: "alice"})
+	c.Assert(err, qt.IsNil)
+	bob, err := srv.Login(client.IDToken{Provider: "Github", Sub: "2", Nickname: "bob"})
+	c.Assert(err, qt.IsNil)
+	carol, err := srv.Login(client.IDToken{Provider: "Github", Sub: "3", Nickname: "carol"})
+	c.Assert(err, qt.IsNil)
+
+	prevBatch, st, err := cl.PostTryContribute(alice)
+	c.Assert(err, qt.IsNil)
+	c.Assert(st, qt.Equals, client.StatusProceed)
+	_, st, _ = cl.PostTryContribute(bob)
+	c.Assert(st, qt.Equals, client.StatusWait)
+	_, st, _ = cl.PostTryContribute(carol)
+	c.Assert(st, qt.Equals, client.StatusWait)
+	status, err := cl.GetCurrentStatus()
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.LobbySize, qt.Equals, uint64(2))
+
+	// carol does not check in on time and leaves the lobby
+	now = now.Add(50 * time.Second)
+	_, st, _ = cl.PostTryContribute(bob)
+	c.Assert(st, qt.Equals, client.StatusWait)
+	now = now.Add(20 * time.Second)
+	status, err = cl.GetCurrentStatus()
+	c.Assert(err, qt.IsNil)
+	c.Assert(status.LobbySize, qt.Equals, uint64(1))
+
+	// alice misses the deadline, so the slot goes to bob, and the
+	// contribution of alice is rejected
+	newBatch, err := prevBatch.ContributeWithIdentity([]byte(testRandomness), "git|1|alice")
+	c.Assert(err, qt.IsNil)
+	now = now.Add(2 * time.Minute)
+	_, st, err = cl.PostTryContribute(bob)
+	c.Assert(err, qt.IsNil)
+	c.Assert(st, qt.Equals, client.StatusProceed)
+	_, err = cl.PostContribute(alice, newBatch)
+	c.Assert(err, qt.ErrorMatches, "invalid request: .*"+CodeNotUsersTurn+".*")
+
+	// bob contributes on time, a bit before the deadline
+	now = now.Add(3*time.Minute - time.Second)
+	bobBatch, err := prevBatch.ContributeWithIdentity([]byte(testRandomness), "git|2|bob")
+	c.Assert(err, qt.IsNil)
+	_, err = cl.PostContribute(bob, bobBatch)
+	c.Assert(err, qt.IsNil)
+	c.Assert(srv.State().ParticipantIDs[2], qt.Equals, "git|2|bob")
+}
+
+func TestConcurrentUploads(t *testing.T) {
+	c := qt.New(t)
+	srv, ts := newTestServer(c, Config{})
+	cl := client.NewClient(ts.URL)
+
+	alice, err := srv.Login(client.IDToken{Provider: "Github", Sub: "1", Nickname: "alice"})
+	c.Assert(err, qt.IsNil)
+	prevBatch, st, err := cl.PostTryContribute(alice)
+	c.Assert(err, qt.IsNil)
+	c.Assert(st, qt.Equals, client.StatusProceed)
+	newBatch, err := prevBatch.ContributeWithIdentity([]byte(testRandomness), "git|1|alice")
+	c.Assert(err, qt.IsNil)
+	b, err := json.Marshal(newBatch)
+	c.Assert(err, qt.IsNil)
+
+	// the first upload sends half of its body and waits
+	pr, pw := io.Pipe()
+	req, err := http.NewRequest("POST", ts.URL+"/contribute", pr)
+	c.Assert(err, qt.IsNil)
+	req.Header.Set("Authorization", "Bearer "+alice)
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	first := make(chan result, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		first <- result{resp, err}
+	}()
+	_, err = pw.Write(b[:len(b)/2])
+	c.Assert(err, qt.IsNil)
+	for {
+		srv.mu.Lock()
+		uploading := srv.uploading
+		srv.mu.Unlock()
+		if uploading {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// a retry of the same session is rejected, while the state is still
+	// served
+	_, err = cl.PostContribute(alice, newBatch)
+	c.Assert(err, qt.ErrorMatches, "invalid request: .*"+CodeAnotherContributionInProgress+".*")
+	state, err := cl.GetCurrentState()
+	c.Assert(err, qt.IsNil)
+	c.Assert(state.ParticipantIDs, qt.HasLen, 2)
+
+	_, err = pw.Write(b[len(b)/2:])
+	c.Assert(err, qt.IsNil)
+	c.Assert(pw.Close(), qt.IsNil)
+	r := <-first
+	c.Assert(r.err, qt.IsNil)
+	_ = r.resp.Body.Close()
+	c.Assert(r.resp.StatusCode, qt.Equals, http.StatusOK)
+
+	state, err = cl.GetCurrentState()
+	c.Assert(err, qt.IsNil)
+	c.Assert(state.ParticipantIDs, qt.HasLen, 3)
+	c.Assert(state.ParticipantIDs[2], qt.Equals, "git|1|alice")
+}
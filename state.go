@@ -0,0 +1,193 @@
+package kzgceremony
+
+import (
+	"fmt"
+	"strings"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// NextBatch returns the BatchContribution that the Sequencer hands to the
+// next participant, which contains the current PowersOfTau of each
+// Transcript and the generator as PotPubKey. The points are shared with the
+// State.
+func (s *State) NextBatch() *BatchContribution {
+	bc := &BatchContribution{}
+	bc.Contributions = make([]Contribution, len(s.Transcripts))
+	for i, t := range s.Transcripts {
+		bc.Contributions[i] = Contribution{
+			NumG1Powers: t.NumG1Powers,
+			NumG2Powers: t.NumG2Powers,
+			PowersOfTau: t.PowersOfTau,
+			PotPubKey:   g2.One(),
+		}
+	}
+	return bc
+}
+
+// Apply checks the BatchContribution of the given participant (eg.
+// "git|1234|username" or "eth|0x...") against the State, and returns the next
+// State, which is the step done by the Sequencer for each received
+// contribution. The State is not modified.
+//
+// For each Contribution, the new PowersOfTau must be computed from the ones
+// of the matching Transcript, proven by the PotPubKey, and follow the powers
+// of tau structure. The optional BLS signatures must sign the participant
+// identity in all the Contributions, and the optional ECDSA signature must
+// sign the PotPubKeys with the key of the Ethereum address of the
+// participant. The participant must not be empty, nor be already in the
+// ParticipantIDs. It returns a *VerificationError describing the first check
+// that failed.
+func (s *State) Apply(bc *BatchContribution, participant string) (*State, error) {
+	return s.ApplyWithMode(bc, participant, VerifyStrict)
+}
+
+// ApplyWithMode acts as Apply, checking the powers of tau structure of each
+// Contribution with the given VerificationMode
+func (s *State) ApplyWithMode(bc *BatchContribution, participant string,
+	mode VerificationMode) (*State, error) {
+	if err := s.checkWitnessLengths(); err != nil {
+		return nil, err
+	}
+	if err := s.checkParticipant(participant); err != nil {
+		return nil, err
+	}
+	err := CheckBatchContributionWithIdentity(s.NextBatch(), bc, participant, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	ns := &State{}
+	ns.Transcripts = make([]Transcript, len(s.Transcripts))
+	for i, t := range s.Transcripts {
+		c := bc.Contributions[i]
+		// the witness arrays are copied, so that the new State does not
+		// share their backing arrays with the previous one
+		w := &Witness{
+			RunningProducts: make([]*bls12381.PointG1, 0, len(t.Witness.RunningProducts)+1),
+			PotPubKeys:      make([]*bls12381.PointG2, 0, len(t.Witness.PotPubKeys)+1),
+			BLSSignatures:   make([]*bls12381.PointG1, 0, len(t.Witness.BLSSignatures)+1),
+		}
+		w.RunningProducts = append(append(w.RunningProducts,
+			t.Witness.RunningProducts...), c.PowersOfTau.G1Powers[1])
+		w.PotPubKeys = append(append(w.PotPubKeys,
+			t.Witness.PotPubKeys...), c.PotPubKey)
+		w.BLSSignatures = append(append(w.BLSSignatures,
+			t.Witness.BLSSignatures...), c.BLSSignature)
+		ns.Transcripts[i] = Transcript{
+			NumG1Powers: t.NumG1Powers,
+			NumG2Powers: t.NumG2Powers,
+			PowersOfTau: c.PowersOfTau,
+			Witness:     w,
+		}
+	}
+	ns.ParticipantIDs = append(append(make([]string, 0, len(s.ParticipantIDs)+1),
+		s.ParticipantIDs...), participant)
+	ns.ParticipantECDSASignatures = append(append(
+		make([]string, 0, len(s.ParticipantECDSASignatures)+1),
+		s.ParticipantECDSASignatures...), bc.ECDSASignature)
+	return ns, nil
+}
+
+// checkWitnessLengths checks that the Witness arrays of each Transcript and
+// the ParticipantECDSASignatures have one entry for each participant
+func (s *State) checkWitnessLengths() error {
+	n := len(s.ParticipantIDs)
+	if len(s.ParticipantECDSASignatures) != n {
+		return &VerificationError{Check: WitnessLengthCheck, Transcript: -1,
+			Element: "ParticipantECDSASignatures", Index: -1,
+			Err: fmt.Errorf("length %d, while there are %d ParticipantIDs",
+				len(s.ParticipantECDSASignatures), n)}
+	}
+	for ti, t := range s.Transcripts {
+		if t.Witness == nil || t.PowersOfTau == nil {
+			return &VerificationError{Check: WitnessLengthCheck,
+				Transcript: ti, Index: -1,
+				Err: fmt.Errorf("missing witness or powers of tau")}
+		}
+		lengths := []struct {
+			element string
+			n       int
+		}{
+			{"RunningProducts", len(t.Witness.RunningProducts)},
+			{"PotPubKeys", len(t.Witness.PotPubKeys)},
+			{"BLSSignatures", len(t.Witness.BLSSignatures)},
+		}
+		for _, l := range lengths {
+			if l.n != n {
+				return &VerificationError{Check: WitnessLengthCheck,
+					Transcript: ti, Element: l.element, Index: -1,
+					Err: fmt.Errorf("length %d, while there are %d ParticipantIDs",
+						l.n, n)}
+			}
+		}
+	}
+	return nil
+}
+
+// checkParticipant checks that the participant identity is not empty and
+// that it is not already in the ParticipantIDs
+func (s *State) checkParticipant(participant string) error {
+	if participant == "" {
+		return &VerificationError{Check: ParticipantCheck, Transcript: -1,
+			Element: "ParticipantIDs", Index: -1,
+			Err: fmt.Errorf("empty participant identity")}
+	}
+	for i, id := range s.ParticipantIDs {
+		if id == participant {
+			return &VerificationError{Check: ParticipantCheck, Transcript: -1,
+				Element: "ParticipantIDs", Index: i,
+				Err: fmt.Errorf("%s has already contributed", participant)}
+		}
+	}
+	return nil
+}
+
+// checkSignatures checks the BLS signatures of the participant identity, which
+// must be present in all the Contributions or in none of them, and the ECDSA
+// signature, which is only accepted from Ethereum participants
+func (bc *BatchContribution) checkSignatures(participant string) error {
+	missing := 0
+	for _, c := range bc.Contributions {
+		if c.BLSSignature == nil {
+			missing++
+		}
+	}
+	if missing > 0 && missing < len(bc.Contributions) {
+		return &VerificationError{Check: BLSSignatureCheck, Transcript: -1,
+			Element: "BLSSignature", Index: -1,
+			Err: fmt.Errorf("signature missing in %d of %d contributions",
+				missing, len(bc.Contributions))}
+	}
+	if missing == 0 && len(bc.Contributions) > 0 {
+		h, err := hashIdentity(participant)
+		if err != nil {
+			return err
+		}
+		pairing := bls12381.NewEngine()
+		for i, c := range bc.Contributions {
+			err := checkBLSSignature(pairing, h, c.BLSSignature, c.PotPubKey)
+			if err != nil {
+				vErr := err.(*VerificationError)
+				vErr.Transcript = i
+				vErr.Element = "BLSSignature"
+				return vErr
+			}
+		}
+	}
+
+	if bc.ECDSASignature == "" {
+		return nil
+	}
+	if !strings.HasPrefix(participant, "eth|") {
+		return &VerificationError{Check: ECDSASignatureCheck, Transcript: -1,
+			Element: "ECDSASignature", Index: -1,
+			Err: fmt.Errorf("ECDSA signature of a non Ethereum participant")}
+	}
+	if err := checkECDSASignature(bc.ECDSASignature,
+		strings.TrimPrefix(participant, "eth|"), bc.potPubKeyEntries()); err != nil {
+		err.Element = "ECDSASignature"
+		return err
+	}
+	return nil
+}
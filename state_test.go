@@ -0,0 +1,214 @@
+package kzgceremony
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+func TestApply(t *testing.T) {
+	c := qt.New(t)
+
+	s := NewEmptyState([]int{16, 8, 8, 4}, []int{4, 4, 4, 4})
+	for i, id := range []string{"git|6507765|arnaucube", "git|42|unsigned", "git|1234|username"} {
+		signed := id
+		if i == 1 {
+			// a contribution without BLS signatures
+			signed = ""
+		}
+		bc, err := s.NextBatch().ContributeWithIdentity(
+			[]byte("1111111111111111111111111111111111111111111111111111111111111111"+id), signed)
+		c.Assert(err, qt.IsNil)
+		ns, err := s.Apply(bc, id)
+		c.Assert(err, qt.IsNil)
+
+		c.Assert(ns.ParticipantIDs, qt.HasLen, i+2)
+		c.Assert(ns.ParticipantIDs[i+1], qt.Equals, id)
+		c.Assert(CheckState(ns, VerifyStrict), qt.IsNil)
+		for ti, t := range ns.Transcripts {
+			c.Assert(t.PowersOfTau, qt.Equals, bc.Contributions[ti].PowersOfTau)
+			c.Assert(t.Witness.PotPubKeys[i+1], qt.Equals, bc.Contributions[ti].PotPubKey)
+		}
+		// the previous State is not modified
+		c.Assert(s.ParticipantIDs, qt.HasLen, i+1)
+		for _, t := range s.Transcripts {
+			c.Assert(t.Witness.RunningProducts, qt.HasLen, i+1)
+		}
+		s = ns
+	}
+
+	history, err := VerifyStateHistory(s, VerifyBatch)
+	c.Assert(err, qt.IsNil)
+	c.Assert(history.Valid(), qt.IsTrue)
+	reports, err := VerifyBLSSignatures(s)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reports[0].Status, qt.Equals, SignatureValid)
+	c.Assert(reports[1].Status, qt.Equals, SignatureMissing)
+	c.Assert(reports[2].Status, qt.Equals, SignatureValid)
+}
+
+func TestNextBatch(t *testing.T) {
+	c := qt.New(t)
+
+	s := NewEmptyState([]int{8, 4}, []int{4, 4})
+	s, err := s.Contribute(
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+
+	bc := s.NextBatch()
+	c.Assert(bc.Contributions, qt.HasLen, 2)
+	c.Assert(bc.ECDSASignature, qt.Equals, "")
+	for i, contribution := range bc.Contributions {
+		c.Assert(contribution.NumG1Powers, qt.Equals, s.Transcripts[i].NumG1Powers)
+		c.Assert(contribution.NumG2Powers, qt.Equals, s.Transcripts[i].NumG2Powers)
+		c.Assert(contribution.PowersOfTau, qt.Equals, s.Transcripts[i].PowersOfTau)
+		c.Assert(g2.Equal(contribution.PotPubKey, g2.One()), qt.IsTrue)
+		c.Assert(contribution.BLSSignature, qt.IsNil)
+	}
+
+	// the PotPubKey and BLSSignature are the ones of the batches of the
+	// official Sequencer
+	j, err := ioutil.ReadFile("batch_contribution_10.json")
+	c.Assert(err, qt.IsNil)
+	official := &BatchContribution{}
+	c.Assert(json.Unmarshal(j, official), qt.IsNil)
+	b, err := bc.MarshalJSON()
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Contains, `"potPubkey":"`+
+		g2PointToString(official.Contributions[0].PotPubKey)+`","blsSignature":""`)
+}
+
+func TestApplyErrors(t *testing.T) {
+	c := qt.New(t)
+
+	s := NewEmptyState([]int{8, 4}, []int{4, 4})
+	s, err := s.Contribute(
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+	id := "eth|0x008aeeda4d805471df9b2a5b0f38a0c3bcba786b"
+	sk, err := EthKeyFromHex("0x7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d")
+	c.Assert(err, qt.IsNil)
+	otherSK, err := EthKeyFromHex("0x1111111111111111111111111111111111111111111111111111111111111111")
+	c.Assert(err, qt.IsNil)
+
+	contribute := func(randomness, identity string) *BatchContribution {
+		bc, err := s.NextBatch().ContributeWithIdentity([]byte(
+			randomness+"2222222222222222222222222222222222222222222222222222222222222222"),
+			identity)
+		c.Assert(err, qt.IsNil)
+		return bc
+	}
+	bc := contribute("", id)
+	c.Assert(bc.SignECDSA(sk), qt.IsNil)
+	_, err = s.Apply(bc, id)
+	c.Assert(err, qt.IsNil)
+
+	copyBatch := func() *BatchContribution {
+		nb := *bc
+		nb.Contributions = append([]Contribution{}, bc.Contributions...)
+		for i := range nb.Contributions {
+			srs := *nb.Contributions[i].PowersOfTau
+			srs.G1Powers = append([]*bls12381.PointG1{}, srs.G1Powers...)
+			srs.G2Powers = append([]*bls12381.PointG2{}, srs.G2Powers...)
+			nb.Contributions[i].PowersOfTau = &srs
+		}
+		return &nb
+	}
+	other := contribute("3", id)
+
+	testCases := []struct {
+		name       string
+		bc         func() *BatchContribution
+		check      VerificationCheck
+		transcript int
+		element    string
+	}{
+		{"number of contributions", func() *BatchContribution {
+			nb := copyBatch()
+			nb.Contributions = nb.Contributions[:1]
+			return nb
+		}, SizeCheck, -1, "Contributions"},
+		{"number of powers", func() *BatchContribution {
+			nb := copyBatch()
+			nb.Contributions[1].NumG1Powers = 8
+			return nb
+		}, SizeCheck, 1, "PowersOfTau"},
+		{"length of powers", func() *BatchContribution {
+			nb := copyBatch()
+			nb.Contributions[1].PowersOfTau.G2Powers =
+				nb.Contributions[1].PowersOfTau.G2Powers[:3]
+			return nb
+		}, SizeCheck, 1, "PowersOfTau"},
+		{"empty pot pubkey", func() *BatchContribution {
+			nb := copyBatch()
+			nb.Contributions[0].PotPubKey = nil
+			return nb
+		}, PointValidityCheck, 0, "PotPubKey"},
+		{"pot pubkey of another contribution", func() *BatchContribution {
+			nb := copyBatch()
+			nb.Contributions[1].PotPubKey = other.Contributions[1].PotPubKey
+			return nb
+		}, PubKeyCheck, 1, "PotPubKey"},
+		{"powers structure", func() *BatchContribution {
+			nb := copyBatch()
+			g1s := nb.Contributions[0].PowersOfTau.G1Powers
+			g1s[5], g1s[6] = g1s[6], g1s[5]
+			return nb
+		}, G1StructureCheck, 0, "G1Powers"},
+		{"partial bls signatures", func() *BatchContribution {
+			nb := copyBatch()
+			nb.Contributions[1].BLSSignature = nil
+			return nb
+		}, BLSSignatureCheck, -1, "BLSSignature"},
+		{"bls signature of another identity", func() *BatchContribution {
+			nb := contribute("", "git|1234|username")
+			return nb
+		}, BLSSignatureCheck, 0, "BLSSignature"},
+		{"ecdsa signature of another key", func() *BatchContribution {
+			nb := copyBatch()
+			c.Assert(nb.SignECDSA(otherSK), qt.IsNil)
+			return nb
+		}, ECDSASignatureCheck, -1, "ECDSASignature"},
+	}
+	for _, tc := range testCases {
+		c.Run(tc.name, func(c *qt.C) {
+			_, err := s.Apply(tc.bc(), id)
+			var vErr *VerificationError
+			c.Assert(errors.As(err, &vErr), qt.IsTrue, qt.Commentf("%v", err))
+			c.Assert(vErr.Check, qt.Equals, tc.check)
+			c.Assert(vErr.Transcript, qt.Equals, tc.transcript)
+			c.Assert(vErr.Element, qt.Equals, tc.element)
+		})
+	}
+
+	// ECDSA signature of a non Ethereum participant
+	nb := contribute("", "git|1234|username")
+	c.Assert(nb.SignECDSA(sk), qt.IsNil)
+	_, err = s.Apply(nb, "git|1234|username")
+	c.Assert(err, qt.ErrorMatches,
+		"verification failed: ecdsa signature check, ECDSASignature: ECDSA signature of a non Ethereum participant")
+
+	// participant identities
+	_, err = s.Apply(contribute("", ""), "")
+	c.Assert(err, qt.ErrorMatches,
+		"verification failed: participant check, ParticipantIDs: empty participant identity")
+	ns, err := s.Apply(bc, id)
+	c.Assert(err, qt.IsNil)
+	_, err = ns.Apply(bc, id)
+	var pErr *VerificationError
+	c.Assert(errors.As(err, &pErr), qt.IsTrue)
+	c.Assert(pErr.Check, qt.Equals, ParticipantCheck)
+	c.Assert(pErr.Index, qt.Equals, 2)
+
+	// inconsistent witness of the State
+	broken := *s
+	broken.ParticipantIDs = broken.ParticipantIDs[:1]
+	_, err = broken.Apply(bc, id)
+	var vErr *VerificationError
+	c.Assert(errors.As(err, &vErr), qt.IsTrue)
+	c.Assert(vErr.Check, qt.Equals, WitnessLengthCheck)
+}
@@ -0,0 +1,438 @@
+package kzgceremony
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// streamChunkSize is the number of point strings that the streaming decoder
+// holds at once, which are then decoded concurrently
+var streamChunkSize = 1024
+
+// TranscriptFunc is called by StateDecoder.Walk with each Transcript as soon
+// as it is decoded, together with its index in the State. Returning an error
+// stops the decoding.
+type TranscriptFunc func(i int, t *Transcript) error
+
+// StateDecoder reads a State in the official Ethereum KZG Ceremony JSON
+// format from an io.Reader, decoding the points one element at a time, so
+// that the string form of the State is never held in memory
+type StateDecoder struct {
+	dec *json.Decoder
+}
+
+// NewStateDecoder returns a StateDecoder that reads from r
+func NewStateDecoder(r io.Reader) *StateDecoder {
+	return &StateDecoder{dec: json.NewDecoder(r)}
+}
+
+// Decode reads the whole State into s
+func (d *StateDecoder) Decode(s *State) error {
+	var transcripts []Transcript
+	header, err := d.Walk(func(i int, t *Transcript) error {
+		transcripts = append(transcripts, *t)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.Transcripts = transcripts
+	s.ParticipantIDs = header.ParticipantIDs
+	s.ParticipantECDSASignatures = header.ParticipantECDSASignatures
+	return nil
+}
+
+// Walk reads the State calling fn with each Transcript, without keeping
+// them, which allows verifying a State holding a single Transcript in memory
+// at a time. The returned State contains the participants data and no
+// Transcripts.
+func (d *StateDecoder) Walk(fn TranscriptFunc) (*State, error) {
+	s := &State{}
+	if err := expectDelim(d.dec, '{'); err != nil {
+		return nil, err
+	}
+	for d.dec.More() {
+		key, err := readKey(d.dec)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "transcripts":
+			err = readArray(d.dec, func(i int) error {
+				t, err := readTranscript(d.dec)
+				if err != nil {
+					return fmt.Errorf("transcript %d: %w", i, err)
+				}
+				return fn(i, t)
+			})
+		case "participantIds":
+			s.ParticipantIDs, err = readStrings(d.dec)
+		case "participantEcdsaSignatures":
+			s.ParticipantECDSASignatures, err = readStrings(d.dec)
+		default:
+			err = skipValue(d.dec)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := expectDelim(d.dec, '}'); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func readTranscript(dec *json.Decoder) (*Transcript, error) {
+	t := &Transcript{PowersOfTau: &SRS{}, Witness: &Witness{}}
+	err := readObject(dec, func(key string) error {
+		switch key {
+		case "numG1Powers":
+			return dec.Decode(&t.NumG1Powers)
+		case "numG2Powers":
+			return dec.Decode(&t.NumG2Powers)
+		case "powersOfTau":
+			return readObject(dec, func(key string) error {
+				var err error
+				switch key {
+				case "G1Powers":
+					t.PowersOfTau.G1Powers, err = readPointsG1(dec)
+				case "G2Powers":
+					t.PowersOfTau.G2Powers, err = readPointsG2(dec)
+				default:
+					err = skipValue(dec)
+				}
+				return err
+			})
+		case "witness":
+			return readObject(dec, func(key string) error {
+				var err error
+				switch key {
+				case "runningProducts":
+					t.Witness.RunningProducts, err = readPointsG1(dec)
+				case "potPubkeys":
+					t.Witness.PotPubKeys, err = readPointsG2(dec)
+				case "blsSignatures":
+					t.Witness.BLSSignatures, err = readPointsG1(dec)
+				default:
+					err = skipValue(dec)
+				}
+				return err
+			})
+		default:
+			return skipValue(dec)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if t.NumG1Powers != uint64(len(t.PowersOfTau.G1Powers)) {
+		return nil, fmt.Errorf("wrong NumG1Powers")
+	}
+	if t.NumG2Powers != uint64(len(t.PowersOfTau.G2Powers)) {
+		return nil, fmt.Errorf("wrong NumG2Powers")
+	}
+	return t, nil
+}
+
+// readPointsG1 reads an array of G1 points in the ZCash compressed format,
+// holding at most streamChunkSize strings at a time
+func readPointsG1(dec *json.Decoder) ([]*bls12381.PointG1, error) {
+	var points []*bls12381.PointG1
+	chunk := make([]string, 0, streamChunkSize)
+	flush := func() error {
+		dst := make([]*bls12381.PointG1, len(chunk))
+		if i, err := decodePointsG1(dst, chunk); err != nil {
+			return fmt.Errorf("G1 point %d: %w", len(points)+i, err)
+		}
+		points = append(points, dst...)
+		chunk = chunk[:0]
+		return nil
+	}
+	err := readArray(dec, func(i int) error {
+		var s string
+		if err := dec.Decode(&s); err != nil {
+			return err
+		}
+		chunk = append(chunk, s)
+		if len(chunk) == streamChunkSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// readPointsG2 acts as readPointsG1 for G2 points
+func readPointsG2(dec *json.Decoder) ([]*bls12381.PointG2, error) {
+	var points []*bls12381.PointG2
+	chunk := make([]string, 0, streamChunkSize)
+	flush := func() error {
+		dst := make([]*bls12381.PointG2, len(chunk))
+		if i, err := decodePointsG2(dst, chunk); err != nil {
+			return fmt.Errorf("G2 point %d: %w", len(points)+i, err)
+		}
+		points = append(points, dst...)
+		chunk = chunk[:0]
+		return nil
+	}
+	err := readArray(dec, func(i int) error {
+		var s string
+		if err := dec.Decode(&s); err != nil {
+			return err
+		}
+		chunk = append(chunk, s)
+		if len(chunk) == streamChunkSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+func readStrings(dec *json.Decoder) ([]string, error) {
+	var s []string
+	err := readArray(dec, func(i int) error {
+		var e string
+		if err := dec.Decode(&e); err != nil {
+			return err
+		}
+		s = append(s, e)
+		return nil
+	})
+	return s, err
+}
+
+// readObject reads a JSON object calling fn for each key, which must consume
+// its value. A null value is read as an empty object.
+func readObject(dec *json.Decoder, fn func(key string) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected JSON object, got %v", tok)
+	}
+	for dec.More() {
+		key, err := readKey(dec)
+		if err != nil {
+			return err
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// readArray reads a JSON array calling fn with the index of each element,
+// which must consume it. A null value is read as an empty array.
+func readArray(dec *json.Decoder, fn func(i int) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected JSON array, got %v", tok)
+	}
+	for i := 0; dec.More(); i++ {
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func readKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("expected JSON object key, got %v", tok)
+	}
+	return key, nil
+}
+
+func expectDelim(dec *json.Decoder, d json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != d {
+		return fmt.Errorf("expected %v, got %v", d, tok)
+	}
+	return nil
+}
+
+// skipValue consumes the next JSON value, used for unknown keys
+func skipValue(dec *json.Decoder) error {
+	var v json.RawMessage
+	return dec.Decode(&v)
+}
+
+// BatchContributionEncoder writes a BatchContribution in the official
+// Ethereum KZG Ceremony JSON format to an io.Writer, compressing the points
+// one at a time straight into the output, without building the string form
+// of the BatchContribution in memory
+type BatchContributionEncoder struct {
+	w io.Writer
+}
+
+// NewBatchContributionEncoder returns a BatchContributionEncoder that writes
+// to w
+func NewBatchContributionEncoder(w io.Writer) *BatchContributionEncoder {
+	return &BatchContributionEncoder{w: w}
+}
+
+// Encode writes the JSON encoding of bc, which is the same as the one of
+// json.Marshal
+func (e *BatchContributionEncoder) Encode(bc *BatchContribution) error {
+	w := &jsonWriter{
+		w:  bufio.NewWriter(e.w),
+		g1: bls12381.NewG1(),
+		g2: bls12381.NewG2(),
+	}
+	w.raw(`{"contributions":[`)
+	for i, c := range bc.Contributions {
+		if i > 0 {
+			w.raw(",")
+		}
+		w.raw(`{"numG1Powers":`)
+		w.raw(strconv.FormatUint(c.NumG1Powers, 10))
+		w.raw(`,"numG2Powers":`)
+		w.raw(strconv.FormatUint(c.NumG2Powers, 10))
+		w.raw(`,"powersOfTau":{"G1Powers":`)
+		var g1s []*bls12381.PointG1
+		var g2s []*bls12381.PointG2
+		if c.PowersOfTau != nil {
+			g1s, g2s = c.PowersOfTau.G1Powers, c.PowersOfTau.G2Powers
+		}
+		w.arrayG1(g1s)
+		w.raw(`,"G2Powers":`)
+		w.arrayG2(g2s)
+		w.raw(`},"potPubkey":`)
+		w.pointG2(c.PotPubKey)
+		w.raw(`,"blsSignature":`)
+		w.pointG1(c.BLSSignature)
+		w.raw("}")
+	}
+	w.raw(`],"ecdsaSignature":`)
+	w.str(bc.ECDSASignature)
+	w.raw("}")
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// jsonWriter writes JSON tokens, keeping the first error so that the callers
+// check it once at the end
+type jsonWriter struct {
+	w   *bufio.Writer
+	g1  *bls12381.G1
+	g2  *bls12381.G2
+	err error
+	// buf holds the hex encoding of a compressed G2 point, the largest
+	// value written
+	buf [2 + 2*96]byte
+}
+
+func (w *jsonWriter) raw(s string) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.WriteString(s)
+}
+
+func (w *jsonWriter) str(s string) {
+	if w.err != nil {
+		return
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		w.err = err
+		return
+	}
+	_, w.err = w.w.Write(b)
+}
+
+// compressed writes the compressed point as a 0x prefixed hex JSON string
+func (w *jsonWriter) compressed(p []byte) {
+	if w.err != nil {
+		return
+	}
+	w.buf[0], w.buf[1] = '0', 'x'
+	n := 2 + hex.Encode(w.buf[2:], p)
+	if w.err = w.w.WriteByte('"'); w.err != nil {
+		return
+	}
+	if _, w.err = w.w.Write(w.buf[:n]); w.err != nil {
+		return
+	}
+	w.err = w.w.WriteByte('"')
+}
+
+// pointG1 writes the point as g1PointToString does
+func (w *jsonWriter) pointG1(p *bls12381.PointG1) {
+	if p == nil {
+		w.raw(`""`)
+		return
+	}
+	w.compressed(w.g1.ToCompressed(p))
+}
+
+// pointG2 writes the point as g2PointToString does
+func (w *jsonWriter) pointG2(p *bls12381.PointG2) {
+	if p == nil {
+		w.raw(`""`)
+		return
+	}
+	w.compressed(w.g2.ToCompressed(p))
+}
+
+func (w *jsonWriter) arrayG1(points []*bls12381.PointG1) {
+	w.raw("[")
+	for i, p := range points {
+		if i > 0 {
+			w.raw(",")
+		}
+		w.pointG1(p)
+	}
+	w.raw("]")
+}
+
+func (w *jsonWriter) arrayG2(points []*bls12381.PointG2) {
+	w.raw("[")
+	for i, p := range points {
+		if i > 0 {
+			w.raw(",")
+		}
+		w.pointG2(p)
+	}
+	w.raw("]")
+}
@@ -0,0 +1,158 @@
+package kzgceremony
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestStateDecoder(t *testing.T) {
+	c := qt.New(t)
+	j, err := ioutil.ReadFile("current_state_10.json")
+	c.Assert(err, qt.IsNil)
+
+	var sStr stateStr
+	err = json.Unmarshal(j, &sStr)
+	c.Assert(err, qt.IsNil)
+
+	defer func(n int) { streamChunkSize = n }(streamChunkSize)
+	for _, chunkSize := range []int{1, 3, 1024} {
+		streamChunkSize = chunkSize
+
+		state := &State{}
+		err = NewStateDecoder(bytes.NewReader(j)).Decode(state)
+		c.Assert(err, qt.IsNil)
+		c.Assert(state.Transcripts, qt.HasLen, len(sStr.Transcripts))
+		c.Assert(state.ParticipantIDs, qt.DeepEquals, sStr.ParticipantIDs)
+		c.Assert(state.ParticipantECDSASignatures, qt.DeepEquals,
+			sStr.ParticipantECDSASignatures)
+		for i, tr := range state.Transcripts {
+			c.Assert(g1PointsToStrings(tr.PowersOfTau.G1Powers), qt.DeepEquals,
+				sStr.Transcripts[i].PowersOfTau.G1Powers)
+			c.Assert(g2PointsToStrings(tr.PowersOfTau.G2Powers), qt.DeepEquals,
+				sStr.Transcripts[i].PowersOfTau.G2Powers)
+			c.Assert(g1PointsToStrings(tr.Witness.RunningProducts), qt.DeepEquals,
+				sStr.Transcripts[i].Witness.RunningProducts)
+			c.Assert(g2PointsToStrings(tr.Witness.PotPubKeys), qt.DeepEquals,
+				sStr.Transcripts[i].Witness.PotPubKeys)
+			c.Assert(g1PointsToStrings(tr.Witness.BLSSignatures), qt.DeepEquals,
+				sStr.Transcripts[i].Witness.BLSSignatures)
+		}
+	}
+}
+
+func TestStateDecoderWalk(t *testing.T) {
+	c := qt.New(t)
+	j, err := ioutil.ReadFile("current_state_10.json")
+	c.Assert(err, qt.IsNil)
+
+	var sizes []uint64
+	header, err := NewStateDecoder(bytes.NewReader(j)).Walk(
+		func(i int, tr *Transcript) error {
+			c.Assert(i, qt.Equals, len(sizes))
+			sizes = append(sizes, tr.NumG1Powers)
+			return nil
+		})
+	c.Assert(err, qt.IsNil)
+	c.Assert(sizes, qt.HasLen, 4)
+	c.Assert(header.Transcripts, qt.IsNil)
+	c.Assert(header.ParticipantIDs, qt.HasLen, 10)
+
+	// an error returned by the callback stops the decoding
+	_, err = NewStateDecoder(bytes.NewReader(j)).Walk(
+		func(i int, tr *Transcript) error {
+			return fmt.Errorf("stop at %d", i)
+		})
+	c.Assert(err, qt.ErrorMatches, "stop at 0")
+}
+
+func TestStateDecoderErrors(t *testing.T) {
+	c := qt.New(t)
+	j, err := ioutil.ReadFile("current_state_10.json")
+	c.Assert(err, qt.IsNil)
+
+	var sStr stateStr
+	err = json.Unmarshal(j, &sStr)
+	c.Assert(err, qt.IsNil)
+
+	defer func(n int) { streamChunkSize = n }(streamChunkSize)
+	streamChunkSize = 3
+	// the index of the failing point is reported across chunks
+	sStr.Transcripts[1].PowersOfTau.G1Powers[7] = "0xc" + strings.Repeat("0", 95)
+	b, err := json.Marshal(sStr)
+	c.Assert(err, qt.IsNil)
+	err = NewStateDecoder(bytes.NewReader(b)).Decode(&State{})
+	c.Assert(err, qt.ErrorMatches,
+		"transcript 1: G1 point 7: point can not be zero")
+
+	sStr.Transcripts[1].PowersOfTau.G1Powers =
+		sStr.Transcripts[1].PowersOfTau.G1Powers[:5]
+	b, err = json.Marshal(sStr)
+	c.Assert(err, qt.IsNil)
+	err = NewStateDecoder(bytes.NewReader(b)).Decode(&State{})
+	c.Assert(err, qt.ErrorMatches, "transcript 1: wrong NumG1Powers")
+
+	err = NewStateDecoder(strings.NewReader(`{"transcripts": {}}`)).Decode(&State{})
+	c.Assert(err, qt.ErrorMatches, "expected JSON array, got {")
+	err = NewStateDecoder(strings.NewReader(`{"transcripts": [`)).Decode(&State{})
+	c.Assert(err, qt.Not(qt.IsNil))
+}
+
+func TestBatchContributionEncoder(t *testing.T) {
+	c := qt.New(t)
+	j, err := ioutil.ReadFile("batch_contribution_10.json")
+	c.Assert(err, qt.IsNil)
+	bc := &BatchContribution{}
+	err = json.Unmarshal(j, bc)
+	c.Assert(err, qt.IsNil)
+	bc.ECDSASignature = "0x1234\"<>"
+	bc.Contributions[1].BLSSignature = nil
+
+	// expected encoding through the string representation
+	var cStr batchContributionStr
+	cStr.ECDSASignature = bc.ECDSASignature
+	for _, contr := range bc.Contributions {
+		cStr.Contributions = append(cStr.Contributions, contributionStr{
+			NumG1Powers: contr.NumG1Powers,
+			NumG2Powers: contr.NumG2Powers,
+			PowersOfTau: powersOfTauStr{
+				G1Powers: g1PointsToStrings(contr.PowersOfTau.G1Powers),
+				G2Powers: g2PointsToStrings(contr.PowersOfTau.G2Powers),
+			},
+			PotPubKey:    g2PointToString(contr.PotPubKey),
+			BLSSignature: g1PointToString(contr.BLSSignature),
+		})
+	}
+	expected, err := json.Marshal(cStr)
+	c.Assert(err, qt.IsNil)
+
+	var b bytes.Buffer
+	err = NewBatchContributionEncoder(&b).Encode(bc)
+	c.Assert(err, qt.IsNil)
+	c.Assert(b.String(), qt.Equals, string(expected))
+
+	m, err := json.Marshal(bc)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(m), qt.Equals, string(expected))
+
+	bc2 := &BatchContribution{}
+	err = json.Unmarshal(b.Bytes(), bc2)
+	c.Assert(err, qt.IsNil)
+	c.Assert(bc2.ECDSASignature, qt.Equals, bc.ECDSASignature)
+	c.Assert(bc2.Contributions[1].BLSSignature, qt.IsNil)
+
+	// write errors are returned
+	err = NewBatchContributionEncoder(failingWriter{}).Encode(bc)
+	c.Assert(err, qt.ErrorMatches, "write failed")
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("write failed")
+}
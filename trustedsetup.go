@@ -0,0 +1,67 @@
+package kzgceremony
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/arnaucube/eth-kzg-ceremony-alt/poly"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// WriteTrustedSetup writes the SRS in the trusted_setup.txt layout loaded by
+// c-kzg-4844 (v2 onwards) and go-kzg-4844: the number of G1 points and the
+// number of G2 points, followed by the G1 points in Lagrange form over the
+// roots of unity domain of size len(G1Powers), the G2 points in monomial
+// form, and the G1 points in monomial form, one compressed hex point per
+// line.
+//
+// As in the published setup, the Lagrange points are written in the natural
+// order of the domain ω⁰, ω¹, ..., ωⁿ⁻¹: the loaders (and the consensus
+// specs, through bit_reversal_permutation) apply the bit-reversal
+// permutation themselves after reading them.
+//
+// The number of G1 powers must be a power of two.
+func WriteTrustedSetup(w io.Writer, srs *SRS) error {
+	lagrange, err := srs.G1Lagrange()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	_, _ = fmt.Fprintf(bw, "%d\n%d\n", len(lagrange), len(srs.G2Powers))
+	for _, p := range lagrange {
+		_, _ = fmt.Fprintf(bw, "%s\n", hex.EncodeToString(g1.ToCompressed(p)))
+	}
+	for _, p := range srs.G2Powers {
+		_, _ = fmt.Fprintf(bw, "%s\n", hex.EncodeToString(g2.ToCompressed(p)))
+	}
+	for _, p := range srs.G1Powers {
+		_, _ = fmt.Fprintf(bw, "%s\n", hex.EncodeToString(g1.ToCompressed(p)))
+	}
+	return bw.Flush()
+}
+
+// G1Lagrange returns the Lagrange form [l₀(τ)]₁, [l₁(τ)]₁, ..., [lₙ₋₁(τ)]₁
+// of the G1 powers [τ⁰]₁, [τ¹]₁, ..., [τⁿ⁻¹]₁, where lᵢ is the Lagrange
+// polynomial of the roots of unity domain ω⁰, ω¹, ..., ωⁿ⁻¹ (with ω as in
+// the consensus specs), computed through an inverse FFT over G1. The number
+// of G1 powers must be a power of two.
+func (srs *SRS) G1Lagrange() ([]*bls12381.PointG1, error) {
+	return g1Lagrange(srs.G1Powers)
+}
+
+func g1Lagrange(monomial []*bls12381.PointG1) ([]*bls12381.PointG1, error) {
+	n := len(monomial)
+	if n == 0 || n&(n-1) != 0 {
+		return nil, fmt.Errorf("number of G1 powers %d is not a power of two", n)
+	}
+	domain, err := poly.NewDomain(n)
+	if err != nil {
+		return nil, err
+	}
+	domain.Workers = NumWorkers
+	// lᵢ(τ) = 1/n ⋅ Σⱼ ω⁻ⁱʲ τʲ
+	return domain.IFFTG1(monomial)
+}
@@ -0,0 +1,94 @@
+package kzgceremony
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/arnaucube/eth-kzg-ceremony-alt/poly"
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// readTrustedSetupMonomial reads the monomial SRS from a c-kzg-4844
+// trusted_setup.txt, which after the Lagrange G1 points and the G2 points
+// contains the monomial G1 points. trusted_setup_4096.txt is the mainnet
+// setup published in c-kzg-4844 v2.1.8.
+func readTrustedSetupMonomial(c *qt.C, path string) (*SRS, string) {
+	b, err := ioutil.ReadFile(path)
+	c.Assert(err, qt.IsNil)
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+
+	nG1, err := strconv.Atoi(lines[0])
+	c.Assert(err, qt.IsNil)
+	nG2, err := strconv.Atoi(lines[1])
+	c.Assert(err, qt.IsNil)
+	c.Assert(lines, qt.HasLen, 2+2*nG1+nG2)
+
+	srs := &SRS{}
+	for _, l := range lines[2+nG1+nG2:] {
+		b, err := hex.DecodeString(l)
+		c.Assert(err, qt.IsNil)
+		p, err := g1.FromCompressed(b)
+		c.Assert(err, qt.IsNil)
+		srs.G1Powers = append(srs.G1Powers, p)
+	}
+	for _, l := range lines[2+nG1 : 2+nG1+nG2] {
+		b, err := hex.DecodeString(l)
+		c.Assert(err, qt.IsNil)
+		p, err := g2.FromCompressed(b)
+		c.Assert(err, qt.IsNil)
+		srs.G2Powers = append(srs.G2Powers, p)
+	}
+	return srs, string(b)
+}
+
+func TestWriteTrustedSetupMainnet(t *testing.T) {
+	c := qt.New(t)
+	srs, expected := readTrustedSetupMonomial(c, "trusted_setup_4096.txt")
+	c.Assert(srs.G1Powers, qt.HasLen, 4096)
+
+	// the output is the unmodified published file
+	var b bytes.Buffer
+	err := WriteTrustedSetup(&b, srs)
+	c.Assert(err, qt.IsNil)
+	c.Assert(b.String(), qt.Equals, expected)
+}
+
+func TestG1LagrangeSmall(t *testing.T) {
+	c := qt.New(t)
+
+	// for a polynomial p(X) = Σ cᵢ Xⁱ, Σ p(ωⁱ) ⋅ [lᵢ(τ)]₁ = Σ cᵢ ⋅ [τⁱ]₁
+	n := 8
+	prev := newEmptySRS(n, 2)
+	tw, err := tau(0, n, 2,
+		[]byte("1111111111111111111111111111111111111111111111111111111111111111"))
+	c.Assert(err, qt.IsNil)
+	defer tw.zeroize()
+	srs := computeContribution(tw, prev)
+
+	lagrange, err := g1Lagrange(srs.G1Powers)
+	c.Assert(err, qt.IsNil)
+
+	// p(X) = X³: Σ ω³ⁱ [lᵢ(τ)]₁ must be [τ³]₁
+	omega := poly.RootOfUnity(n)
+	acc := g1.Zero()
+	w := bls12381.NewFr().One()
+	for i := 0; i < n; i++ {
+		wi := bls12381.NewFr().One()
+		for k := 0; k < 3; k++ {
+			wi.Mul(wi, w)
+		}
+		t := g1.New()
+		g1.MulScalar(t, lagrange[i], wi)
+		g1.Add(acc, acc, t)
+		w.Mul(w, omega)
+	}
+	c.Assert(g1.Equal(acc, srs.G1Powers[3]), qt.IsTrue)
+
+	_, err = g1Lagrange(srs.G1Powers[:6])
+	c.Assert(err, qt.ErrorMatches, "number of G1 powers 6 is not a power of two")
+}
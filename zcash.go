@@ -0,0 +1,859 @@
+package kzgceremony
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+
+	bls12381 "github.com/kilic/bls12-381"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+// The Zcash powersoftau ceremony (https://github.com/ebfull/powersoftau)
+// computes an accumulator with the powers of τ, α⋅τ and β⋅τ over BLS12-381
+// through a chain of files:
+//
+//	challenge: BLAKE2b-512 of the previous response (or of the empty input
+//	           for the first challenge) ‖ accumulator (uncompressed)
+//	response:  BLAKE2b-512 of the challenge ‖ accumulator (compressed) ‖
+//	           public key (uncompressed)
+//
+// where the accumulator is made of the powers [τⁱ]₁ (i < 2n-1), [τⁱ]₂,
+// [α⋅τⁱ]₁, [β⋅τⁱ]₁ (i < n) and [β]₂, and the public key of the proofs of
+// knowledge of τ, α and β. The points use the ZCash serialization, as the
+// rest of this package. The files do not contain their sizes nor their
+// compression, which are described by a ZcashFormat.
+
+// ZcashTauPowers is the number n of powers (TAU_POWERS_LENGTH) of the Zcash
+// Sapling ceremony
+const ZcashTauPowers = 1 << 21
+
+// ZcashSaplingFormat is the format of the files of the Zcash Sapling
+// ceremony
+var ZcashSaplingFormat = ZcashFormat{Powers: ZcashTauPowers, CompressedResponse: true}
+
+// zcash hash_to_g2 personalizations of the proofs of knowledge
+const (
+	zcashPersonalizationTau = iota
+	zcashPersonalizationAlpha
+	zcashPersonalizationBeta
+)
+
+// zcashG2Cofactor is the cofactor h₂ of G2, by which the random points of
+// hash_to_g2 are multiplied
+var zcashG2Cofactor, _ = new(big.Int).SetString("5d543a95414e7f1091d50792876a202cd91de4547085abaa68a205b2e5a7ddfa628f1cb4d9e82ef21537e293a6691ae1616ec6e786f0c70cf1c38e31c7238e5", 16)
+
+// ZcashFormat describes the files of a Zcash powersoftau ceremony
+type ZcashFormat struct {
+	// Powers is the number n of powers of the accumulators, which contain
+	// 2n-1 powers [τⁱ]₁ and n powers in the other vectors
+	Powers int
+	// CompressedChallenge is set when the challenges contain compressed
+	// points (the original ceremony uses uncompressed points)
+	CompressedChallenge bool
+	// CompressedResponse is set when the responses contain compressed
+	// points (as the original ceremony)
+	CompressedResponse bool
+}
+
+// ZcashAccumulator contains the powers of a Zcash powersoftau ceremony
+type ZcashAccumulator struct {
+	// TauG1 are the powers [τⁱ]₁, i < 2n-1
+	TauG1 []*bls12381.PointG1
+	// TauG2 are the powers [τⁱ]₂, i < n
+	TauG2 []*bls12381.PointG2
+	// AlphaTauG1 are the powers [α⋅τⁱ]₁, i < n
+	AlphaTauG1 []*bls12381.PointG1
+	// BetaTauG1 are the powers [β⋅τⁱ]₁, i < n
+	BetaTauG1 []*bls12381.PointG1
+	// BetaG2 is [β]₂
+	BetaG2 *bls12381.PointG2
+}
+
+// ZcashPublicKey contains the proofs of knowledge of the τ, α and β of a
+// contribution: for each one of them the G1 points s and s⋅x, for a random
+// s, and the G2 point [x]ₕ, where h is computed from the challenge hash and
+// the G1 points
+type ZcashPublicKey struct {
+	TauG1   [2]*bls12381.PointG1
+	AlphaG1 [2]*bls12381.PointG1
+	BetaG1  [2]*bls12381.PointG1
+	TauG2   *bls12381.PointG2
+	AlphaG2 *bls12381.PointG2
+	BetaG2  *bls12381.PointG2
+}
+
+// ZcashChallenge is the content of a challenge file
+type ZcashChallenge struct {
+	// PrevHash is the hash of the previous response file, or ZcashBlankHash
+	// for the first challenge
+	PrevHash    [64]byte
+	Accumulator *ZcashAccumulator
+}
+
+// ZcashResponse is the content of a response file
+type ZcashResponse struct {
+	// ChallengeHash is the hash of the challenge file the response was
+	// computed from
+	ChallengeHash [64]byte
+	Accumulator   *ZcashAccumulator
+	PublicKey     *ZcashPublicKey
+}
+
+// ZcashBlankHash returns the BLAKE2b-512 hash of the empty input, used as
+// PrevHash by the first challenge of the ceremonies
+func ZcashBlankHash() [64]byte {
+	return blake2b.Sum512(nil)
+}
+
+// NewZcashAccumulator returns the initial accumulator of n powers, where
+// τ = α = β = 1
+func NewZcashAccumulator(n int) *ZcashAccumulator {
+	a := &ZcashAccumulator{
+		TauG1:      make([]*bls12381.PointG1, 2*n-1),
+		TauG2:      make([]*bls12381.PointG2, n),
+		AlphaTauG1: make([]*bls12381.PointG1, n),
+		BetaTauG1:  make([]*bls12381.PointG1, n),
+		BetaG2:     g2.One(),
+	}
+	for i := range a.TauG1 {
+		a.TauG1[i] = g1.One()
+	}
+	for i := 0; i < n; i++ {
+		a.TauG2[i] = g2.One()
+		a.AlphaTauG1[i] = g1.One()
+		a.BetaTauG1[i] = g1.One()
+	}
+	return a
+}
+
+// SRS returns the powers of τ of the accumulator as an SRS
+func (a *ZcashAccumulator) SRS() *SRS {
+	return &SRS{G1Powers: a.TauG1, G2Powers: a.TauG2}
+}
+
+// WriteChallenge writes the challenge file
+func (f ZcashFormat) WriteChallenge(w io.Writer, c *ZcashChallenge) error {
+	if err := f.checkAccumulator(c.Accumulator); err != nil {
+		return err
+	}
+	zw := newZcashWriter(w, f.CompressedChallenge)
+	zw.write(c.PrevHash[:])
+	zw.accumulator(c.Accumulator)
+	return zw.flush()
+}
+
+// WriteResponse writes the response file
+func (f ZcashFormat) WriteResponse(w io.Writer, r *ZcashResponse) error {
+	if err := f.checkAccumulator(r.Accumulator); err != nil {
+		return err
+	}
+	if r.PublicKey == nil {
+		return fmt.Errorf("response without public key")
+	}
+	zw := newZcashWriter(w, f.CompressedResponse)
+	zw.write(r.ChallengeHash[:])
+	zw.accumulator(r.Accumulator)
+	zw.publicKey(r.PublicKey)
+	return zw.flush()
+}
+
+// ReadChallenge reads a challenge file, checking the correctness of all the
+// points
+func (f ZcashFormat) ReadChallenge(r io.Reader) (*ZcashChallenge, error) {
+	zr := newZcashReader(r, f.CompressedChallenge)
+	c := &ZcashChallenge{}
+	zr.read(c.PrevHash[:])
+	c.Accumulator = zr.accumulator(f.Powers)
+	if err := zr.finish(); err != nil {
+		return nil, fmt.Errorf("challenge: %w", err)
+	}
+	return c, nil
+}
+
+// ReadResponse reads a response file, checking the correctness of all the
+// points
+func (f ZcashFormat) ReadResponse(r io.Reader) (*ZcashResponse, error) {
+	zr := newZcashReader(r, f.CompressedResponse)
+	resp := &ZcashResponse{}
+	zr.read(resp.ChallengeHash[:])
+	resp.Accumulator = zr.accumulator(f.Powers)
+	resp.PublicKey = zr.publicKey()
+	if err := zr.finish(); err != nil {
+		return nil, fmt.Errorf("response: %w", err)
+	}
+	return resp, nil
+}
+
+// ChallengeHash returns the BLAKE2b-512 hash of the challenge file
+func (f ZcashFormat) ChallengeHash(c *ZcashChallenge) ([64]byte, error) {
+	h, _ := blake2b.New512(nil)
+	if err := f.WriteChallenge(h, c); err != nil {
+		return [64]byte{}, err
+	}
+	var out [64]byte
+	copy(out[:], h.Sum(nil))
+	return out, nil
+}
+
+// ResponseHash returns the BLAKE2b-512 hash of the response file
+func (f ZcashFormat) ResponseHash(r *ZcashResponse) ([64]byte, error) {
+	h, _ := blake2b.New512(nil)
+	if err := f.WriteResponse(h, r); err != nil {
+		return [64]byte{}, err
+	}
+	var out [64]byte
+	copy(out[:], h.Sum(nil))
+	return out, nil
+}
+
+// NextChallenge returns the challenge that follows the response
+func (f ZcashFormat) NextChallenge(r *ZcashResponse) (*ZcashChallenge, error) {
+	h, err := f.ResponseHash(r)
+	if err != nil {
+		return nil, err
+	}
+	return &ZcashChallenge{PrevHash: h, Accumulator: r.Accumulator}, nil
+}
+
+// VerifyResponse checks that the response was correctly computed from the
+// challenge, as the verify_transform of the Zcash ceremony
+func (f ZcashFormat) VerifyResponse(c *ZcashChallenge, r *ZcashResponse,
+	mode VerificationMode) bool {
+	return f.CheckResponse(c, r, mode) == nil
+}
+
+// CheckResponse does the same checks than VerifyResponse, returning a
+// *VerificationError describing the first check that failed, or nil if the
+// response is valid
+func (f ZcashFormat) CheckResponse(c *ZcashChallenge, r *ZcashResponse,
+	mode VerificationMode) error {
+	if err := f.checkAccumulator(c.Accumulator); err != nil {
+		return err
+	}
+	if err := f.checkAccumulator(r.Accumulator); err != nil {
+		return err
+	}
+	if r.PublicKey == nil {
+		return fmt.Errorf("response without public key")
+	}
+	before, after, key := c.Accumulator, r.Accumulator, r.PublicKey
+
+	// 1. check that the response was computed from the challenge
+	challengeHash, err := f.ChallengeHash(c)
+	if err != nil {
+		return err
+	}
+	if challengeHash != r.ChallengeHash {
+		return &VerificationError{Check: HashChainCheck, Transcript: -1,
+			Element: "ChallengeHash", Index: -1}
+	}
+
+	// 2. check that the elements of the response are valid points
+	if err := checkZcashPoints(after, key); err != nil {
+		return err
+	}
+
+	// 3. check the proofs of knowledge of τ, α and β:
+	//   e(s, [x]ₕ) == e(s⋅x, h)
+	hTau := zcashHashToG2(&r.ChallengeHash, zcashPersonalizationTau, key.TauG1)
+	hAlpha := zcashHashToG2(&r.ChallengeHash, zcashPersonalizationAlpha, key.AlphaG1)
+	hBeta := zcashHashToG2(&r.ChallengeHash, zcashPersonalizationBeta, key.BetaG1)
+	for _, pok := range []struct {
+		element string
+		g1      [2]*bls12381.PointG1
+		h, g2   *bls12381.PointG2
+	}{
+		{"PublicKey.TauG2", key.TauG1, hTau, key.TauG2},
+		{"PublicKey.AlphaG2", key.AlphaG1, hAlpha, key.AlphaG2},
+		{"PublicKey.BetaG2", key.BetaG1, hBeta, key.BetaG2},
+	} {
+		if !sameRatio(pok.g1[0], pok.g1[1], pok.h, pok.g2) {
+			return &VerificationError{Check: PubKeyCheck, Transcript: -1,
+				Element: pok.element, Index: -1, Point: g2PointToString(pok.g2)}
+		}
+	}
+
+	// 4. check that the powers are computed from the generators
+	if !g1.Equal(after.TauG1[0], g1.One()) {
+		return &VerificationError{Check: GenesisCheck, Transcript: -1,
+			Element: "TauG1", Index: 0, Point: g1PointToString(after.TauG1[0])}
+	}
+	if !g2.Equal(after.TauG2[0], g2.One()) {
+		return &VerificationError{Check: GenesisCheck, Transcript: -1,
+			Element: "TauG2", Index: 0, Point: g2PointToString(after.TauG2[0])}
+	}
+
+	// 5. check that the previous τ, α and β were multiplied by the ones of
+	// the public key:
+	//   e([τ]₁, [x]ₕ) == e([τ⋅x]₁, h)
+	if !sameRatio(before.TauG1[1], after.TauG1[1], hTau, key.TauG2) {
+		return &VerificationError{Check: RunningProductCheck, Transcript: -1,
+			Element: "TauG1", Index: 1, Point: g1PointToString(after.TauG1[1])}
+	}
+	if !sameRatio(before.AlphaTauG1[0], after.AlphaTauG1[0], hAlpha, key.AlphaG2) {
+		return &VerificationError{Check: RunningProductCheck, Transcript: -1,
+			Element: "AlphaTauG1", Index: 0, Point: g1PointToString(after.AlphaTauG1[0])}
+	}
+	if !sameRatio(before.BetaTauG1[0], after.BetaTauG1[0], hBeta, key.BetaG2) {
+		return &VerificationError{Check: RunningProductCheck, Transcript: -1,
+			Element: "BetaTauG1", Index: 0, Point: g1PointToString(after.BetaTauG1[0])}
+	}
+	if !sameRatio(before.BetaTauG1[0], after.BetaTauG1[0], before.BetaG2, after.BetaG2) {
+		return &VerificationError{Check: RunningProductCheck, Transcript: -1,
+			Element: "BetaG2", Index: -1, Point: g2PointToString(after.BetaG2)}
+	}
+
+	// 6. check the powers of τ structure of the three G1 vectors and the
+	// G2 vector
+	if err := verifySRSStructure(after.SRS(), mode); err != nil {
+		return withZcashElement(err)
+	}
+	if err := verifyZcashPowersG1(after.AlphaTauG1, after.TauG2[1], "AlphaTauG1", mode); err != nil {
+		return err
+	}
+	return verifyZcashPowersG1(after.BetaTauG1, after.TauG2[1], "BetaTauG1", mode)
+}
+
+// checkAccumulator checks that the accumulator has the sizes of the format
+func (f ZcashFormat) checkAccumulator(a *ZcashAccumulator) error {
+	if f.Powers < 2 {
+		return fmt.Errorf("zcash format needs at least 2 powers, got %d", f.Powers)
+	}
+	if a == nil || len(a.TauG1) != 2*f.Powers-1 || len(a.TauG2) != f.Powers ||
+		len(a.AlphaTauG1) != f.Powers || len(a.BetaTauG1) != f.Powers || a.BetaG2 == nil {
+		return fmt.Errorf("accumulator does not have the sizes of %d powers", f.Powers)
+	}
+	return nil
+}
+
+// withZcashElement renames the SRS element of err when it is a
+// *VerificationError to the name of the accumulator vector
+func withZcashElement(err error) error {
+	if vErr, ok := err.(*VerificationError); ok {
+		switch vErr.Element {
+		case "G1Powers":
+			vErr.Element = "TauG1"
+		case "G2Powers":
+			vErr.Element = "TauG2"
+		}
+	}
+	return err
+}
+
+// checkZcashPoints checks the correctness of all the points of the
+// accumulator and of the public key
+func checkZcashPoints(a *ZcashAccumulator, key *ZcashPublicKey) error {
+	if err := checkSRSPoints(a.SRS()); err != nil {
+		return withZcashElement(err)
+	}
+	g1s := []struct {
+		element string
+		points  []*bls12381.PointG1
+	}{
+		{"AlphaTauG1", a.AlphaTauG1},
+		{"BetaTauG1", a.BetaTauG1},
+		{"PublicKey.TauG1", key.TauG1[:]},
+		{"PublicKey.AlphaG1", key.AlphaG1[:]},
+		{"PublicKey.BetaG1", key.BetaG1[:]},
+	}
+	for _, v := range g1s {
+		for i, p := range v.points {
+			if err := checkG1PointCorrectness(p); err != nil {
+				return &VerificationError{Check: PointValidityCheck, Transcript: -1,
+					Element: v.element, Index: i, Point: g1PointToString(p), Err: err}
+			}
+		}
+	}
+	g2s := []struct {
+		element string
+		point   *bls12381.PointG2
+	}{
+		{"BetaG2", a.BetaG2},
+		{"PublicKey.TauG2", key.TauG2},
+		{"PublicKey.AlphaG2", key.AlphaG2},
+		{"PublicKey.BetaG2", key.BetaG2},
+	}
+	for _, v := range g2s {
+		if err := checkG2PointCorrectness(v.point); err != nil {
+			return &VerificationError{Check: PointValidityCheck, Transcript: -1,
+				Element: v.element, Index: -1, Point: g2PointToString(v.point), Err: err}
+		}
+	}
+	return nil
+}
+
+// sameRatio checks that a₁ and b₁ are related as a₂ and b₂:
+// e(a₁, b₂) == e(b₁, a₂)
+func sameRatio(a1, b1 *bls12381.PointG1, a2, b2 *bls12381.PointG2) bool {
+	return bls12381.NewEngine().AddPair(a1, b2).AddPairInv(b1, a2).Check()
+}
+
+// verifyZcashPowersG1 checks that each point of the vector is the previous
+// one multiplied by τ: e(pᵢ, [τ]₂) == e(pᵢ₊₁, [1]₂), using the given
+// VerificationMode as verifySRSStructure
+func verifyZcashPowersG1(points []*bls12381.PointG1, tauG2 *bls12381.PointG2,
+	element string, mode VerificationMode) error {
+	n := len(points) - 1
+	if mode == VerifyBatch {
+		r, err := randomScalars(n)
+		if err != nil {
+			return err
+		}
+		lhs, err := MSMG1(points[:n], r)
+		if err != nil {
+			return err
+		}
+		rhs, err := MSMG1(points[1:], r)
+		if err != nil {
+			return err
+		}
+		if sameRatio(lhs, rhs, g2.One(), tauG2) {
+			return nil
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !sameRatio(points[i], points[i+1], g2.One(), tauG2) {
+			return &VerificationError{Check: G1StructureCheck, Transcript: -1,
+				Element: element, Index: i + 1, Point: g1PointToString(points[i+1])}
+		}
+	}
+	if mode == VerifyBatch {
+		// the batch check failed but no failing power was found
+		return &VerificationError{Check: G1StructureCheck, Transcript: -1,
+			Element: element, Index: -1}
+	}
+	return nil
+}
+
+// zcashHashToG2 returns the point h of a proof of knowledge, as computed by
+// the Zcash ceremony: the BLAKE2b-512 hash of the personalization, the
+// challenge hash and the uncompressed G1 points seeds a ChaCha20 generator,
+// from which a random point is sampled as the G2::rand of the pairing crate.
+// The generator is checked against rand_chacha (see TestZcashRNG), while the
+// sampling of the point is not yet checked against files of the Zcash
+// ceremony.
+func zcashHashToG2(digest *[64]byte, personalization byte, g1s [2]*bls12381.PointG1) *bls12381.PointG2 {
+	h, _ := blake2b.New512(nil)
+	_, _ = h.Write([]byte{personalization})
+	_, _ = h.Write(digest[:])
+	_, _ = h.Write(g1.ToUncompressed(g1s[0]))
+	_, _ = h.Write(g1.ToUncompressed(g1s[1]))
+	rng := newZcashRNG(h.Sum(nil))
+
+	g2 := bls12381.NewG2()
+	for {
+		x := fq2{rng.fq(), rng.fq()}
+		greatest := rng.next32()&1 == 1
+		// y² = x³ + 4(u + 1)
+		y2 := x.mul(x).mul(x).add(fq2{big.NewInt(4), big.NewInt(4)})
+		y, ok := y2.sqrt()
+		if !ok {
+			continue
+		}
+		// pick the lexicographically largest or smallest of ±y
+		negY := y.neg()
+		if (y.cmp(negY) < 0) == greatest {
+			y = negY
+		}
+		b := make([]byte, 0, 4*fpSize)
+		for _, e := range []*big.Int{x[1], x[0], y[1], y[0]} {
+			b = append(b, e.FillBytes(make([]byte, fpSize))...)
+		}
+		p, err := g2.FromBytes(b)
+		if err != nil {
+			continue
+		}
+		// MulScalarBig uses the GLV method, which only holds in the
+		// subgroup, so the cofactor is multiplied by double-and-add
+		q := g2.Zero()
+		for i := zcashG2Cofactor.BitLen() - 1; i >= 0; i-- {
+			g2.Double(q, q)
+			if zcashG2Cofactor.Bit(i) == 1 {
+				g2.Add(q, q, p)
+			}
+		}
+		if !g2.IsZero(q) {
+			return q
+		}
+	}
+}
+
+// zcashRNG reproduces the ChaChaRng of the rand 0.4 crate, seeded with the
+// first eight big-endian words of the seed, as the key of ChaCha20 with zero
+// nonce and counter
+type zcashRNG struct {
+	cipher *chacha20.Cipher
+}
+
+func newZcashRNG(seed []byte) *zcashRNG {
+	var key [chacha20.KeySize]byte
+	for i := 0; i < 8; i++ {
+		binary.LittleEndian.PutUint32(key[4*i:], binary.BigEndian.Uint32(seed[4*i:]))
+	}
+	var nonce [chacha20.NonceSize]byte
+	cipher, _ := chacha20.NewUnauthenticatedCipher(key[:], nonce[:])
+	return &zcashRNG{cipher: cipher}
+}
+
+func (r *zcashRNG) next32() uint32 {
+	var b [4]byte
+	r.cipher.XORKeyStream(b[:], b[:])
+	return binary.LittleEndian.Uint32(b[:])
+}
+
+// next64 returns the next u64, made of two words with the first one as the
+// high word, as in rand 0.4
+func (r *zcashRNG) next64() uint64 {
+	hi := uint64(r.next32())
+	return hi<<32 | uint64(r.next32())
+}
+
+// fq returns a random element of Fq as the pairing crate: six random limbs
+// with the top three bits cleared are taken, when smaller than q, as the
+// Montgomery form of the element
+func (r *zcashRNG) fq() *big.Int {
+	for {
+		var b [fpSize]byte
+		for i := 0; i < 6; i++ {
+			limb := r.next64()
+			if i == 5 {
+				limb &= ^uint64(0) >> 3
+			}
+			binary.BigEndian.PutUint64(b[fpSize-8*(i+1):], limb)
+		}
+		v := new(big.Int).SetBytes(b[:])
+		if v.Cmp(fpModulus) < 0 {
+			v.Mul(v, fpMontRInv)
+			return v.Mod(v, fpModulus)
+		}
+	}
+}
+
+// fq2 is an element c0 + c1⋅u of Fq2 = Fq[u]/(u² + 1), used to sample the
+// points of zcashHashToG2
+type fq2 [2]*big.Int
+
+func (a fq2) add(b fq2) fq2 {
+	return fq2{fqMod(new(big.Int).Add(a[0], b[0])), fqMod(new(big.Int).Add(a[1], b[1]))}
+}
+
+func (a fq2) mul(b fq2) fq2 {
+	// (a0 + a1⋅u)(b0 + b1⋅u) = a0⋅b0 - a1⋅b1 + (a0⋅b1 + a1⋅b0)⋅u
+	c0 := new(big.Int).Mul(a[0], b[0])
+	c0.Sub(c0, new(big.Int).Mul(a[1], b[1]))
+	c1 := new(big.Int).Mul(a[0], b[1])
+	c1.Add(c1, new(big.Int).Mul(a[1], b[0]))
+	return fq2{fqMod(c0), fqMod(c1)}
+}
+
+func (a fq2) neg() fq2 {
+	return fq2{fqMod(new(big.Int).Neg(a[0])), fqMod(new(big.Int).Neg(a[1]))}
+}
+
+func (a fq2) exp(e *big.Int) fq2 {
+	r := fq2{big.NewInt(1), big.NewInt(0)}
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		r = r.mul(r)
+		if e.Bit(i) == 1 {
+			r = r.mul(a)
+		}
+	}
+	return r
+}
+
+func (a fq2) equal(b fq2) bool {
+	return a[0].Cmp(b[0]) == 0 && a[1].Cmp(b[1]) == 0
+}
+
+// cmp compares lexicographically c1 and then c0, as the Ord of the pairing
+// crate
+func (a fq2) cmp(b fq2) int {
+	if c := a[1].Cmp(b[1]); c != 0 {
+		return c
+	}
+	return a[0].Cmp(b[0])
+}
+
+// sqrt returns a square root of a, using the algorithm 9 of "Square root
+// computation over even extension fields" (Adj, Rodríguez-Henríquez) for
+// q ≡ 3 mod 4
+func (a fq2) sqrt() (fq2, bool) {
+	one := fq2{big.NewInt(1), big.NewInt(0)}
+	minusOne := one.neg()
+	// a₁ = a^((q - 3) / 4)
+	e := new(big.Int).Sub(fpModulus, big.NewInt(3))
+	a1 := a.exp(e.Rsh(e, 2))
+	alpha := a1.mul(a1).mul(a)
+	// α^q ⋅ α, where α^q is the conjugate of α
+	a0 := fq2{alpha[0], fqMod(new(big.Int).Neg(alpha[1]))}.mul(alpha)
+	if a0.equal(minusOne) {
+		return fq2{}, false
+	}
+	x0 := a1.mul(a)
+	var x fq2
+	if alpha.equal(minusOne) {
+		x = fq2{big.NewInt(0), big.NewInt(1)}.mul(x0)
+	} else {
+		e := new(big.Int).Sub(fpModulus, big.NewInt(1))
+		x = alpha.add(one).exp(e.Rsh(e, 1)).mul(x0)
+	}
+	return x, x.mul(x).equal(a)
+}
+
+func fqMod(v *big.Int) *big.Int {
+	return v.Mod(v, fpModulus)
+}
+
+// zcashWriter writes the elements of the Zcash files, keeping the first
+// error
+type zcashWriter struct {
+	w          *bufio.Writer
+	compressed bool
+	g1         *bls12381.G1
+	g2         *bls12381.G2
+	err        error
+}
+
+func newZcashWriter(w io.Writer, compressed bool) *zcashWriter {
+	return &zcashWriter{w: bufio.NewWriter(w), compressed: compressed,
+		g1: bls12381.NewG1(), g2: bls12381.NewG2()}
+}
+
+func (w *zcashWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.Write(b)
+}
+
+func (w *zcashWriter) pointG1(p *bls12381.PointG1, compressed bool) {
+	if compressed {
+		w.write(w.g1.ToCompressed(p))
+		return
+	}
+	w.write(w.g1.ToUncompressed(p))
+}
+
+func (w *zcashWriter) pointG2(p *bls12381.PointG2, compressed bool) {
+	if compressed {
+		w.write(w.g2.ToCompressed(p))
+		return
+	}
+	w.write(w.g2.ToUncompressed(p))
+}
+
+func (w *zcashWriter) accumulator(a *ZcashAccumulator) {
+	for _, p := range a.TauG1 {
+		w.pointG1(p, w.compressed)
+	}
+	for _, p := range a.TauG2 {
+		w.pointG2(p, w.compressed)
+	}
+	for _, v := range [][]*bls12381.PointG1{a.AlphaTauG1, a.BetaTauG1} {
+		for _, p := range v {
+			w.pointG1(p, w.compressed)
+		}
+	}
+	w.pointG2(a.BetaG2, w.compressed)
+}
+
+// publicKey writes the public key, which is always uncompressed
+func (w *zcashWriter) publicKey(k *ZcashPublicKey) {
+	for _, p := range [][2]*bls12381.PointG1{k.TauG1, k.AlphaG1, k.BetaG1} {
+		w.pointG1(p[0], false)
+		w.pointG1(p[1], false)
+	}
+	for _, p := range []*bls12381.PointG2{k.TauG2, k.AlphaG2, k.BetaG2} {
+		w.pointG2(p, false)
+	}
+}
+
+func (w *zcashWriter) flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// zcashReader reads the elements of the Zcash files, checking the
+// correctness of the points and keeping the first error
+type zcashReader struct {
+	r          *bufio.Reader
+	compressed bool
+	err        error
+}
+
+func newZcashReader(r io.Reader, compressed bool) *zcashReader {
+	return &zcashReader{r: bufio.NewReader(r), compressed: compressed}
+}
+
+func (r *zcashReader) read(b []byte) {
+	if r.err != nil {
+		return
+	}
+	_, r.err = io.ReadFull(r.r, b)
+}
+
+func (r *zcashReader) accumulator(n int) *ZcashAccumulator {
+	if n < 2 {
+		r.err = fmt.Errorf("zcash format needs at least 2 powers, got %d", n)
+		return nil
+	}
+	return &ZcashAccumulator{
+		TauG1:      r.pointsG1("TauG1", 2*n-1, r.compressed),
+		TauG2:      r.pointsG2("TauG2", n, r.compressed),
+		AlphaTauG1: r.pointsG1("AlphaTauG1", n, r.compressed),
+		BetaTauG1:  r.pointsG1("BetaTauG1", n, r.compressed),
+		BetaG2:     r.pointG2("BetaG2", r.compressed),
+	}
+}
+
+func (r *zcashReader) publicKey() *ZcashPublicKey {
+	k := &ZcashPublicKey{}
+	copy(k.TauG1[:], r.pointsG1("PublicKey.TauG1", 2, false))
+	copy(k.AlphaG1[:], r.pointsG1("PublicKey.AlphaG1", 2, false))
+	copy(k.BetaG1[:], r.pointsG1("PublicKey.BetaG1", 2, false))
+	k.TauG2 = r.pointG2("PublicKey.TauG2", false)
+	k.AlphaG2 = r.pointG2("PublicKey.AlphaG2", false)
+	k.BetaG2 = r.pointG2("PublicKey.BetaG2", false)
+	return k
+}
+
+// pointsG1 reads n points by chunks, decoding each chunk concurrently
+func (r *zcashReader) pointsG1(element string, n int, compressed bool) []*bls12381.PointG1 {
+	size := 2 * fpSize
+	if compressed {
+		size = fpSize
+	}
+	var points []*bls12381.PointG1
+	buf := make([]byte, pointsChunkSize*size)
+	for len(points) < n && r.err == nil {
+		m := n - len(points)
+		if m > pointsChunkSize {
+			m = pointsChunkSize
+		}
+		b := buf[:m*size]
+		r.read(b)
+		if r.err != nil {
+			r.err = fmt.Errorf("%s: %w", element, r.err)
+			return nil
+		}
+		chunk := make([]*bls12381.PointG1, m)
+		errs := make([]error, m)
+		parallelize(m, func(start, end int) {
+			g1 := bls12381.NewG1()
+			for i := start; i < end; i++ {
+				chunk[i], errs[i] = decodeZcashPointG1(g1, b[i*size:(i+1)*size], compressed)
+				if errs[i] != nil {
+					return
+				}
+			}
+		})
+		for i, err := range errs {
+			if err != nil {
+				r.err = fmt.Errorf("%s: G1 point %d: %w", element, len(points)+i, err)
+				return nil
+			}
+		}
+		points = append(points, chunk...)
+	}
+	return points
+}
+
+// pointsG2 acts as pointsG1 for G2 points
+func (r *zcashReader) pointsG2(element string, n int, compressed bool) []*bls12381.PointG2 {
+	size := 4 * fpSize
+	if compressed {
+		size = 2 * fpSize
+	}
+	var points []*bls12381.PointG2
+	buf := make([]byte, pointsChunkSize*size)
+	for len(points) < n && r.err == nil {
+		m := n - len(points)
+		if m > pointsChunkSize {
+			m = pointsChunkSize
+		}
+		b := buf[:m*size]
+		r.read(b)
+		if r.err != nil {
+			r.err = fmt.Errorf("%s: %w", element, r.err)
+			return nil
+		}
+		chunk := make([]*bls12381.PointG2, m)
+		errs := make([]error, m)
+		parallelize(m, func(start, end int) {
+			g2 := bls12381.NewG2()
+			for i := start; i < end; i++ {
+				chunk[i], errs[i] = decodeZcashPointG2(g2, b[i*size:(i+1)*size], compressed)
+				if errs[i] != nil {
+					return
+				}
+			}
+		})
+		for i, err := range errs {
+			if err != nil {
+				r.err = fmt.Errorf("%s: G2 point %d: %w", element, len(points)+i, err)
+				return nil
+			}
+		}
+		points = append(points, chunk...)
+	}
+	return points
+}
+
+func (r *zcashReader) pointG2(element string, compressed bool) *bls12381.PointG2 {
+	points := r.pointsG2(element, 1, compressed)
+	if r.err != nil {
+		return nil
+	}
+	return points[0]
+}
+
+// finish returns the first error, checking that there is no data left
+func (r *zcashReader) finish() error {
+	if r.err != nil {
+		return r.err
+	}
+	if _, err := r.r.ReadByte(); err != io.EOF {
+		return fmt.Errorf("unexpected data after the end of the file")
+	}
+	return nil
+}
+
+func decodeZcashPointG1(g1 *bls12381.G1, b []byte, compressed bool) (*bls12381.PointG1, error) {
+	var p *bls12381.PointG1
+	var err error
+	if compressed {
+		p, err = g1.FromCompressed(b)
+	} else {
+		p, err = g1.FromUncompressed(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkG1Point(g1, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func decodeZcashPointG2(g2 *bls12381.G2, b []byte, compressed bool) (*bls12381.PointG2, error) {
+	var p *bls12381.PointG2
+	var err error
+	if compressed {
+		p, err = g2.FromCompressed(b)
+	} else {
+		p, err = g2.FromUncompressed(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkG2Point(g2, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,318 @@
+package kzgceremony
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+func randFr(c *qt.C) *bls12381.Fr {
+	e, err := bls12381.NewFr().Rand(rand.Reader)
+	c.Assert(err, qt.IsNil)
+	return e
+}
+
+// zcashContribute computes the response to the challenge as the
+// contributors of the Zcash ceremony do, for random τ, α and β
+func zcashContribute(c *qt.C, f ZcashFormat, challenge *ZcashChallenge) *ZcashResponse {
+	digest, err := f.ChallengeHash(challenge)
+	c.Assert(err, qt.IsNil)
+	tau, alpha, beta := randFr(c), randFr(c), randFr(c)
+
+	keyPart := func(personalization byte, x *bls12381.Fr) ([2]*bls12381.PointG1, *bls12381.PointG2) {
+		s, sx := g1.New(), g1.New()
+		g1.MulScalar(s, g1.One(), randFr(c))
+		g1.MulScalar(sx, s, x)
+		h := zcashHashToG2(&digest, personalization, [2]*bls12381.PointG1{s, sx})
+		g2.MulScalar(h, h, x)
+		return [2]*bls12381.PointG1{s, sx}, h
+	}
+	key := &ZcashPublicKey{}
+	key.TauG1, key.TauG2 = keyPart(zcashPersonalizationTau, tau)
+	key.AlphaG1, key.AlphaG2 = keyPart(zcashPersonalizationAlpha, alpha)
+	key.BetaG1, key.BetaG2 = keyPart(zcashPersonalizationBeta, beta)
+
+	before := challenge.Accumulator
+	after := NewZcashAccumulator(f.Powers)
+	ti := bls12381.NewFr().One()
+	t := bls12381.NewFr()
+	for i := range after.TauG1 {
+		g1.MulScalar(after.TauG1[i], before.TauG1[i], ti)
+		if i < f.Powers {
+			g2.MulScalar(after.TauG2[i], before.TauG2[i], ti)
+			t.Mul(ti, alpha)
+			g1.MulScalar(after.AlphaTauG1[i], before.AlphaTauG1[i], t)
+			t.Mul(ti, beta)
+			g1.MulScalar(after.BetaTauG1[i], before.BetaTauG1[i], t)
+		}
+		ti.Mul(ti, tau)
+	}
+	g2.MulScalar(after.BetaG2, before.BetaG2, beta)
+	return &ZcashResponse{ChallengeHash: digest, Accumulator: after, PublicKey: key}
+}
+
+func equalZcashAccumulators(c *qt.C, a, b *ZcashAccumulator) {
+	for _, v := range [][2][]*bls12381.PointG1{{a.TauG1, b.TauG1},
+		{a.AlphaTauG1, b.AlphaTauG1}, {a.BetaTauG1, b.BetaTauG1}} {
+		c.Assert(v[0], qt.HasLen, len(v[1]))
+		for i := range v[0] {
+			c.Assert(g1.Equal(v[0][i], v[1][i]), qt.IsTrue)
+		}
+	}
+	c.Assert(a.TauG2, qt.HasLen, len(b.TauG2))
+	for i := range a.TauG2 {
+		c.Assert(g2.Equal(a.TauG2[i], b.TauG2[i]), qt.IsTrue)
+	}
+	c.Assert(g2.Equal(a.BetaG2, b.BetaG2), qt.IsTrue)
+}
+
+func TestZcashBlankHash(t *testing.T) {
+	c := qt.New(t)
+	h := ZcashBlankHash()
+	c.Assert(hex.EncodeToString(h[:]), qt.Equals,
+		"786a02f742015903c6c6fd852552d272912f4740e15847618a86e217f71f5419"+
+			"d25e1031afee585313896444934eb04b903a685b1448b755d56f701afe9be2ce")
+}
+
+func TestZcashChallengeResponse(t *testing.T) {
+	c := qt.New(t)
+
+	n := 8
+	for _, f := range []ZcashFormat{
+		{Powers: n, CompressedResponse: true},
+		{Powers: n, CompressedChallenge: true},
+	} {
+		challenge := &ZcashChallenge{PrevHash: ZcashBlankHash(),
+			Accumulator: NewZcashAccumulator(n)}
+
+		for round := 0; round < 2; round++ {
+			// challenge round trip
+			var b bytes.Buffer
+			c.Assert(f.WriteChallenge(&b, challenge), qt.IsNil)
+			g1Size, g2Size := 96, 192
+			if f.CompressedChallenge {
+				g1Size, g2Size = 48, 96
+			}
+			c.Assert(b.Len(), qt.Equals, 64+(2*n-1+2*n)*g1Size+(n+1)*g2Size)
+			readChallenge, err := f.ReadChallenge(&b)
+			c.Assert(err, qt.IsNil)
+			c.Assert(readChallenge.PrevHash, qt.Equals, challenge.PrevHash)
+			equalZcashAccumulators(c, readChallenge.Accumulator, challenge.Accumulator)
+
+			// response round trip
+			response := zcashContribute(c, f, readChallenge)
+			b.Reset()
+			c.Assert(f.WriteResponse(&b, response), qt.IsNil)
+			g1Size, g2Size = 96, 192
+			if f.CompressedResponse {
+				g1Size, g2Size = 48, 96
+			}
+			c.Assert(b.Len(), qt.Equals,
+				64+(2*n-1+2*n)*g1Size+(n+1)*g2Size+6*96+3*192)
+			readResponse, err := f.ReadResponse(&b)
+			c.Assert(err, qt.IsNil)
+			c.Assert(readResponse.ChallengeHash, qt.Equals, response.ChallengeHash)
+			equalZcashAccumulators(c, readResponse.Accumulator, response.Accumulator)
+
+			for _, mode := range []VerificationMode{VerifyStrict, VerifyBatch} {
+				c.Assert(f.CheckResponse(challenge, readResponse, mode), qt.IsNil)
+				c.Assert(f.VerifyResponse(challenge, readResponse, mode), qt.IsTrue)
+			}
+			c.Assert(CheckSRS(readResponse.Accumulator.SRS(), VerifyBatch), qt.IsNil)
+
+			challenge, err = f.NextChallenge(readResponse)
+			c.Assert(err, qt.IsNil)
+			responseHash, err := f.ResponseHash(readResponse)
+			c.Assert(err, qt.IsNil)
+			c.Assert(challenge.PrevHash, qt.Equals, responseHash)
+		}
+	}
+}
+
+func TestZcashCheckResponseErrors(t *testing.T) {
+	c := qt.New(t)
+
+	f := ZcashFormat{Powers: 4, CompressedResponse: true}
+	challenge := &ZcashChallenge{PrevHash: ZcashBlankHash(),
+		Accumulator: NewZcashAccumulator(4)}
+	challenge.Accumulator = zcashContribute(c, f, challenge).Accumulator
+	response := zcashContribute(c, f, challenge)
+
+	copyResponse := func() *ZcashResponse {
+		a := response.Accumulator
+		k := *response.PublicKey
+		return &ZcashResponse{
+			ChallengeHash: response.ChallengeHash,
+			Accumulator: &ZcashAccumulator{
+				TauG1:      append([]*bls12381.PointG1{}, a.TauG1...),
+				TauG2:      append([]*bls12381.PointG2{}, a.TauG2...),
+				AlphaTauG1: append([]*bls12381.PointG1{}, a.AlphaTauG1...),
+				BetaTauG1:  append([]*bls12381.PointG1{}, a.BetaTauG1...),
+				BetaG2:     a.BetaG2,
+			},
+			PublicKey: &k,
+		}
+	}
+	other := zcashContribute(c, f, challenge)
+
+	for _, tc := range []struct {
+		name    string
+		modify  func(r *ZcashResponse)
+		check   VerificationCheck
+		element string
+		index   int
+	}{
+		{"hash", func(r *ZcashResponse) { r.ChallengeHash[0]++ },
+			HashChainCheck, "ChallengeHash", -1},
+		{"point", func(r *ZcashResponse) { r.Accumulator.BetaTauG1[2] = g1.Zero() },
+			PointValidityCheck, "BetaTauG1", 2},
+		{"key point", func(r *ZcashResponse) { r.PublicKey.AlphaG1[0] = g1.Zero() },
+			PointValidityCheck, "PublicKey.AlphaG1", 0},
+		{"pok", func(r *ZcashResponse) { r.PublicKey.AlphaG2 = other.PublicKey.AlphaG2 },
+			PubKeyCheck, "PublicKey.AlphaG2", -1},
+		{"genesis", func(r *ZcashResponse) { r.Accumulator.TauG2[0] = r.Accumulator.TauG2[1] },
+			GenesisCheck, "TauG2", 0},
+		// the powers of another τ
+		{"tau", func(r *ZcashResponse) { r.Accumulator = other.Accumulator },
+			RunningProductCheck, "TauG1", 1},
+		{"beta g2", func(r *ZcashResponse) { r.Accumulator.BetaG2 = g2.One() },
+			RunningProductCheck, "BetaG2", -1},
+		{"tau structure", func(r *ZcashResponse) {
+			a := r.Accumulator
+			a.TauG1[4], a.TauG1[5] = a.TauG1[5], a.TauG1[4]
+		}, G1StructureCheck, "TauG1", 4},
+		{"alpha structure", func(r *ZcashResponse) {
+			a := r.Accumulator
+			a.AlphaTauG1[3] = a.AlphaTauG1[2]
+		}, G1StructureCheck, "AlphaTauG1", 3},
+	} {
+		for _, mode := range []VerificationMode{VerifyStrict, VerifyBatch} {
+			r := copyResponse()
+			tc.modify(r)
+			var vErr *VerificationError
+			err := f.CheckResponse(challenge, r, mode)
+			c.Assert(errors.As(err, &vErr), qt.IsTrue, qt.Commentf(tc.name))
+			c.Assert(vErr.Check, qt.Equals, tc.check, qt.Commentf(tc.name))
+			c.Assert(vErr.Element, qt.Equals, tc.element, qt.Commentf(tc.name))
+			c.Assert(vErr.Index, qt.Equals, tc.index, qt.Commentf(tc.name))
+		}
+	}
+	c.Assert(f.CheckResponse(challenge, response, VerifyStrict), qt.IsNil)
+
+	err := ZcashFormat{Powers: 5}.CheckResponse(challenge, response, VerifyStrict)
+	c.Assert(err, qt.ErrorMatches, "accumulator does not have the sizes of 5 powers")
+}
+
+func TestZcashReadErrors(t *testing.T) {
+	c := qt.New(t)
+
+	f := ZcashFormat{Powers: 4, CompressedResponse: true}
+	challenge := &ZcashChallenge{PrevHash: ZcashBlankHash(),
+		Accumulator: NewZcashAccumulator(4)}
+	var b bytes.Buffer
+	c.Assert(f.WriteResponse(&b, zcashContribute(c, f, challenge)), qt.IsNil)
+	valid := b.Bytes()
+
+	_, err := f.ReadResponse(bytes.NewReader(valid[:len(valid)-1]))
+	c.Assert(err, qt.ErrorMatches, "response: PublicKey.BetaG2: unexpected EOF")
+	_, err = f.ReadResponse(bytes.NewReader(append(valid, 0)))
+	c.Assert(err, qt.ErrorMatches, "response: unexpected data after the end of the file")
+	_, err = ZcashFormat{Powers: 4}.ReadResponse(bytes.NewReader(valid))
+	c.Assert(err, qt.ErrorMatches, "response: TauG1: G1 point 0: .*")
+
+	// the point at infinity
+	invalid := append([]byte(nil), valid...)
+	copy(invalid[64+48:], append([]byte{0xc0}, make([]byte, 47)...))
+	_, err = f.ReadResponse(bytes.NewReader(invalid))
+	c.Assert(err, qt.ErrorMatches, "response: TauG1: G1 point 1: point can not be zero")
+
+	b.Reset()
+	c.Assert(f.WriteChallenge(&b, challenge), qt.IsNil)
+	invalid = b.Bytes()
+	// y of the uncompressed generator
+	invalid[64+95]++
+	_, err = f.ReadChallenge(bytes.NewReader(invalid))
+	c.Assert(err, qt.ErrorMatches, "challenge: TauG1: G1 point 0: point is not on curve")
+}
+
+func TestZcashHashToG2(t *testing.T) {
+	c := qt.New(t)
+
+	digest := ZcashBlankHash()
+	s := [2]*bls12381.PointG1{g1.One(), g1.One()}
+	h0 := zcashHashToG2(&digest, zcashPersonalizationTau, s)
+	c.Assert(checkG2PointCorrectness(h0), qt.IsNil)
+	c.Assert(g2.Equal(h0, zcashHashToG2(&digest, zcashPersonalizationTau, s)), qt.IsTrue)
+	h1 := zcashHashToG2(&digest, zcashPersonalizationAlpha, s)
+	c.Assert(checkG2PointCorrectness(h1), qt.IsNil)
+	c.Assert(g2.Equal(h0, h1), qt.IsFalse)
+
+	// the square roots used to sample the points
+	for i := 0; i < 10; i++ {
+		a := fq2{randFq(c), randFq(c)}
+		sq := a.mul(a)
+		r, ok := sq.sqrt()
+		c.Assert(ok, qt.IsTrue)
+		c.Assert(r.equal(a) || r.equal(a.neg()), qt.IsTrue)
+	}
+}
+
+func randFq(c *qt.C) *big.Int {
+	v, err := rand.Int(rand.Reader, fpModulus)
+	c.Assert(err, qt.IsNil)
+	return v
+}
+
+func TestZcashRNG(t *testing.T) {
+	c := qt.New(t)
+
+	// the first 40 words of the generators seeded with 32 zero bytes and
+	// with the bytes 0x00..0x1f, computed with ChaCha20Rng of the
+	// rand_chacha crate keyed with the big-endian words of the seed. The
+	// first block of the zero seed is the keystream of the test vector #1
+	// of RFC 8439 (A.1). The bytes after the first 32 are not used.
+	testCases := []struct {
+		seed     []byte
+		expected string
+	}{
+		{make([]byte, 64),
+			"ade0b876903df1a0e56a5d4028bd8653b819d2bd1aed8da0ccef36a8c70d778b" +
+				"7c5941da8d4857513fe02477374ad8b8f4b8436a1ca1181569b687c38665eeb2" +
+				"bee7079f7a3851557c97ba980d082d73a0290fcb6965e3483e53c612ed7aee32" +
+				"7621b729434ee69cb03371d5d539d874281fed3145fb0a511f0ae1ac6f4d794b" +
+				"e6a0092de16c266308d17eae75a06819998e718ec662d37b3446c3b05db3a0a9"},
+		{append(seqBytes(32), bytes.Repeat([]byte{0xff}, 32)...),
+			"6c40c0b508058281e830f92a102ec0de44f9fba75c179c02a1237f2dfeb9cf49" +
+				"6a3fb27b540108a63ea9a76869576b501df7b5e1fd66fa7fa611516aa1bbbb27" +
+				"8bd33d2559c6c496d7a01772b17eadbce16d13dbf81877b0041b25d2a8402fc6" +
+				"f2a5dcca05dd095beeaf5734a10c1a0809ba662c8721289f0433911e375edbd0" +
+				"6ae79cf983e2f3d346d7bdc401d9005ba5f653a365e7a34f1cfcb30490396bf9"},
+	}
+	for _, tc := range testCases {
+		rng := newZcashRNG(tc.seed)
+		var words []byte
+		for i := 0; i < 40; i++ {
+			words = binary.BigEndian.AppendUint32(words, rng.next32())
+		}
+		c.Assert(hex.EncodeToString(words), qt.Equals, tc.expected)
+	}
+
+	// next64 takes the first word as the high one
+	rng := newZcashRNG(make([]byte, 64))
+	c.Assert(rng.next64(), qt.Equals, uint64(0xade0b876903df1a0))
+}
+
+func seqBytes(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}